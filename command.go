@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fiber-prjct-management-web/internal/jobs"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/checkpoint"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/mailer"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runCommand run maintenance command from cli instead of starting the http server, e.g. go run . fsck -delete-orphans
+func runCommand(args []string) error {
+	switch args[0] {
+	case "fsck":
+		return runStorageCheck(args[1:])
+	case "audit-verify":
+		return runAuditVerify(args[1:])
+	case "audit-checkpoint":
+		return runAuditCheckpoint()
+	case "audit-keygen":
+		return runAuditKeygen()
+	case "report-digest":
+		return runReportDigest()
+	default:
+		return fmt.Errorf("unknown command %s, available command: fsck, audit-verify, audit-checkpoint, audit-keygen, report-digest", args[0])
+	}
+}
+
+func runStorageCheck(args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	deleteOrphans := fs.Bool("delete-orphans", false, "delete orphaned files older than grace period")
+	grace := fs.Duration("grace", 24*time.Hour, "grace period before orphaned file can be deleted")
+	asJSON := fs.Bool("json", false, "print report as json")
+	fs.Parse(args)
+
+	report, err := jobs.StorageCheck(database.DB, repository.NewDailyLogRepository(database.DB), jobs.StorageCheckOptions{
+		DeleteOrphans: *deleteOrphans,
+		GracePeriod:   *grace,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	fmt.Printf("files on disk: %d, file references: %d\n", report.TotalFiles, report.TotalReferences)
+
+	fmt.Printf("\nmissing files (%d):\n", len(report.MissingFiles))
+	for _, file := range report.MissingFiles {
+		fmt.Printf("  log %d (project %d): %s\n", file.LogId, file.ProjectId, file.Path)
+	}
+
+	fmt.Printf("\norphaned files (%d):\n", len(report.OrphanedFiles))
+	for _, file := range report.OrphanedFiles {
+		status := ""
+		if file.Deleted {
+			status = " [deleted]"
+		}
+
+		fmt.Printf("  %s (%d bytes, modified %s)%s\n", file.Path, file.Size, file.ModTime, status)
+	}
+
+	if *deleteOrphans {
+		fmt.Printf("\ndeleted %d orphaned files (%d bytes)\n", report.DeletedFiles, report.DeletedBytes)
+	}
+
+	return nil
+}
+
+func runAuditVerify(args []string) error {
+	fs := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print report as json")
+	fs.Parse(args)
+
+	if err := checkpoint.Init(); err != nil {
+		return err
+	}
+
+	report, err := jobs.VerifyAuditChain(database.DB, repository.NewAuditRepository(database.DB))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("checked events: %d, last event: %d %s\n", report.CheckedEvents, report.LastEventId, report.LastHash)
+
+		if report.Checkpoints < 0 {
+			fmt.Println("checkpoints: disabled (AUDIT_SIGNING_KEY is not set)")
+		} else {
+			fmt.Printf("verified checkpoints: %d\n", report.Checkpoints)
+		}
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("audit chain broken at event %d: %s", report.BrokenEventId, report.Reason)
+	}
+
+	if !*asJSON {
+		fmt.Println("audit chain is valid")
+	}
+
+	return nil
+}
+
+func runAuditCheckpoint() error {
+	if err := checkpoint.Init(); err != nil {
+		return err
+	}
+
+	if checkpoint.Default == nil {
+		return fmt.Errorf("checkpoint is disabled, set AUDIT_SIGNING_KEY (generate one with audit-keygen)")
+	}
+
+	signed, err := jobs.CreateAuditCheckpoint(database.DB, repository.NewAuditRepository(database.DB))
+	if err != nil {
+		return err
+	}
+
+	if signed == nil {
+		fmt.Println("no new audit event since the last checkpoint")
+		return nil
+	}
+
+	fmt.Printf("checkpoint of event %d %s written to %s\n", signed.EventId, signed.Hash, checkpoint.Default.Path())
+	return nil
+}
+
+func runAuditKeygen() error {
+	key, err := checkpoint.GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	signer, err := checkpoint.NewSigner(key, "")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("AUDIT_SIGNING_KEY=%s\n", key)
+	fmt.Printf("public key (give to the auditors): %s\n", signer.PublicKey())
+	return nil
+}
+
+// runReportDigest send the due report digests now instead of waiting for the worker
+func runReportDigest() error {
+	mailer.Init()
+	if mailer.Default == nil {
+		return fmt.Errorf("email is disabled, set SMTP_HOST")
+	}
+
+	return jobs.SendReportDigests(database.DB, repository.NewReportSubscriptionRepository(database.DB), repository.NewProjectRepository(database.DB), repository.NewDailyLogRepository(database.DB), repository.NewBudgetRepository(database.DB))
+}
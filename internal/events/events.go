@@ -0,0 +1,72 @@
+package events
+
+import "database/sql"
+
+// event types published by the handlers
+const (
+	LogCreated             = "log.created"
+	LogUpdated             = "log.updated"
+	LogDeleted             = "log.deleted"
+	LogsImported           = "log.imported"
+	BudgetThresholdCrossed = "budget.threshold_crossed"
+	ProjectChanged         = "project.changed"
+	ProjectStatusChanged   = "project.status_changed"
+	UserChangedByAdmin     = "user.changed_by_admin"
+)
+
+// Event is a change published by the handler that made it. Data is the changed entity, e.g. models.DailyLog for
+// LogCreated, or Change when the subscribers need the entity before the change
+type Event struct {
+	Type      string
+	ActorId   int // 0 is a change made by the app, e.g. a budget alert
+	ActorName string
+	ProjectId int
+	EntityId  int
+	Data      interface{}
+}
+
+// Change is the data of an event that changed an entity
+type Change struct {
+	Before interface{}
+	After  interface{}
+}
+
+// LogsImportedData is the data of LogsImported
+type LogsImportedData struct {
+	Filename string
+	Imported int
+}
+
+// Handler run inside the transaction of the change, returned error rollback the change
+type Handler func(tx *sql.Tx, event Event) error
+
+// Bus call the subscribers of an event synchronously, in the order they subscribed
+type Bus struct {
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Default is the bus the handlers publish to, subscribers are added on start before the server listen
+var Default = NewBus()
+
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Publish(tx *sql.Tx, event Event) error {
+	for _, handler := range b.handlers[event.Type] {
+		if err := handler(tx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish publish the event to the default bus
+func Publish(tx *sql.Tx, event Event) error {
+	return Default.Publish(tx, event)
+}
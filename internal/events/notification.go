@@ -0,0 +1,155 @@
+package events
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/report"
+	"fiber-prjct-management-web/internal/repository"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// notificationTextLimit limit the log description quoted on a notification
+const notificationTextLimit = 100
+
+// Notifier make the in-app notifications of the events. Project events notify the project members, the owner and
+// the super admins, except the user who made the change
+type Notifier struct {
+	notificationRepo repository.NotificationRepository
+	projectRepo      repository.ProjectRepository
+}
+
+func NewNotifier(notificationRepo repository.NotificationRepository, projectRepo repository.ProjectRepository) *Notifier {
+	return &Notifier{notificationRepo, projectRepo}
+}
+
+// Subscribe add the notifier to the events it notify
+func (n *Notifier) Subscribe(bus *Bus) {
+	bus.Subscribe(LogCreated, n.logCreated)
+	bus.Subscribe(LogsImported, n.logsImported)
+	bus.Subscribe(BudgetThresholdCrossed, n.budgetThresholdCrossed)
+	bus.Subscribe(ProjectStatusChanged, n.projectStatusChanged)
+	bus.Subscribe(UserChangedByAdmin, n.userChangedByAdmin)
+}
+
+func (n *Notifier) logCreated(tx *sql.Tx, event Event) error {
+	dailyLog := event.Data.(models.DailyLog)
+
+	project, err := n.projectRepo.FindByID(tx, event.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("%s menambahkan log %s", actorName(event), strings.SplitN(dailyLog.LogDate, "T", 2)[0])
+	if dailyLog.Description != "" {
+		message += ": " + shorten(dailyLog.Description)
+	}
+
+	return n.notificationRepo.CreateForProject(tx, event.ProjectId, event.ActorId, models.Notification{
+		Type:    event.Type,
+		Title:   "Log baru di " + project.Name,
+		Message: message,
+		Link:    fmt.Sprintf("/project/%d", event.ProjectId),
+	})
+}
+
+func (n *Notifier) logsImported(tx *sql.Tx, event Event) error {
+	data := event.Data.(LogsImportedData)
+
+	project, err := n.projectRepo.FindByID(tx, event.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	return n.notificationRepo.CreateForProject(tx, event.ProjectId, event.ActorId, models.Notification{
+		Type:    event.Type,
+		Title:   "Log baru di " + project.Name,
+		Message: fmt.Sprintf("%s mengimport %d log dari %s", actorName(event), data.Imported, data.Filename),
+		Link:    fmt.Sprintf("/project/%d", event.ProjectId),
+	})
+}
+
+// budgetThresholdCrossed is raised by the app, so the user who made the change is also notified
+func (n *Notifier) budgetThresholdCrossed(tx *sql.Tx, event Event) error {
+	alert := event.Data.(models.BudgetAlert)
+
+	project, err := n.projectRepo.FindByID(tx, event.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	scope := "Budget project"
+	if alert.CategoryId.Valid {
+		scope = "Budget kategori " + alert.CategoryName
+	}
+
+	return n.notificationRepo.CreateForProject(tx, event.ProjectId, 0, models.Notification{
+		Type:    event.Type,
+		Title:   fmt.Sprintf("Budget %s mencapai %d%%", project.Name, alert.Threshold),
+		Message: fmt.Sprintf("%s sudah terpakai %.2f%%", scope, alert.UsagePercentage),
+		Link:    fmt.Sprintf("/project/%d", event.ProjectId),
+	})
+}
+
+func (n *Notifier) projectStatusChanged(tx *sql.Tx, event Event) error {
+	change := event.Data.(Change)
+	before := change.Before.(models.Project)
+	after := change.After.(models.Project)
+
+	return n.notificationRepo.CreateForProject(tx, event.ProjectId, event.ActorId, models.Notification{
+		Type:  event.Type,
+		Title: "Status " + after.Name + " berubah",
+		Message: fmt.Sprintf("%s mengubah status dari %s menjadi %s", actorName(event),
+			report.ProjectStatusName(before.Status), report.ProjectStatusName(after.Status)),
+		Link: fmt.Sprintf("/project/%d", event.ProjectId),
+	})
+}
+
+// userChangedByAdmin notify the user whose account is changed by a super admin
+func (n *Notifier) userChangedByAdmin(tx *sql.Tx, event Event) error {
+	change := event.Data.(Change)
+	before := change.Before.(models.User)
+	after := change.After.(models.User)
+
+	changes := []string{}
+	if before.Username != after.Username {
+		changes = append(changes, fmt.Sprintf("username menjadi %s", after.Username))
+	}
+
+	if before.Role != after.Role {
+		changes = append(changes, fmt.Sprintf("role menjadi %s", report.UserRoleName(after.Role)))
+	}
+
+	if before.Email != after.Email {
+		changes = append(changes, "email")
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return n.notificationRepo.Create(tx, event.EntityId, models.Notification{
+		Type:    event.Type,
+		Title:   "Akun anda diubah",
+		Message: fmt.Sprintf("%s mengubah %s", actorName(event), strings.Join(changes, ", ")),
+		Link:    "/user/self",
+	})
+}
+
+func actorName(event Event) string {
+	if event.ActorName == "" {
+		return "Sistem"
+	}
+
+	return event.ActorName
+}
+
+// shorten cut the text to notificationTextLimit characters
+func shorten(text string) string {
+	if utf8.RuneCountInString(text) <= notificationTextLimit {
+		return text
+	}
+
+	return string([]rune(text)[:notificationTextLimit]) + "..."
+}
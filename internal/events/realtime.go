@@ -0,0 +1,209 @@
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RealtimeChannel is the postgres NOTIFY channel of the realtime events, every app instance listen to it so the
+// clients connected to any instance get the changes made on the others
+const RealtimeChannel = "realtime_events"
+
+// realtimeResync is sent to every client when the LISTEN connection reconnected, the events sent while it was down
+// are lost so the client should reload its data
+const realtimeResync = "resync"
+
+// realtimeClientBuffer limit the messages waiting for a slow client, later messages are dropped
+const realtimeClientBuffer = 16
+
+// RealtimeMessage is the realtime event sent to the clients that can see the project
+type RealtimeMessage struct {
+	Type      string `json:"type"`
+	ProjectId int    `json:"project_id,omitempty"`
+	EntityId  int    `json:"entity_id,omitempty"`
+	ActorId   int    `json:"actor_id,omitempty"`
+}
+
+// realtimeNotification is the NOTIFY payload, the project owner is needed by the listening instances to scope it
+type realtimeNotification struct {
+	RealtimeMessage
+	OwnerId int `json:"owner_id"`
+}
+
+// RealtimePublisher NOTIFY the project events on RealtimeChannel. The notification is sent by postgres when the
+// transaction of the change committed, a rolled back change is never sent
+type RealtimePublisher struct {
+	projectRepo repository.ProjectRepository
+}
+
+func NewRealtimePublisher(projectRepo repository.ProjectRepository) *RealtimePublisher {
+	return &RealtimePublisher{projectRepo}
+}
+
+// Subscribe add the publisher to the events shown on the dashboard
+func (p *RealtimePublisher) Subscribe(bus *Bus) {
+	bus.Subscribe(LogCreated, p.notify)
+	bus.Subscribe(LogUpdated, p.notify)
+	bus.Subscribe(LogDeleted, p.notify)
+	bus.Subscribe(LogsImported, p.notify)
+	bus.Subscribe(ProjectChanged, p.notify)
+}
+
+func (p *RealtimePublisher) notify(tx *sql.Tx, event Event) error {
+	ownerId, err := p.projectOwner(tx, event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(realtimeNotification{
+		RealtimeMessage: RealtimeMessage{Type: event.Type, ProjectId: event.ProjectId, EntityId: event.EntityId, ActorId: event.ActorId},
+		OwnerId:         ownerId,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("select pg_notify($1, $2)", RealtimeChannel, string(payload))
+	return err
+}
+
+// projectOwner get the owner from the event data when it is the project, a deleted project can not be found anymore
+func (p *RealtimePublisher) projectOwner(tx *sql.Tx, event Event) (int, error) {
+	switch data := event.Data.(type) {
+	case models.Project:
+		return data.CreatedBy, nil
+	case Change:
+		if project, ok := data.After.(models.Project); ok {
+			return project.CreatedBy, nil
+		}
+	}
+
+	project, err := p.projectRepo.FindByID(tx, event.ProjectId)
+	if err != nil {
+		return 0, err
+	}
+
+	return project.CreatedBy, nil
+}
+
+// RealtimeClient is a connected user waiting for the realtime events it can see
+type RealtimeClient struct {
+	user     models.UserSession
+	Messages chan []byte
+}
+
+// canSee follow the project list scope, super admin see every project and other users only their own projects
+func (c *RealtimeClient) canSee(notification realtimeNotification) bool {
+	return c.user.Role == 3 || c.user.Id == notification.OwnerId
+}
+
+// Hub LISTEN to RealtimeChannel and fan out the notifications to the clients connected to this instance
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*RealtimeClient]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: map[*RealtimeClient]struct{}{}}
+}
+
+// Listen open the LISTEN connection and start the fan out, the connection is reopened by pq when it is lost
+func (h *Hub) Listen(connStr string) error {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("realtime listener:", err)
+		}
+	})
+
+	if err := listener.Listen(RealtimeChannel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case notification := <-listener.Notify:
+				// nil is sent after the connection reconnected
+				if notification == nil {
+					h.resync()
+					continue
+				}
+
+				h.broadcast(notification.Extra)
+			case <-time.After(90 * time.Second):
+				// check the idle connection is still alive
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Register add the client of the user, Unregister must be called when the client disconnected
+func (h *Hub) Register(user models.UserSession) *RealtimeClient {
+	client := &RealtimeClient{user: user, Messages: make(chan []byte, realtimeClientBuffer)}
+
+	h.mu.Lock()
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	return client
+}
+
+func (h *Hub) Unregister(client *RealtimeClient) {
+	h.mu.Lock()
+	delete(h.clients, client)
+	h.mu.Unlock()
+}
+
+func (h *Hub) broadcast(payload string) {
+	var notification realtimeNotification
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		log.Println("realtime notification:", err)
+		return
+	}
+
+	data, err := json.Marshal(notification.RealtimeMessage)
+	if err != nil {
+		log.Println("realtime notification:", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		if client.canSee(notification) {
+			client.send(data)
+		}
+	}
+}
+
+func (h *Hub) resync() {
+	data, _ := json.Marshal(RealtimeMessage{Type: realtimeResync})
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for client := range h.clients {
+		client.send(data)
+	}
+}
+
+// send never block the fan out, the client reload all its data on any message so a dropped message is not missed
+// while others are still waiting
+func (c *RealtimeClient) send(data []byte) {
+	select {
+	case c.Messages <- data:
+	default:
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/jobs"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditHandler show the record of every change made through the app, only for super admin
+type AuditHandler struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditHandler(auditRepo repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{auditRepo}
+}
+
+func (h *AuditHandler) ViewAudit(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	return c.Render("pages/audit", fiber.Map{
+		"Title": "Audit Log",
+		"User":  user,
+		"Breadcrumb": models.BreadCrumb{
+			BeforeName: "Dashboard",
+			BeforeLink: "/",
+		},
+	})
+}
+
+func (h *AuditHandler) GetAuditEvents(c *fiber.Ctx) error {
+	perPage, err := strconv.Atoi(c.Query("per_page", "10"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	events, total, err := h.auditRepo.FindWithPagination(tx, perPage, page, filter)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithPagination(c, fiber.StatusOK, "Get Audit Events", total, page, perPage, "events", events)
+}
+
+// ExportAuditEvents stream the filtered events as csv, the rows are written while they are read from the database
+func (h *AuditHandler) ExportAuditEvents(c *fiber.Ctx) error {
+	filter, err := auditFilter(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="audit-%s.csv"`, time.Now().Format("20060102-150405")))
+
+	// the csv is written after the handler return, so it use its own read only transaction and error can only be logged
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		tx, err := database.DB.Begin()
+		if err != nil {
+			log.Println("export audit events error: ", err)
+			return
+		}
+		defer tx.Rollback()
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "created_at", "actor_id", "actor_name", "action", "entity_type", "entity_id", "before", "after", "ip", "user_agent", "request_id", "prev_hash", "hash"})
+
+		err = h.auditRepo.Each(tx, filter, func(event models.AuditEvent) error {
+			actorId := ""
+			if event.ActorId.Valid {
+				actorId = strconv.FormatInt(event.ActorId.Int64, 10)
+			}
+
+			return cw.Write([]string{strconv.FormatInt(event.Id, 10), event.CreatedAt, actorId, event.ActorName, event.Action, event.EntityType, event.EntityId, string(event.Before), string(event.After), event.Ip, event.UserAgent, event.RequestId, event.PrevHash, event.Hash})
+		})
+		if err != nil {
+			log.Println("export audit events error: ", err)
+		}
+
+		cw.Flush()
+		w.Flush()
+	})
+
+	return nil
+}
+
+// VerifyAuditChain walk the whole audit chain and the signed checkpoints, the first broken link is reported
+func (h *AuditHandler) VerifyAuditChain(c *fiber.Ctx) error {
+	report, err := jobs.VerifyAuditChain(database.DB, h.auditRepo)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Verify Audit Chain", report)
+}
+
+func auditFilter(c *fiber.Ctx) (models.AuditFilter, error) {
+	filter := models.AuditFilter{
+		Search:     c.Query("search", ""),
+		Action:     c.Query("action", ""),
+		EntityType: c.Query("entity_type", ""),
+		EntityId:   c.Query("entity_id", ""),
+		FromDate:   c.Query("from_date", ""),
+		ToDate:     c.Query("to_date", ""),
+	}
+
+	if c.Query("actor_id", "") != "" {
+		actorId, err := strconv.Atoi(c.Query("actor_id"))
+		if err != nil {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid actor ID")
+		}
+
+		filter.ActorId = actorId
+	}
+
+	for _, date := range []string{filter.FromDate, filter.ToDate} {
+		if date == "" {
+			continue
+		}
+
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Date must be YYYY-MM-DD")
+		}
+	}
+
+	return filter, nil
+}
+
+// recordAudit store a change made by the request user. entityId is nil when the change has no single entity, before
+// and after are the entity before and after the change, nil on create and delete, password fields are never stored.
+// The event is stored when the transaction is committed by the utils commit helpers
+func recordAudit(c *fiber.Ctx, tx *sql.Tx, auditRepo repository.AuditRepository, action string, entityType string, entityId interface{}, before interface{}, after interface{}) error {
+	event := models.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		Ip:         c.IP(),
+		UserAgent:  c.Get(fiber.HeaderUserAgent),
+	}
+
+	if entityId != nil {
+		event.EntityId = fmt.Sprint(entityId)
+	}
+
+	if user, ok := c.Locals("user").(models.UserSession); ok {
+		event.ActorId = sql.NullInt64{Int64: int64(user.Id), Valid: user.Id != 0}
+		event.ActorName = user.Username
+	}
+
+	if requestId, ok := c.Locals("requestid").(string); ok {
+		event.RequestId = requestId
+	}
+
+	var err error
+	if event.Before, err = auditData(before); err != nil {
+		return err
+	}
+
+	if event.After, err = auditData(after); err != nil {
+		return err
+	}
+
+	// the event is appended to the chain just before commit, so the chain lock is not held while the request run
+	utils.BeforeCommit(c, tx, func() error {
+		return auditRepo.Create(tx, &event)
+	})
+
+	return nil
+}
+
+func auditData(data interface{}) (json.RawMessage, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(stripAuditSecrets(value))
+}
+
+func stripAuditSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if strings.Contains(strings.ToLower(key), "password") {
+				delete(v, key)
+				continue
+			}
+
+			v[key] = stripAuditSecrets(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stripAuditSecrets(item)
+		}
+	}
+
+	return value
+}
+
+// recordLogAudit store a change of the daily log, the log after the change is read from the database
+func recordLogAudit(c *fiber.Ctx, tx *sql.Tx, auditRepo repository.AuditRepository, dailyLogRepo repository.DailyLogRepository, action string, before interface{}, logId int) error {
+	after, err := dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		return err
+	}
+
+	return recordAudit(c, tx, auditRepo, action, models.AuditEntityDailyLog, logId, before, after)
+}
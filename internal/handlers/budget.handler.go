@@ -0,0 +1,505 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BudgetHandler handle budget per expense category of a project and the alerts raised when the project
+// or category budget usage cross the thresholds
+type BudgetHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	budgetRepo   repository.BudgetRepository
+	categoryRepo repository.ExpenseCategoryRepository
+	rateRepo     repository.ExchangeRateRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewBudgetHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, categoryRepo repository.ExpenseCategoryRepository, rateRepo repository.ExchangeRateRepository, auditRepo repository.AuditRepository) *BudgetHandler {
+	return &BudgetHandler{
+		projectRepo,
+		dailyLogRepo,
+		budgetRepo,
+		categoryRepo,
+		rateRepo,
+		auditRepo,
+	}
+}
+
+func (h *BudgetHandler) GetProjectBudgets(c *fiber.Ctx) error {
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err := h.projectRepo.FindByID(tx, projectID); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondBudgets(c, tx, projectID, "Get Project Budgets")
+}
+
+// SetCategoryBudget create or replace the budget of a category on the project
+func (h *BudgetHandler) SetCategoryBudget(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	budgetInput := new(models.CategoryBudgetInput)
+	if err := c.BodyParser(budgetInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := utils.ValidateStruct(budgetInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Field() {
+			case "CategoryId":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Category is required")
+			case "Amount":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Amount must be 0 or more")
+			}
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	if _, err := h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if _, err := h.categoryRepo.FindByID(tx, budgetInput.CategoryId); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Category not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.budgetRepo.UpsertCategoryBudget(tx, projectID, budgetInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdate, models.AuditEntityCategoryBudget, categoryBudgetID(projectID, budgetInput.CategoryId), nil, budgetInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondBudgets(c, tx, projectID, "Set Category Budget")
+}
+
+func (h *BudgetHandler) DeleteCategoryBudget(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	categoryId, err := strconv.Atoi(c.Params("category_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid category ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	if _, err := h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.budgetRepo.DeleteCategoryBudget(tx, projectID, categoryId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityCategoryBudget, categoryBudgetID(projectID, categoryId), nil, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// category without budget has no alert
+	if err := h.budgetRepo.DeleteAlertsAbove(tx, projectID, categoryId, 0); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondBudgets(c, tx, projectID, "Delete Category Budget")
+}
+
+// GetBudgetAlerts get alerts of user projects (all projects for super admin), filtered with project_id and unread=true
+func (h *BudgetHandler) GetBudgetAlerts(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	if user.Role == 3 {
+		user.Id = 0
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	alerts, err := h.budgetRepo.FindAlerts(tx, c.QueryInt("project_id", 0), user.Id, c.QueryBool("unread", false))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Budget Alerts", alerts)
+}
+
+func (h *BudgetHandler) ReadBudgetAlert(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	alert, err := h.budgetRepo.FindAlertByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Alert not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if user.Role != 3 {
+		if _, err := h.projectRepo.FindIfProjectOwner(tx, alert.ProjectId, user.Id); err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrorJSON(c, fiber.StatusNotFound, "Alert not found")
+			}
+
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := h.budgetRepo.MarkAlertRead(tx, id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Read Budget Alert")
+}
+
+// respondBudgets respond with the whole project budget usage and the usage per category
+func (h *BudgetHandler) respondBudgets(c *fiber.Ctx, tx *sql.Tx, projectID int, message string) error {
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	projectUsage, usages, err := findBudgetUsage(tx, h.dailyLogRepo, h.budgetRepo, projectID, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, message, fiber.Map{
+		"project":    projectUsage,
+		"categories": usages,
+	})
+}
+
+// findBudgetUsage get the whole project budget usage, which is the same as DailyLogStats budget usage, and the usage per category
+func findBudgetUsage(tx *sql.Tx, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, projectID int, opts models.StatsOptions) (models.BudgetUsage, []models.BudgetUsage, error) {
+	stats, err := dailyLogRepo.FindStats(tx, projectID, opts)
+	if err != nil {
+		return models.BudgetUsage{}, nil, err
+	}
+
+	projectUsage := models.BudgetUsage{
+		Currency:        opts.Currency,
+		Budget:          stats.Budget,
+		Actual:          stats.TotalExpense,
+		Remaining:       stats.Budget - stats.TotalExpense,
+		UsagePercentage: stats.BudgetUsagePercentage,
+	}
+
+	usages, err := budgetRepo.FindUsage(tx, projectID, opts)
+	if err != nil {
+		return projectUsage, nil, err
+	}
+
+	return projectUsage, usages, nil
+}
+
+// syncBudgetAlerts raise alert for every threshold crossed by the project or category budget usage, alert of threshold
+// that is no longer crossed is removed so it is raised again on the next crossing, a raised alert is published as an
+// event. Must be called after expense or budget changed.
+// Usage is measured in the reporting currency
+func syncBudgetAlerts(tx *sql.Tx, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, projectID int) error {
+	opts := models.StatsOptions{Currency: utils.ReportingCurrency()}
+	projectUsage, usages, err := findBudgetUsage(tx, dailyLogRepo, budgetRepo, projectID, opts)
+	if err != nil {
+		return err
+	}
+
+	thresholds := utils.BudgetAlertThresholds()
+
+	for _, usage := range append([]models.BudgetUsage{projectUsage}, usages...) {
+		if err := budgetRepo.DeleteAlertsAbove(tx, projectID, usage.CategoryId, usage.UsagePercentage); err != nil {
+			return err
+		}
+
+		if usage.Budget <= 0 {
+			continue
+		}
+
+		for _, threshold := range thresholds {
+			if usage.UsagePercentage < float64(threshold) {
+				break
+			}
+
+			alert := models.BudgetAlert{
+				ProjectId:       projectID,
+				CategoryId:      sql.NullInt64{Int64: int64(usage.CategoryId), Valid: usage.CategoryId != 0},
+				CategoryName:    usage.CategoryName,
+				Threshold:       threshold,
+				UsagePercentage: usage.UsagePercentage,
+				Budget:          usage.Budget,
+				Actual:          usage.Actual,
+			}
+
+			created, err := budgetRepo.CreateAlert(tx, &alert)
+			if err != nil {
+				return err
+			}
+
+			if created {
+				event := events.Event{Type: events.BudgetThresholdCrossed, ProjectId: projectID, EntityId: usage.CategoryId, Data: alert}
+				if err := events.Publish(tx, event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetProjectBudgetRevisions get budget revision history of the project
+func (h *BudgetHandler) GetProjectBudgetRevisions(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	revisions, err := h.budgetRepo.FindRevisions(tx, projectID, c.Query("status"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Budget Revisions", fiber.Map{
+		"approval_required": utils.BudgetApprovalRequired(),
+		"can_review":        user.Role == 3,
+		"budget":            project.Budget,
+		"revisions":         revisions,
+	})
+}
+
+// GetPendingBudgetRevisions get budget revisions of all projects waiting for approval
+func (h *BudgetHandler) GetPendingBudgetRevisions(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	revisions, err := h.budgetRepo.FindRevisions(tx, 0, models.BudgetRevisionPending)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Pending Budget Revisions", revisions)
+}
+
+// ApproveBudgetRevision approve pending revision and apply the new budget to the project
+func (h *BudgetHandler) ApproveBudgetRevision(c *fiber.Ctx) error {
+	return h.reviewBudgetRevision(c, models.BudgetRevisionApproved)
+}
+
+// RejectBudgetRevision reject pending revision, the project budget is not changed
+func (h *BudgetHandler) RejectBudgetRevision(c *fiber.Ctx) error {
+	return h.reviewBudgetRevision(c, models.BudgetRevisionRejected)
+}
+
+func (h *BudgetHandler) reviewBudgetRevision(c *fiber.Ctx, status string) error {
+	user := c.Locals("user").(models.UserSession)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	reviewInput := new(models.BudgetReviewInput)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(reviewInput); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	if err := utils.ValidateStruct(reviewInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Note max 255 characters")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	revision, err := h.budgetRepo.FindRevisionByID(tx, id, true)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Budget revision not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if revision.Status != models.BudgetRevisionPending {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Revisi budget sudah "+revision.Status)
+	}
+
+	if status == models.BudgetRevisionApproved {
+		if err := h.projectRepo.UpdateBudget(tx, revision.ProjectId, revision.NewBudget); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	if err := h.budgetRepo.ReviewRevision(tx, id, status, user.Id, strings.TrimSpace(reviewInput.Note)); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if status == models.BudgetRevisionApproved {
+		if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, revision.ProjectId); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	reviewed, err := h.budgetRepo.FindRevisionByID(tx, id, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	action := models.AuditApprove
+	if status == models.BudgetRevisionRejected {
+		action = models.AuditReject
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, action, models.AuditEntityBudgetRevision, id, revision, reviewed); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.ProjectChanged, revision.ProjectId, revision.ProjectId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Budget revision "+status)
+}
+
+// requestBudgetRevision record the change of the project budget, the revision is approved at once unless
+// BUDGET_APPROVAL_REQUIRED env is set. The caller must only apply the new budget when the revision is approved
+func requestBudgetRevision(tx *sql.Tx, budgetRepo repository.BudgetRepository, project models.Project, newBudget int, reason string, userId int) (models.BudgetRevision, error) {
+	revision := models.BudgetRevision{
+		ProjectId:   project.Id,
+		OldBudget:   project.Budget,
+		NewBudget:   newBudget,
+		Reason:      strings.TrimSpace(reason),
+		Status:      models.BudgetRevisionApproved,
+		RequestedBy: sql.NullInt64{Int64: int64(userId), Valid: userId != 0},
+	}
+
+	if revision.Reason == "" {
+		return revision, fiber.NewError(fiber.StatusBadRequest, "Alasan perubahan budget wajib diisi")
+	}
+
+	pending, err := budgetRepo.FindRevisions(tx, project.Id, models.BudgetRevisionPending)
+	if err != nil {
+		return revision, err
+	}
+
+	if len(pending) > 0 {
+		return revision, fiber.NewError(fiber.StatusBadRequest, "Project masih punya perubahan budget yang menunggu persetujuan")
+	}
+
+	if utils.BudgetApprovalRequired() {
+		revision.Status = models.BudgetRevisionPending
+	}
+
+	if err := budgetRepo.CreateRevision(tx, &revision); err != nil {
+		return revision, err
+	}
+
+	return revision, nil
+}
+
+// categoryBudgetID is the audit entity id of a category budget, the budget is keyed by project and category
+func categoryBudgetID(projectID int, categoryId int) string {
+	return strconv.Itoa(projectID) + "/" + strconv.Itoa(categoryId)
+}
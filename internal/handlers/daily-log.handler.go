@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"bufio"
 	"database/sql"
+	"fiber-prjct-management-web/internal/events"
 	"fiber-prjct-management-web/internal/models"
 	"fiber-prjct-management-web/internal/repository"
 	"fiber-prjct-management-web/pkg/database"
 	"fiber-prjct-management-web/pkg/utils"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,12 +23,24 @@ import (
 type DailyLogHandler struct {
 	projectRepo  repository.ProjectRepository
 	dailyLogRepo repository.DailyLogRepository
+	uploadRepo   repository.UploadRepository
+	budgetRepo   repository.BudgetRepository
+	rateRepo     repository.ExchangeRateRepository
+	lockRepo     repository.PeriodLockRepository
+	revisionRepo repository.LogRevisionRepository
+	auditRepo    repository.AuditRepository
 }
 
-func NewDailyLogHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository) *DailyLogHandler {
+func NewDailyLogHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, uploadRepo repository.UploadRepository, budgetRepo repository.BudgetRepository, rateRepo repository.ExchangeRateRepository, lockRepo repository.PeriodLockRepository, revisionRepo repository.LogRevisionRepository, auditRepo repository.AuditRepository) *DailyLogHandler {
 	return &DailyLogHandler{
 		projectRepo,
 		dailyLogRepo,
+		uploadRepo,
+		budgetRepo,
+		rateRepo,
+		lockRepo,
+		revisionRepo,
+		auditRepo,
 	}
 }
 
@@ -94,12 +113,21 @@ func (h *DailyLogHandler) GetProjectLogStats(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
 	}
 
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
 	tx, err := database.DB.Begin()
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 	defer utils.CommitOrRollback(tx, c)
 
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
 	// check if projectowner
 	if _, err = h.projectRepo.FindByID(tx, projectID); err != nil {
 		if err == sql.ErrNoRows {
@@ -110,24 +138,76 @@ func (h *DailyLogHandler) GetProjectLogStats(c *fiber.Ctx) error {
 	}
 
 	// project stats
-	projectStats, err := h.dailyLogRepo.FindStats(tx, projectID)
+	projectStats, err := h.dailyLogRepo.FindStats(tx, projectID, opts)
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
 	// project stats cumulative
-	projectStatsCum, err := h.dailyLogRepo.FindStatsCumulative(tx, projectID)
+	projectStatsCum, err := h.dailyLogRepo.FindStatsCumulative(tx, projectID, opts)
 	if err != nil {
 		fmt.Println("sini error", err)
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
+	// project storage usage
+	storageUsage, err := h.projectRepo.FindStorageUsage(tx, projectID, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// budget vs actual per category
+	budgetUsage, err := h.budgetRepo.FindUsage(tx, projectID, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondWithData(c, fiber.StatusOK, "Get Project Log Stats", fiber.Map{
 		"projectStats":    projectStats,
 		"projectStatsCum": projectStatsCum,
+		"storageUsage":    storageUsage,
+		"budgetUsage":     budgetUsage,
 	})
 }
 
+// GetProjectCategoryStats get project expense per category and per month with the share of budget
+func (h *DailyLogHandler) GetProjectCategoryStats(c *fiber.Ctx) error {
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if _, err = h.projectRepo.FindByID(tx, projectID); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found/ User is not project owner")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	categoryStats, err := h.dailyLogRepo.FindCategoryStats(tx, projectID, 0, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Project Category Stats", categoryStats)
+}
+
 func (h *DailyLogHandler) GetOneLogData(c *fiber.Ctx) error {
 	// user := c.Locals("user").(models.UserSession)
 	projectID, err := strconv.Atoi(c.Params("project_id"))
@@ -194,6 +274,7 @@ func (h *DailyLogHandler) CreateDailyLog(c *fiber.Ctx) error {
 		Issues:      c.FormValue("issues"),
 		Income:      income,
 		Expense:     expense,
+		Currency:    utils.NormalizeCurrency(c.FormValue("currency")),
 		File:        "",
 	}
 
@@ -217,7 +298,8 @@ func (h *DailyLogHandler) CreateDailyLog(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
 
 	// check if today log already exist
 	checkLogToday, err := h.dailyLogRepo.FindByDate(tx, logInput.LogDate, projectID)
@@ -230,7 +312,7 @@ func (h *DailyLogHandler) CreateDailyLog(c *fiber.Ctx) error {
 	}
 
 	// check if user is project owner
-	_, err = h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id)
+	project, err := h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
@@ -241,31 +323,62 @@ func (h *DailyLogHandler) CreateDailyLog(c *fiber.Ctx) error {
 
 	logInput.ProjectId = projectID
 
+	if err := ensurePeriodOpen(tx, h.lockRepo, projectID, logInput.LogDate); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// log without currency use the project currency
+	if logInput.Currency == "" {
+		logInput.Currency = project.Currency
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, logInput.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
 	// upload file if uploaded
-	file, err := c.FormFile("file")
-	if (err != nil) && (file != nil) {
-		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	uploaded, err := h.saveLogFile(c, tx, files, projectID, logInput.LogDate, user.Id, 0)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 	}
 
-	if file != nil {
-		filename, err := utils.GenerateNameLogsFiles(logInput.LogDate, logInput.ProjectId)
-		if err != nil {
-			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
-		}
+	if uploaded.Path != "" {
+		logInput.File = uploaded.Path
+		logInput.FileStatus = uploaded.Status
+		logInput.FileThumbnail = uploaded.Thumbnail
+		logInput.FileSize = uploaded.Size
 
-		filepath, err := utils.FileUpload(c, "logs/", filename)
-		if err != nil {
+		if err := h.projectRepo.AddStorageUsage(tx, projectID, uploaded.Size, 1); err != nil {
 			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 		}
-
-		logInput.File = filepath
 	}
 
 	if err = h.dailyLogRepo.Create(tx, &logInput); err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	return utils.RespondMessage(c, fiber.StatusOK, "Create Daily Log")
+	createdLog, err := h.dailyLogRepo.FindByDate(tx, logInput.LogDate, projectID)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, models.DailyLog{}, createdLog.Id, user.Id, models.LogRevisionCreated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, models.AuditCreate, nil, createdLog.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogCreated, projectID, createdLog.Id, createdLog); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, fileStatusMessage("Create Daily Log", logInput.FileStatus))
 }
 
 func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
@@ -298,6 +411,7 @@ func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
 		ProjectId:   projectID,
 		Income:      income,
 		Expense:     expense,
+		Currency:    utils.NormalizeCurrency(c.FormValue("currency")),
 	}
 
 	err = utils.ValidateStruct(logUpdateInput)
@@ -319,10 +433,12 @@ func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
 
 	// check if project owner
-	if _, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id); err != nil {
+	logData, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log data on project not found/ User is not log owner")
 		}
@@ -330,6 +446,26 @@ func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
+	if err := ensureLogEditable(logData); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// log can not be moved out of or into a locked month
+	for _, date := range []string{logData.LogDate, logUpdateInput.LogDate} {
+		if err := ensurePeriodOpen(tx, h.lockRepo, projectID, date); err != nil {
+			return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+	}
+
+	// keep the log currency when not set
+	if logUpdateInput.Currency == "" {
+		logUpdateInput.Currency = logData.Currency
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, logUpdateInput.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
 	// check if log date already exist on another log data
 	checkLogToday, err := h.dailyLogRepo.FindByDate(tx, logUpdateInput.LogDate, projectID)
 	if (err != nil) && (err != sql.ErrNoRows) {
@@ -340,34 +476,40 @@ func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Daily log with selected date already exist")
 	}
 
-	// process file upload if user upload new file
-	file, err := c.FormFile("file")
-	if (err != nil) && (file != nil) {
-		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	// process file upload if user upload new file, old file size is released from the usage because the file will be replaced
+	uploaded, err := h.saveLogFile(c, tx, files, projectID, logUpdateInput.LogDate, user.Id, logData.FileSize)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
 	}
 
-	if file != nil {
-		// delete old file if exist
-		if checkLogToday.File.String != "" {
-			if err := utils.DeleteFile(checkLogToday.File.String); err != nil {
-				return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
-			}
-		}
+	if uploaded.Path != "" {
+		// old file is deleted after the new data committed
+		files.Delete(logData.File.String, logData.FileThumbnail.String)
 
-		// upload file
-		filename, err := utils.GenerateNameLogsFiles(logUpdateInput.LogDate, logUpdateInput.ProjectId)
-		if err != nil {
-			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		logUpdateInput.File = uploaded.Path
+		logUpdateInput.FileStatus = uploaded.Status
+		logUpdateInput.FileThumbnail = uploaded.Thumbnail
+		logUpdateInput.FileSize = uploaded.Size
+
+		replacedFiles := 0
+		if logData.File.String != "" {
+			replacedFiles = 1
 		}
 
-		filepath, err := utils.FileUpload(c, "logs/", filename)
-		if err != nil {
+		if err := h.projectRepo.AddStorageUsage(tx, projectID, uploaded.Size-logData.FileSize, 1-replacedFiles); err != nil {
 			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 		}
-
-		logUpdateInput.File = filepath
 	} else {
-		logUpdateInput.File = checkLogToday.File.String
+		logUpdateInput.File = logData.File.String
+		logUpdateInput.FileStatus = logData.FileStatus.String
+		logUpdateInput.FileThumbnail = logData.FileThumbnail.String
+		logUpdateInput.FileSize = logData.FileSize
+	}
+
+	// income and expense of log with line items are derived from the items
+	if logData.HasLineItems {
+		logUpdateInput.Income = logData.Income
+		logUpdateInput.Expense = logData.Expense
 	}
 
 	// update log data
@@ -375,7 +517,27 @@ func (h *DailyLogHandler) UpdateDailyLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	return utils.RespondMessage(c, fiber.StatusOK, "Update Daily Log")
+	if err := reopenLog(tx, h.dailyLogRepo, logData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, logData, logId, user.Id, models.LogRevisionUpdated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, models.AuditUpdate, logData, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, fileStatusMessage("Update Daily Log", logUpdateInput.FileStatus))
 }
 
 func (h *DailyLogHandler) DeleteLog(c *fiber.Ctx) error {
@@ -394,7 +556,8 @@ func (h *DailyLogHandler) DeleteLog(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
 
 	// check if user is project owner
 	log, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id)
@@ -406,9 +569,19 @@ func (h *DailyLogHandler) DeleteLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// delete file first if the file is exist
+	if err := ensureLogEditable(log); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := ensurePeriodOpen(tx, h.lockRepo, log.ProjectId, log.LogDate); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// file is deleted after the log deletion committed
 	if log.File.String != "" {
-		if err = utils.DeleteFile(log.File.String); err != nil {
+		files.Delete(log.File.String, log.FileThumbnail.String)
+
+		if err = h.projectRepo.AddStorageUsage(tx, projectID, -log.FileSize, -1); err != nil {
 			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 		}
 	}
@@ -418,6 +591,18 @@ func (h *DailyLogHandler) DeleteLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityDailyLog, logId, log, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogDeleted, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Delete Daily Log")
 }
 
@@ -438,7 +623,8 @@ func (h *DailyLogHandler) DeleteFileLog(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
 
 	// find if log exist and the log is the owner of the project
 	log, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, project_id, log_id, user.Id)
@@ -450,10 +636,19 @@ func (h *DailyLogHandler) DeleteFileLog(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// delete file first if there is a file
+	if err := ensureLogEditable(log); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := ensurePeriodOpen(tx, h.lockRepo, log.ProjectId, log.LogDate); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// file is deleted after the empty file path committed
 	if log.File.String != "" {
-		err = utils.DeleteFile(log.File.String)
-		if err != nil {
+		files.Delete(log.File.String, log.FileThumbnail.String)
+
+		if err = h.projectRepo.AddStorageUsage(tx, project_id, -log.FileSize, -1); err != nil {
 			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 		}
 	}
@@ -467,10 +662,299 @@ func (h *DailyLogHandler) DeleteFileLog(c *fiber.Ctx) error {
 		Issues:      log.Issues,
 		Income:      log.Income,
 		Expense:     log.Expense,
+		Currency:    log.Currency,
 	}
 	if err = h.dailyLogRepo.Update(tx, &log_update, log_id); err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
+	if err := reopenLog(tx, h.dailyLogRepo, log); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, log, log_id, user.Id, models.LogRevisionUpdated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, models.AuditDeleteFile, log, log_id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, project_id, log_id, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Delete File Log")
 }
+
+func (h *DailyLogHandler) DownloadFileLog(c *fiber.Ctx) error {
+	log, err := h.findClearedFileLog(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	return c.Download(log.File.String)
+}
+
+// PreviewFileLog show image and pdf file inline on the browser, other file type is downloaded
+func (h *DailyLogHandler) PreviewFileLog(c *fiber.Ctx) error {
+	log, err := h.findClearedFileLog(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if !utils.IsPreviewSupported(log.File.String) {
+		return c.Download(log.File.String)
+	}
+
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="`+filepath.Base(log.File.String)+`"`)
+	return c.SendFile(log.File.String)
+}
+
+func (h *DailyLogHandler) ThumbnailFileLog(c *fiber.Ctx) error {
+	log, err := h.findClearedFileLog(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if log.FileThumbnail.String == "" {
+		return utils.ErrorJSON(c, fiber.StatusNotFound, "Thumbnail not found")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, max-age=86400")
+	return c.SendFile(log.FileThumbnail.String)
+}
+
+// DownloadFilesArchive stream all clean attachments of the project as zip, grouped by log date with a csv manifest
+func (h *DailyLogHandler) DownloadFilesArchive(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	search := c.Query("search", "")
+	fromDate := c.Query("from_date", "")
+	toDate := c.Query("to_date", "")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// superadmin can access all files, admin only from their own project
+	if (user.Role != 3) && (project.CreatedBy != user.Id) {
+		return utils.ErrorJSON(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="project-%d-files.zip"`, projectID))
+
+	// the logs are read and the archive is written after the handler return, so error can only be logged
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer w.Flush()
+
+		if err := h.writeFilesArchive(w, projectID, search, fromDate, toDate); err != nil {
+			log.Println("write files archive error: ", err)
+		}
+	})
+
+	return nil
+}
+
+// writeFilesArchive write the clean files of the filtered logs into the zip one by one, newest date first like the log
+// list, with a manifest of every log
+func (h *DailyLogHandler) writeFilesArchive(w io.Writer, projectID int, search string, fromDate string, toDate string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	archive, err := utils.NewZipArchive(w, "manifest.csv", []string{"log_date", "description", "income", "expense", "filename", "file_status"})
+	if err != nil {
+		return err
+	}
+
+	err = h.dailyLogRepo.Each(tx, search, projectID, fromDate, toDate, 0, 0, func(logData models.DailyLog) error {
+		logDate := strings.Split(logData.LogDate, "T")[0]
+		filename := ""
+
+		status := logData.FileStatus.String
+		if (logData.File.String != "") && (status == "") {
+			status = utils.FileStatusPending
+		}
+
+		// only clean file that still exist on the disk is archived
+		if (logData.File.String != "") && (status == utils.FileStatusClean) {
+			if _, err := os.Stat(logData.File.String); err == nil {
+				filename = logDate + "/" + filepath.Base(logData.File.String)
+				if err := archive.AddFile(utils.ArchiveFile{Name: filename, Path: logData.File.String}); err != nil {
+					return err
+				}
+			} else {
+				status = "missing"
+			}
+		}
+
+		return archive.AddManifestRow([]string{logDate, logData.Description, strconv.Itoa(logData.Income), strconv.Itoa(logData.Expense), filename, status})
+	})
+
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// saveLogFile save log attachment from "file" form file or from completed chunked upload with "upload_id" form value.
+// The file is checked against project storage quota where replacedSize is size of the file that will be replaced,
+// empty UploadedFile is returned when there is no attachment
+func (h *DailyLogHandler) saveLogFile(c *fiber.Ctx, tx *sql.Tx, files *utils.FileTx, projectID int, logDate string, userId int, replacedSize int64) (utils.UploadedFile, error) {
+	var (
+		uploaded utils.UploadedFile
+		upload   models.Upload
+		size     int64
+	)
+
+	file, _ := c.FormFile("file")
+	uploadID := c.FormValue("upload_id")
+
+	if file != nil {
+		size = file.Size
+	} else if uploadID != "" {
+		var err error
+		upload, err = h.uploadRepo.FindByID(tx, uploadID, true)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return uploaded, fiber.NewError(fiber.StatusBadRequest, "Upload not found")
+			}
+
+			return uploaded, err
+		}
+
+		if (upload.ProjectId != projectID) || (upload.CreatedBy != userId) {
+			return uploaded, fiber.NewError(fiber.StatusBadRequest, "Upload not found")
+		}
+
+		if upload.Status != utils.UploadStatusCompleted {
+			return uploaded, fiber.NewError(fiber.StatusBadRequest, "Upload is not completed yet")
+		}
+
+		size = upload.Size
+	} else {
+		return uploaded, nil
+	}
+
+	// lock project storage usage until commit so concurrent upload can not exceed the quota
+	storage, err := h.projectRepo.FindStorageUsage(tx, projectID, true)
+	if err != nil {
+		return uploaded, err
+	}
+
+	if err := utils.CheckStorageQuota(storage.UsedBytes-replacedSize, storage.EffectiveQuota, size); err != nil {
+		return uploaded, err
+	}
+
+	filename, err := utils.GenerateNameLogsFiles(logDate, projectID)
+	if err != nil {
+		return uploaded, err
+	}
+
+	if file != nil {
+		uploaded, err = utils.FileUpload(c, "logs/", filename)
+	} else {
+		uploaded, err = utils.AttachChunkUpload(upload.Id, "logs/", filename, upload.Filename)
+		if err == nil {
+			// chunk file is only removed when the log is saved
+			files.Delete(utils.ChunkFile(upload.Id))
+			err = h.uploadRepo.Delete(tx, upload.Id)
+		}
+	}
+	files.Stage(uploaded.Path, uploaded.Thumbnail)
+
+	return uploaded, err
+}
+
+// findClearedFileLog get log data with file that the user allowed to access and already pass the antivirus scan
+func (h *DailyLogHandler) findClearedFileLog(c *fiber.Ctx) (models.DailyLog, error) {
+	var log models.DailyLog
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return log, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return log, fiber.NewError(fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return log, err
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return log, fiber.NewError(fiber.StatusNotFound, "Project not found")
+		}
+
+		return log, err
+	}
+
+	// superadmin can access all files, admin only from their own project
+	if (user.Role != 3) && (project.CreatedBy != user.Id) {
+		return log, fiber.NewError(fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	log, err = h.dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return log, fiber.NewError(fiber.StatusNotFound, "Log not found")
+		}
+
+		return log, err
+	}
+
+	if (log.ProjectId != projectID) || (log.File.String == "") {
+		return log, fiber.NewError(fiber.StatusNotFound, "File not found")
+	}
+
+	// only file that already scanned and clean can be accessed
+	if log.FileStatus.String != utils.FileStatusClean {
+		status := log.FileStatus.String
+		if status == "" {
+			status = utils.FileStatusPending
+		}
+
+		return log, fiber.NewError(fiber.StatusForbidden, "File can not be accessed, scan status: "+status)
+	}
+
+	return log, nil
+}
+
+func fileStatusMessage(message string, fileStatus string) string {
+	switch fileStatus {
+	case utils.FileStatusInfected:
+		return message + ", uploaded file is infected and has been quarantined"
+	case utils.FileStatusPending:
+		return message + ", uploaded file is waiting for antivirus scan"
+	}
+
+	return message
+}
@@ -12,12 +12,14 @@ import (
 type DashboardHandler struct {
 	projectRepo  repository.ProjectRepository
 	dailyLogRepo repository.DailyLogRepository
+	rateRepo     repository.ExchangeRateRepository
 }
 
-func NewDashboardHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository) *DashboardHandler {
+func NewDashboardHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, rateRepo repository.ExchangeRateRepository) *DashboardHandler {
 	return &DashboardHandler{
 		projectRepo,
 		dailyLogRepo,
+		rateRepo,
 	}
 }
 
@@ -41,13 +43,22 @@ func (h *DashboardHandler) DashboardData(c *fiber.Ctx) error {
 		user.Id = 0
 	}
 
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
 	tx, err := database.DB.Begin()
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 	defer utils.CommitOrRollback(tx, c)
 
-	project_data, err := h.projectRepo.FindProjectsStats(tx, user.Id)
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	project_data, err := h.projectRepo.FindProjectsStats(tx, user.Id, opts)
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
@@ -67,10 +78,22 @@ func (h *DashboardHandler) DashboardData(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	return utils.RespondWithData(c, fiber.StatusOK, "Dashboard Data", fiber.Map{
+	data := fiber.Map{
 		"project_data":            project_data,
 		"project_status_data":     project_status_data,
 		"newest_created_projects": newest_created_projects,
 		"newest_daily_logs":       newest_daily_logs,
-	})
+	}
+
+	// storage usage of all projects only for super admin
+	if user.Role == 3 {
+		storage_usage, err := h.projectRepo.FindStorageUsageStats(tx, 5)
+		if err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		data["storage_usage"] = storage_usage
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Dashboard Data", data)
 }
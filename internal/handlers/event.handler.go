@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// eventHeartbeat keep the idle stream open behind proxies and detect the disconnected client
+const eventHeartbeat = 25 * time.Second
+
+// EventHandler stream the realtime events to the browser with server-sent events
+type EventHandler struct {
+	hub *events.Hub
+}
+
+func NewEventHandler(hub *events.Hub) *EventHandler {
+	return &EventHandler{hub}
+}
+
+// StreamEvents send the project changes the user can see until the client disconnected. It is a web route because
+// EventSource can not send the Authorization header
+func (h *EventHandler) StreamEvents(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		client := h.hub.Register(user)
+		defer h.hub.Unregister(client)
+
+		ticker := time.NewTicker(eventHeartbeat)
+		defer ticker.Stop()
+
+		// browser reconnect 5 seconds after the stream is closed
+		fmt.Fprint(w, "retry: 5000\n\n")
+
+		for {
+			if err := w.Flush(); err != nil {
+				return
+			}
+
+			select {
+			case message := <-client.Messages:
+				fmt.Fprintf(w, "data: %s\n\n", message)
+			case <-ticker.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+		}
+	})
+
+	return nil
+}
+
+// publishEvent publish the change made by the request user to the default bus
+func publishEvent(c *fiber.Ctx, tx *sql.Tx, eventType string, projectId int, entityId int, data interface{}) error {
+	user := c.Locals("user").(models.UserSession)
+
+	return events.Publish(tx, events.Event{Type: eventType, ActorId: user.Id, ActorName: user.Username, ProjectId: projectId, EntityId: entityId, Data: data})
+}
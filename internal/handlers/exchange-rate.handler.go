@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxRateImportRows limit rows of one exchange rate CSV import
+const maxRateImportRows = 10000
+
+type ExchangeRateHandler struct {
+	rateRepo  repository.ExchangeRateRepository
+	auditRepo repository.AuditRepository
+}
+
+func NewExchangeRateHandler(rateRepo repository.ExchangeRateRepository, auditRepo repository.AuditRepository) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		rateRepo,
+		auditRepo,
+	}
+}
+
+func (h *ExchangeRateHandler) ViewExchangeRate(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	return c.Render("pages/exchangeRate", fiber.Map{
+		"Title":             "Exchange Rate",
+		"User":              user,
+		"ReportingCurrency": utils.ReportingCurrency(),
+		"Breadcrumb": models.BreadCrumb{
+			BeforeName: "Dashboard",
+			BeforeLink: "/",
+		},
+	})
+}
+
+// GetRates get rates, filtered by base or quote currency with currency query
+func (h *ExchangeRateHandler) GetRates(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	rates, err := h.rateRepo.FindAll(tx, utils.NormalizeCurrency(c.Query("currency")))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Exchange Rates", fiber.Map{
+		"reporting_currency": utils.ReportingCurrency(),
+		"rates":              rates,
+	})
+}
+
+// CreateRate create rate or replace the rate of the same pair and date
+func (h *ExchangeRateHandler) CreateRate(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	rateInput := new(models.ExchangeRateInput)
+	if err := c.BodyParser(rateInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rateInput.CreatedBy = user.Id
+	if err := validateRateInput(rateInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if err := h.rateRepo.Upsert(tx, rateInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityExchangeRate, nil, nil, rateInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Create Exchange Rate")
+}
+
+// ImportRates import rates from CSV file with header date,base_currency,quote_currency,rate. All rows are validated
+// before saved so nothing is imported when a row is invalid, rate of existing pair and date is replaced
+func (h *ExchangeRateHandler) ImportRates(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "CSV file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer file.Close()
+
+	rates, err := parseRatesCSV(file, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	for i := range rates {
+		if err := h.rateRepo.Upsert(tx, &rates[i]); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	importData := fiber.Map{"file": fileHeader.Filename, "imported": len(rates)}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditImport, models.AuditEntityExchangeRate, nil, nil, importData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Import Exchange Rates", fiber.Map{
+		"imported": len(rates),
+	})
+}
+
+// DeleteRate delete rate, the last rate of a pair can not be deleted while the currency is used and the pair is needed
+// to convert into reporting currency
+func (h *ExchangeRateHandler) DeleteRate(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	rate, err := h.rateRepo.FindByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Exchange rate not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// projects and logs must keep a rate to the reporting currency on either direction, stats can not convert them
+	reporting := utils.ReportingCurrency()
+	currencies, err := h.rateRepo.FindCurrenciesLosingRate(tx, id, reporting)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if len(currencies) > 0 {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, fmt.Sprintf("Kurs terakhir %s/%s tidak bisa dihapus, %s masih dipakai proyek/log dan tidak punya kurs lain ke %s", rate.BaseCurrency, rate.QuoteCurrency, strings.Join(currencies, ", "), reporting))
+	}
+
+	if err := h.rateRepo.Delete(tx, id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityExchangeRate, id, rate, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Delete Exchange Rate")
+}
+
+func validateRateInput(rateInput *models.ExchangeRateInput) error {
+	rateInput.BaseCurrency = utils.NormalizeCurrency(rateInput.BaseCurrency)
+	rateInput.QuoteCurrency = utils.NormalizeCurrency(rateInput.QuoteCurrency)
+
+	if err := utils.ValidateStruct(rateInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Field() {
+			case "BaseCurrency":
+				return fiber.NewError(fiber.StatusBadRequest, "Base currency must be 3 letters currency code")
+			case "QuoteCurrency":
+				return fiber.NewError(fiber.StatusBadRequest, "Quote currency must be 3 letters currency code and different from base currency")
+			case "RateDate":
+				return fiber.NewError(fiber.StatusBadRequest, "Rate date must be YYYY-MM-DD")
+			case "Rate":
+				return fiber.NewError(fiber.StatusBadRequest, "Rate must be more than 0")
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseRatesCSV(r io.Reader, userId int) ([]models.ExchangeRateInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("CSV file is empty or invalid: %v", err)
+	}
+
+	if strings.ToLower(strings.Join(header, ",")) != "date,base_currency,quote_currency,rate" {
+		return nil, fmt.Errorf("CSV header must be date,base_currency,quote_currency,rate")
+	}
+
+	rates := []models.ExchangeRateInput{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		if len(rates) >= maxRateImportRows {
+			return nil, fmt.Errorf("CSV file max %d rows", maxRateImportRows)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: rate must be a number", line)
+		}
+
+		rateInput := models.ExchangeRateInput{
+			RateDate:      strings.TrimSpace(record[0]),
+			BaseCurrency:  record[1],
+			QuoteCurrency: record[2],
+			Rate:          rate,
+			CreatedBy:     userId,
+		}
+
+		if err := validateRateInput(&rateInput); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		rates = append(rates, rateInput)
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("CSV file has no rate")
+	}
+
+	return rates, nil
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExpenseCategoryHandler struct {
+	categoryRepo repository.ExpenseCategoryRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewExpenseCategoryHandler(categoryRepo repository.ExpenseCategoryRepository, auditRepo repository.AuditRepository) *ExpenseCategoryHandler {
+	return &ExpenseCategoryHandler{
+		categoryRepo,
+		auditRepo,
+	}
+}
+
+func (h *ExpenseCategoryHandler) ViewExpenseCategory(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	return c.Render("pages/expenseCategory", fiber.Map{
+		"Title": "Expense Category",
+		"User":  user,
+		"Breadcrumb": models.BreadCrumb{
+			BeforeName: "Dashboard",
+			BeforeLink: "/",
+		},
+	})
+}
+
+// GetCategories get active categories, inactive categories are included with include_inactive=true
+func (h *ExpenseCategoryHandler) GetCategories(c *fiber.Ctx) error {
+	includeInactive := c.QueryBool("include_inactive", false)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	categories, err := h.categoryRepo.FindAll(tx, includeInactive)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Expense Categories", categories)
+}
+
+func (h *ExpenseCategoryHandler) CreateCategory(c *fiber.Ctx) error {
+	categoryInput := new(models.ExpenseCategoryInput)
+	if err := c.BodyParser(categoryInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validateCategoryInput(categoryInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if err := h.categoryRepo.Create(tx, categoryInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	category, err := h.categoryRepo.FindByID(tx, categoryInput.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityExpenseCategory, category.Id, nil, category); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Create Expense Category")
+}
+
+func (h *ExpenseCategoryHandler) UpdateCategory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	categoryInput := new(models.ExpenseCategoryInput)
+	if err := c.BodyParser(categoryInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := validateCategoryInput(categoryInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	before, err := h.categoryRepo.FindByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Category not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.categoryRepo.Update(tx, categoryInput, id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	after, err := h.categoryRepo.FindByID(tx, id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdate, models.AuditEntityExpenseCategory, id, before, after); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Update Expense Category")
+}
+
+// DeleteCategory only delete category that never used, used category should be deactivated instead
+func (h *ExpenseCategoryHandler) DeleteCategory(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	category, err := h.categoryRepo.FindByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Category not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	used, err := h.categoryRepo.CountItems(tx, id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if used > 0 {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Category is used by "+strconv.Itoa(used)+" line items, deactivate it instead")
+	}
+
+	if err := h.categoryRepo.Delete(tx, id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityExpenseCategory, id, category, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Delete Expense Category")
+}
+
+func validateCategoryInput(categoryInput *models.ExpenseCategoryInput) error {
+	if err := utils.ValidateStruct(categoryInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Field() {
+			case "Name":
+				return fiber.NewError(fiber.StatusBadRequest, "Name is required minimal 3 characters and max 100 characters")
+			case "Description":
+				return fiber.NewError(fiber.StatusBadRequest, "Description max 255 characters")
+			}
+		}
+	}
+
+	return nil
+}
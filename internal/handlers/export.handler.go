@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/report"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/export"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportHandler export the daily logs, projects and users lists as csv, xlsx or pdf with the same filters as the list
+// endpoints. The rows are read and written after the handler return, so large lists are not loaded into memory
+type ExportHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	userRepo     repository.UserRepository
+}
+
+func NewExportHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, userRepo repository.UserRepository) *ExportHandler {
+	return &ExportHandler{
+		projectRepo,
+		dailyLogRepo,
+		userRepo,
+	}
+}
+
+// ExportDailyLogs export logs of the project filtered by search, from_date and to_date. The columns are the same as
+// the daily log import, so the csv can be imported again
+func (h *ExportHandler) ExportDailyLogs(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	search := c.Query("search", "")
+	fromDate := c.Query("from_date", "")
+	toDate := c.Query("to_date", "")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// superadmin can export all projects, admin only their own project
+	if (user.Role != 3) && (project.CreatedBy != user.Id) {
+		return utils.ErrorJSON(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	columns := []export.Column{
+		{Title: "log_date", Width: 1.2},
+		{Title: "description", Width: 4},
+		{Title: "issues", Width: 3},
+		{Title: "income", Width: 1.5},
+		{Title: "expense", Width: 1.5},
+		{Title: "currency", Width: 1},
+		{Title: "status", Width: 1.2},
+		{Title: "item_count", Width: 1},
+		{Title: "file", Width: 2},
+	}
+
+	filename := fmt.Sprintf("project-%d-logs", projectID)
+	return streamExport(c, format, filename, "Daily Logs - "+project.Name, columns, func(tx *sql.Tx, write func(row []interface{}) error) error {
+		return h.dailyLogRepo.Each(tx, search, projectID, fromDate, toDate, 0, 0, func(logData models.DailyLog) error {
+			file := ""
+			if logData.File.String != "" {
+				file = filepath.Base(logData.File.String)
+			}
+
+			return write([]interface{}{strings.Split(logData.LogDate, "T")[0], logData.Description, logData.Issues, logData.Income, logData.Expense, logData.Currency, logData.Status, logData.ItemCount, file})
+		})
+	})
+}
+
+// ExportProjects export projects filtered by search, status, from_date and to_date, admin only export their own projects
+func (h *ExportHandler) ExportProjects(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	format, err := exportFormat(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	search := c.Query("search", "")
+	status := c.Query("status", "")
+	fromDate := c.Query("from_date", "")
+	toDate := c.Query("to_date", "")
+
+	// if superadmin can get all project from all user
+	userId := user.Id
+	if user.Role == 3 {
+		userId = 0
+	}
+
+	columns := []export.Column{
+		{Title: "id", Width: 0.8},
+		{Title: "name", Width: 3},
+		{Title: "description", Width: 4},
+		{Title: "status", Width: 1.5},
+		{Title: "start_date", Width: 1.5},
+		{Title: "end_date", Width: 1.5},
+		{Title: "budget", Width: 2},
+		{Title: "currency", Width: 1},
+		{Title: "created_at", Width: 2},
+	}
+
+	return streamExport(c, format, "projects", "Projects", columns, func(tx *sql.Tx, write func(row []interface{}) error) error {
+		return h.projectRepo.Each(tx, search, status, toDate, fromDate, userId, func(project models.Project) error {
+			return write([]interface{}{project.Id, project.Name, project.Description, report.ProjectStatusName(project.Status), strings.Split(project.StartDate.String, "T")[0], strings.Split(project.EndDate.String, "T")[0], project.Budget, project.Currency, project.CreatedAt})
+		})
+	})
+}
+
+// ExportUsers export users filtered by search, role, from_date and to_date
+func (h *ExportHandler) ExportUsers(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	search := c.Query("search", "")
+	role := c.Query("role", "")
+	fromDate := c.Query("from_date", "")
+	toDate := c.Query("to_date", "")
+
+	columns := []export.Column{
+		{Title: "id", Width: 0.8},
+		{Title: "username", Width: 2.5},
+		{Title: "email", Width: 3},
+		{Title: "role", Width: 1.5},
+		{Title: "created_at", Width: 2},
+		{Title: "updated_at", Width: 2},
+	}
+
+	return streamExport(c, format, "users", "Users", columns, func(tx *sql.Tx, write func(row []interface{}) error) error {
+		return h.userRepo.Each(tx, search, role, toDate, fromDate, func(user models.User) error {
+			return write([]interface{}{user.Id, user.Username, user.Email, report.UserRoleName(user.Role), user.CreatedAt, user.UpdatedAt})
+		})
+	})
+}
+
+// streamExport write the rows from each into the export file of the format. The file is written after the handler
+// return, so it use its own read only transaction and error can only be logged
+func streamExport(c *fiber.Ctx, format string, filename string, title string, columns []export.Column, each func(tx *sql.Tx, write func(row []interface{}) error) error) error {
+	c.Set(fiber.HeaderContentType, export.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-%s.%s"`, filename, time.Now().Format("20060102-150405"), format))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer w.Flush()
+
+		tx, err := database.DB.Begin()
+		if err != nil {
+			log.Println("export "+filename+" error: ", err)
+			return
+		}
+		defer tx.Rollback()
+
+		writer, err := export.NewWriter(format, w, title, columns)
+		if err != nil {
+			log.Println("export "+filename+" error: ", err)
+			return
+		}
+
+		if err := each(tx, writer.Write); err != nil {
+			log.Println("export "+filename+" error: ", err)
+		}
+
+		if err := writer.Close(); err != nil {
+			log.Println("export "+filename+" error: ", err)
+		}
+	})
+
+	return nil
+}
+
+func exportFormat(c *fiber.Ctx) (string, error) {
+	format := strings.ToLower(c.Query("format", export.FormatCSV))
+	if !export.IsFormat(format) {
+		return "", fiber.NewError(fiber.StatusBadRequest, "format must be csv, xlsx or pdf")
+	}
+
+	return format, nil
+}
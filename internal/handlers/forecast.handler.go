@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const dateLayout = "2006-01-02"
+
+type ForecastHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	rateRepo     repository.ExchangeRateRepository
+}
+
+func NewForecastHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, rateRepo repository.ExchangeRateRepository) *ForecastHandler {
+	return &ForecastHandler{
+		projectRepo,
+		dailyLogRepo,
+		rateRepo,
+	}
+}
+
+// GetProjectForecast get projected spend at completion, the date the budget runs out and the variance against
+// the budget spread evenly over the project dates, from the average daily spend since the project start
+func (h *ForecastHandler) GetProjectForecast(c *fiber.Ctx) error {
+	projectID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	stats, err := h.dailyLogRepo.FindStats(tx, projectID, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	cumulative, err := h.dailyLogRepo.FindStatsCumulative(tx, projectID, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	asOf := time.Now()
+	if opts.AsOf != "" {
+		asOf, _ = time.Parse(dateLayout, opts.AsOf)
+	}
+
+	forecast := buildForecast(project, stats, cumulative, truncateDate(asOf))
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Project Forecast", forecast)
+}
+
+// buildForecast project the spend linearly with the average daily spend from the project start (or the first log)
+// until asOf. Spend at completion and planned spend need the project end date
+func buildForecast(project models.Project, stats models.DailyLogStats, cumulative []models.DailyLogStatsCumulative, asOf time.Time) models.ProjectForecast {
+	forecast := models.ProjectForecast{
+		ProjectId:   project.Id,
+		Currency:    stats.Currency,
+		AsOf:        asOf.Format(dateLayout),
+		Budget:      stats.Budget,
+		ActualSpend: stats.TotalExpense,
+		Note:        "CPI/SPI butuh progres task, belum tersedia pada proyek",
+		Series:      []models.ForecastPoint{},
+	}
+
+	start, hasStart := parseDate(project.StartDate.String)
+	if !hasStart && (len(cumulative) > 0) {
+		start, hasStart = parseDate(cumulative[0].LogDate.String)
+	}
+	if !hasStart {
+		start = asOf
+	}
+	forecast.StartDate = start.Format(dateLayout)
+
+	forecast.ElapsedDays = daysBetween(start, asOf) + 1
+	if forecast.ElapsedDays < 0 {
+		forecast.ElapsedDays = 0
+	}
+
+	if forecast.ElapsedDays > 0 {
+		forecast.DailyBurnRate = roundTwo(float64(forecast.ActualSpend) / float64(forecast.ElapsedDays))
+	}
+
+	forecast.BudgetExceeded = (forecast.Budget > 0) && (forecast.ActualSpend > forecast.Budget)
+	forecast.Series = append(forecast.Series, models.ForecastPoint{Date: forecast.AsOf, CumulativeExpense: float64(forecast.ActualSpend)})
+
+	end, hasEnd := parseDate(project.EndDate.String)
+	if hasEnd {
+		forecast.EndDate = end.Format(dateLayout)
+
+		planned := daysBetween(start, end) + 1
+		if planned < 1 {
+			planned = 1
+		}
+
+		remaining := daysBetween(asOf, end)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		eac := roundTwo(float64(forecast.ActualSpend) + forecast.DailyBurnRate*float64(remaining))
+		vac := roundTwo(float64(forecast.Budget) - eac)
+		plannedSpend := roundTwo(float64(forecast.Budget) * math.Min(float64(forecast.ElapsedDays), float64(planned)) / float64(planned))
+		spendVariance := roundTwo(plannedSpend - float64(forecast.ActualSpend))
+
+		forecast.PlannedDays = &planned
+		forecast.RemainingDays = &remaining
+		forecast.ProjectedSpendAtCompletion = &eac
+		forecast.VarianceAtCompletion = &vac
+		forecast.PlannedSpendToDate = &plannedSpend
+		forecast.SpendVariance = &spendVariance
+
+		if remaining > 0 {
+			forecast.Series = append(forecast.Series, models.ForecastPoint{Date: forecast.EndDate, CumulativeExpense: eac})
+		}
+	}
+
+	if forecast.Budget > 0 {
+		var runOut string
+
+		if forecast.BudgetExceeded {
+			// the log date the cumulative expense passed the budget
+			expense := 0
+			for _, stat := range cumulative {
+				expense += stat.Expense
+				if date, ok := parseDate(stat.LogDate.String); ok && (expense > forecast.Budget) {
+					runOut = date.Format(dateLayout)
+					break
+				}
+			}
+		} else if forecast.DailyBurnRate > 0 {
+			days := int(math.Ceil(float64(forecast.Budget-forecast.ActualSpend) / forecast.DailyBurnRate))
+			runOut = asOf.AddDate(0, 0, days).Format(dateLayout)
+
+			// show where the budget runs out when it is before the end date or the project has no end date
+			if !hasEnd || (runOut < forecast.EndDate) {
+				forecast.Series = append(forecast.Series, models.ForecastPoint{Date: runOut, CumulativeExpense: float64(forecast.Budget)})
+			}
+		}
+
+		if runOut != "" {
+			forecast.BudgetRunOutDate = &runOut
+		}
+	}
+
+	sort.Slice(forecast.Series, func(i, j int) bool {
+		return forecast.Series[i].Date < forecast.Series[j].Date
+	})
+
+	return forecast
+}
+
+// parseDate parse date or timestamp from the database, only the date part is used
+func parseDate(value string) (time.Time, bool) {
+	if len(value) < len(dateLayout) {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(dateLayout, value[:len(dateLayout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	return int(math.Round(truncateDate(to).Sub(truncateDate(from)).Hours() / 24))
+}
+
+func roundTwo(value float64) float64 {
+	return math.Round(value*100) / 100
+}
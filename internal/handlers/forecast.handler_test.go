@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildForecast(t *testing.T) {
+	date := func(value string) sql.NullString {
+		return sql.NullString{String: value, Valid: value != ""}
+	}
+	day := func(value string) time.Time {
+		d, _ := time.Parse(dateLayout, value)
+		return d
+	}
+	intPtr := func(value int) *int { return &value }
+	floatPtr := func(value float64) *float64 { return &value }
+	stringPtr := func(value string) *string { return &value }
+
+	tests := []struct {
+		name       string
+		project    models.Project
+		stats      models.DailyLogStats
+		cumulative []models.DailyLogStatsCumulative
+		asOf       string
+
+		startDate     string
+		elapsedDays   int
+		burnRate      float64
+		plannedDays   *int
+		remainingDays *int
+		eac           *float64
+		vac           *float64
+		plannedSpend  *float64
+		spendVariance *float64
+		runOut        *string
+		exceeded      bool
+		series        []models.ForecastPoint
+	}{
+		{
+			name:          "on track with end date",
+			project:       models.Project{StartDate: date("2024-01-01T00:00:00Z"), EndDate: date("2024-01-31T00:00:00Z")},
+			stats:         models.DailyLogStats{Budget: 6200, TotalExpense: 1000},
+			asOf:          "2024-01-10",
+			startDate:     "2024-01-01",
+			elapsedDays:   10,
+			burnRate:      100,
+			plannedDays:   intPtr(31),
+			remainingDays: intPtr(21),
+			eac:           floatPtr(3100),
+			vac:           floatPtr(3100),
+			plannedSpend:  floatPtr(2000),
+			spendVariance: floatPtr(1000),
+			runOut:        stringPtr("2024-03-02"),
+			series: []models.ForecastPoint{
+				{Date: "2024-01-10", CumulativeExpense: 1000},
+				{Date: "2024-01-31", CumulativeExpense: 3100},
+			},
+		},
+		{
+			name:    "start from first log without project dates",
+			project: models.Project{},
+			stats:   models.DailyLogStats{Budget: 1000, TotalExpense: 500},
+			cumulative: []models.DailyLogStatsCumulative{
+				{LogDate: date("2024-01-06T00:00:00Z"), Expense: 200},
+				{LogDate: date("2024-01-09T00:00:00Z"), Expense: 300},
+			},
+			asOf:        "2024-01-10",
+			startDate:   "2024-01-06",
+			elapsedDays: 5,
+			burnRate:    100,
+			runOut:      stringPtr("2024-01-15"),
+			series: []models.ForecastPoint{
+				{Date: "2024-01-10", CumulativeExpense: 500},
+				{Date: "2024-01-15", CumulativeExpense: 1000},
+			},
+		},
+		{
+			name:    "budget exceeded on a log date",
+			project: models.Project{StartDate: date("2024-01-01T00:00:00Z")},
+			stats:   models.DailyLogStats{Budget: 1000, TotalExpense: 1200},
+			cumulative: []models.DailyLogStatsCumulative{
+				{LogDate: date("2024-01-01T00:00:00Z"), Expense: 400},
+				{LogDate: date("2024-01-02T00:00:00Z"), Expense: 400},
+				{LogDate: date("2024-01-03T00:00:00Z"), Expense: 400},
+			},
+			asOf:        "2024-01-05",
+			startDate:   "2024-01-01",
+			elapsedDays: 5,
+			burnRate:    240,
+			runOut:      stringPtr("2024-01-03"),
+			exceeded:    true,
+			series: []models.ForecastPoint{
+				{Date: "2024-01-05", CumulativeExpense: 1200},
+			},
+		},
+		{
+			name:        "no logs and no dates",
+			stats:       models.DailyLogStats{Budget: 1000},
+			asOf:        "2024-01-10",
+			startDate:   "2024-01-10",
+			elapsedDays: 1,
+			series: []models.ForecastPoint{
+				{Date: "2024-01-10", CumulativeExpense: 0},
+			},
+		},
+		{
+			name:          "past the end date",
+			project:       models.Project{StartDate: date("2024-01-01T00:00:00Z"), EndDate: date("2024-01-10T00:00:00Z")},
+			stats:         models.DailyLogStats{Budget: 3000, TotalExpense: 2000},
+			asOf:          "2024-01-20",
+			startDate:     "2024-01-01",
+			elapsedDays:   20,
+			burnRate:      100,
+			plannedDays:   intPtr(10),
+			remainingDays: intPtr(0),
+			eac:           floatPtr(2000),
+			vac:           floatPtr(1000),
+			plannedSpend:  floatPtr(3000),
+			spendVariance: floatPtr(1000),
+			runOut:        stringPtr("2024-01-30"),
+			series: []models.ForecastPoint{
+				{Date: "2024-01-20", CumulativeExpense: 2000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast := buildForecast(tt.project, tt.stats, tt.cumulative, day(tt.asOf))
+
+			assert.Equal(t, tt.asOf, forecast.AsOf)
+			assert.Equal(t, tt.startDate, forecast.StartDate)
+			assert.Equal(t, tt.elapsedDays, forecast.ElapsedDays)
+			assert.Equal(t, tt.burnRate, forecast.DailyBurnRate)
+			assert.Equal(t, tt.plannedDays, forecast.PlannedDays)
+			assert.Equal(t, tt.remainingDays, forecast.RemainingDays)
+			assert.Equal(t, tt.eac, forecast.ProjectedSpendAtCompletion)
+			assert.Equal(t, tt.vac, forecast.VarianceAtCompletion)
+			assert.Equal(t, tt.plannedSpend, forecast.PlannedSpendToDate)
+			assert.Equal(t, tt.spendVariance, forecast.SpendVariance)
+			assert.Equal(t, tt.runOut, forecast.BudgetRunOutDate)
+			assert.Equal(t, tt.exceeded, forecast.BudgetExceeded)
+			assert.Equal(t, tt.series, forecast.Series)
+			assert.Nil(t, forecast.CPI)
+		})
+	}
+}
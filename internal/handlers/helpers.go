@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// statsOptions get stats options from query, amounts are converted into currency query or the reporting currency,
+// as_of query calculate the stats on the date and include_pending query also count draft and submitted logs. The
+// currency must be checked with checkCurrency before the stats query, fx_rate raise an exception without a rate
+func statsOptions(c *fiber.Ctx) (models.StatsOptions, error) {
+	opts := models.StatsOptions{Currency: utils.ReportingCurrency()}
+
+	if currency := utils.NormalizeCurrency(c.Query("currency")); currency != "" {
+		if !utils.IsCurrencyCode(currency) {
+			return opts, fiber.NewError(fiber.StatusBadRequest, "Currency must be 3 letters currency code")
+		}
+
+		opts.Currency = currency
+	}
+
+	if asOf := c.Query("as_of"); asOf != "" {
+		if err := utils.GetValidator().Var(asOf, "datetime=2006-01-02"); err != nil {
+			return opts, fiber.NewError(fiber.StatusBadRequest, "as_of must be YYYY-MM-DD")
+		}
+
+		opts.AsOf = asOf
+	}
+
+	if includePending := c.Query("include_pending"); includePending != "" {
+		include, err := strconv.ParseBool(includePending)
+		if err != nil {
+			return opts, fiber.NewError(fiber.StatusBadRequest, "include_pending must be true or false")
+		}
+
+		opts.IncludePending = include
+	}
+
+	return opts, nil
+}
+
+// checkCurrency validate currency code and make sure it can be converted into the reporting currency
+func checkCurrency(tx *sql.Tx, rateRepo repository.ExchangeRateRepository, currency string) error {
+	if !utils.IsCurrencyCode(currency) {
+		return fiber.NewError(fiber.StatusBadRequest, "Currency must be 3 letters currency code")
+	}
+
+	reporting := utils.ReportingCurrency()
+	exists, err := rateRepo.HasRate(tx, currency, reporting)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Kurs %s ke %s belum ada, tambahkan kurs terlebih dahulu", currency, reporting))
+	}
+
+	return nil
+}
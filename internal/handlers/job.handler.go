@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/jobs"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// JobHandler show the background job queue and its schedules, only for super admin
+type JobHandler struct {
+	jobRepo   repository.JobRepository
+	auditRepo repository.AuditRepository
+}
+
+func NewJobHandler(jobRepo repository.JobRepository, auditRepo repository.AuditRepository) *JobHandler {
+	return &JobHandler{jobRepo, auditRepo}
+}
+
+func (h *JobHandler) ViewJobs(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	return c.Render("pages/jobs", fiber.Map{
+		"Title": "Jobs",
+		"User":  user,
+		"Breadcrumb": models.BreadCrumb{
+			BeforeName: "Dashboard",
+			BeforeLink: "/",
+		},
+	})
+}
+
+// GetJobs get the jobs filtered by status and type query, newest first
+func (h *JobHandler) GetJobs(c *fiber.Ctx) error {
+	perPage, err := strconv.Atoi(c.Query("per_page", "10"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	filter := models.JobFilter{
+		Status: c.Query("status"),
+		Type:   c.Query("type"),
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	jobList, total, err := h.jobRepo.FindWithPagination(tx, perPage, page, filter)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithPagination(c, fiber.StatusOK, "Get Jobs", total, page, perPage, "jobs", jobList)
+}
+
+// GetJobCounts get the number of jobs per status
+func (h *JobHandler) GetJobCounts(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	counts, err := h.jobRepo.CountByStatus(tx)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Job Counts", counts)
+}
+
+func (h *JobHandler) GetJobSchedules(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	schedules, err := h.jobRepo.FindSchedules(tx)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Job Schedules", schedules)
+}
+
+// RetryJob run the failed job again now with all its attempts
+func (h *JobHandler) RetryJob(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	job, err := h.jobRepo.FindByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Job not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	retried, err := h.jobRepo.Retry(tx, id, time.Now())
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !retried {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Hanya job yang gagal yang bisa diulang")
+	}
+
+	before := fiber.Map{"status": job.Status, "attempts": job.Attempts, "last_error": job.LastError}
+	after := fiber.Map{"status": models.JobPending, "attempts": 0}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditRetry, models.AuditEntityJob, id, before, after); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Retry Job")
+}
+
+// RunJobSchedule enqueue the job of the schedule now, the next scheduled run is not changed
+func (h *JobHandler) RunJobSchedule(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	schedule, err := h.jobRepo.FindSchedule(tx, name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Job schedule not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	unfinished, err := h.jobRepo.HasUnfinished(tx, schedule.Name)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if unfinished {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Job dari jadwal ini masih berjalan")
+	}
+
+	id, err := h.jobRepo.Enqueue(tx, schedule.JobType, nil, time.Now(), jobs.DefaultMaxAttempts, schedule.Name)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditRun, models.AuditEntityJobSchedule, schedule.Name, nil, fiber.Map{"job_id": id, "job_type": schedule.JobType}); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Run Job Schedule")
+}
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"math"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LineItemHandler handle income and expense items of a daily log, log income and expense are
+// recalculated from the items on every change
+type LineItemHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	lineItemRepo repository.LineItemRepository
+	categoryRepo repository.ExpenseCategoryRepository
+	budgetRepo   repository.BudgetRepository
+	lockRepo     repository.PeriodLockRepository
+	revisionRepo repository.LogRevisionRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewLineItemHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, lineItemRepo repository.LineItemRepository, categoryRepo repository.ExpenseCategoryRepository, budgetRepo repository.BudgetRepository, lockRepo repository.PeriodLockRepository, revisionRepo repository.LogRevisionRepository, auditRepo repository.AuditRepository) *LineItemHandler {
+	return &LineItemHandler{
+		projectRepo,
+		dailyLogRepo,
+		lineItemRepo,
+		categoryRepo,
+		budgetRepo,
+		lockRepo,
+		revisionRepo,
+		auditRepo,
+	}
+}
+
+func (h *LineItemHandler) GetLineItems(c *fiber.Ctx) error {
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err = h.projectRepo.FindByID(tx, projectID); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	log, err := h.dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Log not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if log.ProjectId != projectID {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log id not found on this project")
+	}
+
+	return h.respondLineItems(c, tx, logId, "Get Line Items")
+}
+
+func (h *LineItemHandler) CreateLineItem(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	itemInput := new(models.LineItemInput)
+	if err := c.BodyParser(itemInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	log, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log data on project not found/ User is not log owner")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := ensureLogEditable(log); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := ensurePeriodOpen(tx, h.lockRepo, projectID, log.LogDate); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := h.validateLineItem(tx, itemInput, 0); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	itemInput.DailyLogId = logId
+	if err := h.lineItemRepo.Create(tx, itemInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.dailyLogRepo.UpdateTotalsFromItems(tx, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := reopenLog(tx, h.dailyLogRepo, log); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// item change is a new log version when the log totals change
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, log, logId, user.Id, models.LogRevisionUpdated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityLineItem, itemInput.Id, nil, itemInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondLineItems(c, tx, logId, "Create Line Item")
+}
+
+func (h *LineItemHandler) UpdateLineItem(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	itemId, err := strconv.Atoi(c.Params("item_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid item ID")
+	}
+
+	itemInput := new(models.LineItemInput)
+	if err := c.BodyParser(itemInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	log, item, err := h.findOwnLineItem(tx, projectID, logId, itemId, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// the item keep its category even when the category already deactivated
+	if err := h.validateLineItem(tx, itemInput, int(item.CategoryId.Int64)); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	itemInput.DailyLogId = logId
+	if err := h.lineItemRepo.Update(tx, itemInput, itemId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.dailyLogRepo.UpdateTotalsFromItems(tx, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := reopenLog(tx, h.dailyLogRepo, log); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// item change is a new log version when the log totals change
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, log, logId, user.Id, models.LogRevisionUpdated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdate, models.AuditEntityLineItem, itemId, item, itemInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondLineItems(c, tx, logId, "Update Line Item")
+}
+
+func (h *LineItemHandler) DeleteLineItem(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	itemId, err := strconv.Atoi(c.Params("item_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid item ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	log, item, err := h.findOwnLineItem(tx, projectID, logId, itemId, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := h.lineItemRepo.Delete(tx, itemId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.dailyLogRepo.UpdateTotalsFromItems(tx, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := reopenLog(tx, h.dailyLogRepo, log); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// item change is a new log version when the log totals change
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, log, logId, user.Id, models.LogRevisionUpdated); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityLineItem, itemId, item, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return h.respondLineItems(c, tx, logId, "Delete Line Item")
+}
+
+// findOwnLineItem get line item and its editable log that owned by the user, the log must not be in a locked month
+func (h *LineItemHandler) findOwnLineItem(tx *sql.Tx, projectID int, logId int, itemId int, userId int) (models.DailyLog, models.LineItem, error) {
+	log, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return log, models.LineItem{}, fiber.NewError(fiber.StatusBadRequest, "Log data on project not found/ User is not log owner")
+		}
+
+		return log, models.LineItem{}, err
+	}
+
+	if err := ensureLogEditable(log); err != nil {
+		return log, models.LineItem{}, err
+	}
+
+	if err := ensurePeriodOpen(tx, h.lockRepo, projectID, log.LogDate); err != nil {
+		return log, models.LineItem{}, err
+	}
+
+	item, err := h.lineItemRepo.FindByID(tx, itemId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return log, item, fiber.NewError(fiber.StatusNotFound, "Line item not found")
+		}
+
+		return log, item, err
+	}
+
+	if item.DailyLogId != logId {
+		return log, item, fiber.NewError(fiber.StatusNotFound, "Line item not found")
+	}
+
+	return log, item, nil
+}
+
+// validateLineItem validate input and calculate the amount, expense item must have active category
+// except currentCategory which is the category the item already has
+func (h *LineItemHandler) validateLineItem(tx *sql.Tx, itemInput *models.LineItemInput, currentCategory int) error {
+	if err := utils.ValidateStruct(itemInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Field() {
+			case "Type":
+				return fiber.NewError(fiber.StatusBadRequest, "Type must be income or expense")
+			case "CategoryId":
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid category")
+			case "Description":
+				return fiber.NewError(fiber.StatusBadRequest, "Description max 255 characters")
+			case "Quantity":
+				return fiber.NewError(fiber.StatusBadRequest, "Quantity must be more than 0")
+			case "UnitPrice":
+				return fiber.NewError(fiber.StatusBadRequest, "Unit price must be 0 or more")
+			}
+		}
+	}
+
+	if (itemInput.Type == models.LineItemExpense) && (itemInput.CategoryId == 0) {
+		return fiber.NewError(fiber.StatusBadRequest, "Category is required for expense item")
+	}
+
+	if itemInput.CategoryId != 0 {
+		category, err := h.categoryRepo.FindByID(tx, itemInput.CategoryId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fiber.NewError(fiber.StatusBadRequest, "Category not found")
+			}
+
+			return err
+		}
+
+		if !category.IsActive && (category.Id != currentCategory) {
+			return fiber.NewError(fiber.StatusBadRequest, "Category is not active")
+		}
+	}
+
+	amount, err := lineItemAmount(itemInput.Quantity, itemInput.UnitPrice)
+	if err != nil {
+		return err
+	}
+	itemInput.Amount = amount
+
+	return nil
+}
+
+// lineItemAmount calculate quantity * unit price rounded to the nearest unit, an amount that does not fit the amount
+// column is rejected
+func lineItemAmount(quantity float64, unitPrice int) (int, error) {
+	amount := math.Round(quantity * float64(unitPrice))
+	if amount >= math.MaxInt64 {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Amount is too large")
+	}
+
+	return int(amount), nil
+}
+
+// respondLineItems respond with all items of the log and the log totals
+func (h *LineItemHandler) respondLineItems(c *fiber.Ctx, tx *sql.Tx, logId int, message string) error {
+	items, err := h.lineItemRepo.FindByLog(tx, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	log, err := h.dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, message, fiber.Map{
+		"items":   items,
+		"income":  log.Income,
+		"expense": log.Expense,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineItemAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		quantity  float64
+		unitPrice int
+		amount    int
+		tooLarge  bool
+	}{
+		{"whole quantity", 3, 15000, 45000, false},
+		{"fraction is rounded", 1.5, 333, 500, false},
+		{"half is rounded up", 0.5, 5, 3, false},
+		{"free item", 2, 0, 0, false},
+		{"largest quantity", 9999999999.99, 100000, 999999999999000, false},
+		{"overflow", 2, math.MaxInt64, 0, true},
+		{"overflow by quantity", 9999999999.99, math.MaxInt64 / 1000, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := lineItemAmount(tt.quantity, tt.unitPrice)
+			if tt.tooLarge {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.amount, amount)
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxLogImportRows limit rows of one daily log import file
+const maxLogImportRows = 5000
+
+// LogImportHandler import daily logs in bulk from csv or xlsx file
+type LogImportHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	budgetRepo   repository.BudgetRepository
+	rateRepo     repository.ExchangeRateRepository
+	lockRepo     repository.PeriodLockRepository
+	revisionRepo repository.LogRevisionRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewLogImportHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, rateRepo repository.ExchangeRateRepository, lockRepo repository.PeriodLockRepository, revisionRepo repository.LogRevisionRepository, auditRepo repository.AuditRepository) *LogImportHandler {
+	return &LogImportHandler{
+		projectRepo,
+		dailyLogRepo,
+		budgetRepo,
+		rateRepo,
+		lockRepo,
+		revisionRepo,
+		auditRepo,
+	}
+}
+
+// ImportDailyLogs import logs from csv or xlsx file with a header row. Columns are matched to the fields by header name
+// or by mapping form value, a json of field to column name e.g. {"log_date":"Tanggal"}. Every row is validated first
+// and the report of the row errors is returned, with dry_run=true nothing is saved, otherwise all rows are saved in one
+// transaction only when no row has error
+func (h *LogImportHandler) ImportDailyLogs(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	dryRun := false
+	if value := c.FormValue("dry_run"); value != "" {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "dry_run must be true or false")
+		}
+	}
+
+	mapping := map[string]string{}
+	if value := c.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "mapping must be json of field to column name")
+		}
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "CSV or XLSX file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer file.Close()
+
+	rows, err := utils.ReadSpreadsheet(file, fileHeader.Filename, maxLogImportRows)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if len(rows) < 2 {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "File has no daily log")
+	}
+
+	columns, columnNames, err := logImportColumns(rows[0], mapping)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	// rolled back on error response, so a failed row never leave the other rows saved
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
+
+	// check if user is project owner
+	project, err := h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	report := models.LogImportReport{DryRun: dryRun, Columns: columnNames, RowErrors: []models.LogImportRowError{}}
+	logs := []models.DailyLogInput{}
+	dateRows := map[string]int{}
+	currencyErrors := map[string]error{}
+
+	for i, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+
+		report.TotalRows++
+		rowNumber := i + 2
+		logInput, rowErrors := parseLogImportRow(row, columns, projectID, project.Currency)
+
+		if logInput.LogDate != "" {
+			if previous, exists := dateRows[logInput.LogDate]; exists {
+				rowErrors = append(rowErrors, fmt.Sprintf("log_date %s sama dengan baris %d", logInput.LogDate, previous))
+			} else {
+				dateRows[logInput.LogDate] = rowNumber
+
+				// one log per date, the same rule as creating a log
+				existingLog, err := h.dailyLogRepo.FindByDate(tx, logInput.LogDate, projectID)
+				if (err != nil) && (err != sql.ErrNoRows) {
+					return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+				}
+
+				if existingLog.Id != 0 {
+					rowErrors = append(rowErrors, "Daily log with selected date already exist")
+				}
+			}
+
+			if err := ensurePeriodOpen(tx, h.lockRepo, projectID, logInput.LogDate); err != nil {
+				if utils.ErrorStatus(err, fiber.StatusInternalServerError) == fiber.StatusInternalServerError {
+					return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+				}
+
+				rowErrors = append(rowErrors, err.Error())
+			}
+		}
+
+		if logInput.Currency != "" {
+			currencyErr, checked := currencyErrors[logInput.Currency]
+			if !checked {
+				currencyErr = checkCurrency(tx, h.rateRepo, logInput.Currency)
+				if (currencyErr != nil) && (utils.ErrorStatus(currencyErr, fiber.StatusInternalServerError) == fiber.StatusInternalServerError) {
+					return utils.ErrorJSON(c, fiber.StatusInternalServerError, currencyErr.Error())
+				}
+
+				currencyErrors[logInput.Currency] = currencyErr
+			}
+
+			if currencyErr != nil {
+				rowErrors = append(rowErrors, currencyErr.Error())
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			report.RowErrors = append(report.RowErrors, models.LogImportRowError{Row: rowNumber, LogDate: logInput.LogDate, Errors: rowErrors})
+			continue
+		}
+
+		report.ValidRows++
+		logs = append(logs, logInput)
+	}
+
+	if report.TotalRows == 0 {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "File has no daily log")
+	}
+
+	report.Valid = len(report.RowErrors) == 0
+
+	if dryRun {
+		return utils.RespondWithData(c, fiber.StatusOK, "Validate Daily Log Import", report)
+	}
+
+	if !report.Valid {
+		return utils.ErrorJSONWithData(c, fiber.StatusBadRequest, fmt.Sprintf("%d baris tidak valid, tidak ada log yang diimport", len(report.RowErrors)), report)
+	}
+
+	logDates := []string{}
+	for i := range logs {
+		if err := h.dailyLogRepo.Create(tx, &logs[i]); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		createdLog, err := h.dailyLogRepo.FindByDate(tx, logs[i].LogDate, projectID)
+		if err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, models.DailyLog{}, createdLog.Id, user.Id, models.LogRevisionCreated); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		logDates = append(logDates, logs[i].LogDate)
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	importData := fiber.Map{"project_id": projectID, "file": fileHeader.Filename, "imported": len(logs), "log_dates": logDates}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditImport, models.AuditEntityDailyLog, nil, nil, importData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogsImported, projectID, 0, events.LogsImportedData{Filename: fileHeader.Filename, Imported: len(logs)}); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	report.Imported = len(logs)
+	return utils.RespondWithData(c, fiber.StatusOK, "Import Daily Logs", report)
+}
+
+// logImportColumns find the column index of every field, a field without mapping use the column with the field name.
+// log_date column is required, the other missing columns are read as empty
+func logImportColumns(header []string, mapping map[string]string) (map[string]int, map[string]string, error) {
+	names := make([]string, len(header))
+	headerIndex := map[string]int{}
+	for i, name := range header {
+		names[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+
+		name = strings.ToLower(names[i])
+		if _, exists := headerIndex[name]; !exists {
+			headerIndex[name] = i
+		}
+	}
+
+	for field := range mapping {
+		if !isLogImportField(field) {
+			return nil, nil, fmt.Errorf("mapping field %s is unknown, fields are %s", field, strings.Join(models.LogImportFields, ", "))
+		}
+	}
+
+	columns := map[string]int{}
+	columnNames := map[string]string{}
+	for _, field := range models.LogImportFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+
+		index, exists := headerIndex[strings.ToLower(strings.TrimSpace(name))]
+		if !exists {
+			if mapped || (field == "log_date") {
+				return nil, nil, fmt.Errorf("column %s for %s is not found in the file header", name, field)
+			}
+
+			continue
+		}
+
+		columns[field] = index
+		columnNames[field] = names[index]
+	}
+
+	return columns, columnNames, nil
+}
+
+// parseLogImportRow read a row into log input and validate it, empty amount is 0 and empty currency is the project
+// currency
+func parseLogImportRow(row []string, columns map[string]int, projectID int, projectCurrency string) (models.DailyLogInput, []string) {
+	rowErrors := []string{}
+	value := func(field string) string {
+		index, exists := columns[field]
+		if !exists || (index >= len(row)) {
+			return ""
+		}
+
+		return strings.TrimSpace(row[index])
+	}
+
+	logInput := models.DailyLogInput{
+		ProjectId:   projectID,
+		Description: value("description"),
+		Issues:      value("issues"),
+		Currency:    utils.NormalizeCurrency(value("currency")),
+	}
+
+	logDate, valid := utils.SpreadsheetDate(value("log_date"))
+	if valid {
+		logInput.LogDate = logDate
+	} else if value("log_date") == "" {
+		rowErrors = append(rowErrors, "Log Date is required")
+	} else {
+		rowErrors = append(rowErrors, "Log Date must be YYYY-MM-DD")
+	}
+
+	income, valid := parseImportAmount(value("income"))
+	if !valid {
+		rowErrors = append(rowErrors, "Income must be a number more than or equal 0")
+	}
+	logInput.Income = income
+
+	expense, valid := parseImportAmount(value("expense"))
+	if !valid {
+		rowErrors = append(rowErrors, "Expense must be a number more than or equal 0")
+	}
+	logInput.Expense = expense
+
+	// log without currency use the project currency
+	if logInput.Currency == "" {
+		logInput.Currency = projectCurrency
+	}
+
+	return logInput, rowErrors
+}
+
+func parseImportAmount(value string) (int, bool) {
+	if value == "" {
+		return 0, true
+	}
+
+	amount, err := strconv.Atoi(value)
+	if (err != nil) || (amount < 0) {
+		return 0, false
+	}
+
+	return amount, true
+}
+
+func isLogImportField(field string) bool {
+	for _, known := range models.LogImportFields {
+		if field == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fiber-prjct-management-web/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogImportColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []string
+		mapping     map[string]string
+		columns     map[string]int
+		columnNames map[string]string
+		err         string
+	}{
+		{
+			name:        "field names with bom, case and spaces",
+			header:      []string{"\ufeffLog_Date", " Description ", "income", "expense"},
+			columns:     map[string]int{"log_date": 0, "description": 1, "income": 2, "expense": 3},
+			columnNames: map[string]string{"log_date": "Log_Date", "description": "Description", "income": "income", "expense": "expense"},
+		},
+		{
+			name:        "mapped columns",
+			header:      []string{"Tanggal", "Keterangan", "Pengeluaran"},
+			mapping:     map[string]string{"log_date": "tanggal", "description": "Keterangan", "expense": "Pengeluaran"},
+			columns:     map[string]int{"log_date": 0, "description": 1, "expense": 2},
+			columnNames: map[string]string{"log_date": "Tanggal", "description": "Keterangan", "expense": "Pengeluaran"},
+		},
+		{
+			name:        "first duplicate column is used",
+			header:      []string{"log_date", "income", "income"},
+			columns:     map[string]int{"log_date": 0, "income": 1},
+			columnNames: map[string]string{"log_date": "log_date", "income": "income"},
+		},
+		{
+			name:   "log date is required",
+			header: []string{"description", "income"},
+			err:    "column log_date for log_date is not found in the file header",
+		},
+		{
+			name:    "mapped column must exist",
+			header:  []string{"log_date"},
+			mapping: map[string]string{"income": "Pemasukan"},
+			err:     "column Pemasukan for income is not found in the file header",
+		},
+		{
+			name:    "unknown mapping field",
+			header:  []string{"log_date"},
+			mapping: map[string]string{"amount": "Jumlah"},
+			err:     "mapping field amount is unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, columnNames, err := logImportColumns(tt.header, tt.mapping)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.columns, columns)
+			assert.Equal(t, tt.columnNames, columnNames)
+		})
+	}
+}
+
+func TestParseLogImportRow(t *testing.T) {
+	columns := map[string]int{"log_date": 0, "description": 1, "issues": 2, "income": 3, "expense": 4, "currency": 5}
+
+	tests := []struct {
+		name     string
+		row      []string
+		logInput models.DailyLogInput
+		errors   []string
+	}{
+		{
+			name: "complete row",
+			row:  []string{"2024-05-01", " Pengecoran ", "Hujan", "0", "1500000", "usd"},
+			logInput: models.DailyLogInput{
+				ProjectId: 7, LogDate: "2024-05-01", Description: "Pengecoran", Issues: "Hujan", Expense: 1500000, Currency: "USD",
+			},
+			errors: []string{},
+		},
+		{
+			name: "excel date serial, empty amounts and project currency",
+			row:  []string{"45413", "Pengecoran", "", "", "", ""},
+			logInput: models.DailyLogInput{
+				ProjectId: 7, LogDate: "2024-05-01", Description: "Pengecoran", Currency: "IDR",
+			},
+			errors: []string{},
+		},
+		{
+			name: "short row read as empty",
+			row:  []string{"2024-05-01"},
+			logInput: models.DailyLogInput{
+				ProjectId: 7, LogDate: "2024-05-01", Currency: "IDR",
+			},
+			errors: []string{},
+		},
+		{
+			name:     "missing date",
+			row:      []string{"", "Pengecoran", "", "100", "0", "IDR"},
+			logInput: models.DailyLogInput{ProjectId: 7, Description: "Pengecoran", Income: 100, Currency: "IDR"},
+			errors:   []string{"Log Date is required"},
+		},
+		{
+			name:     "invalid values",
+			row:      []string{"01/05/2024", "", "", "-5", "1.000", "IDR"},
+			logInput: models.DailyLogInput{ProjectId: 7, Currency: "IDR"},
+			errors: []string{
+				"Log Date must be YYYY-MM-DD",
+				"Income must be a number more than or equal 0",
+				"Expense must be a number more than or equal 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logInput, rowErrors := parseLogImportRow(tt.row, columns, 7, "IDR")
+
+			assert.Equal(t, tt.logInput, logInput)
+			assert.Equal(t, tt.errors, rowErrors)
+		})
+	}
+}
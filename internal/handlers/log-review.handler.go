@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LogReviewHandler handle the approval of daily logs. The project owner submit the log to a reviewer (an admin or
+// super admin other than the owner, or any super admin when not assigned) who approve or reject it
+type LogReviewHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	reviewRepo   repository.LogReviewRepository
+	budgetRepo   repository.BudgetRepository
+	userRepo     repository.UserRepository
+	lockRepo     repository.PeriodLockRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewLogReviewHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, reviewRepo repository.LogReviewRepository, budgetRepo repository.BudgetRepository, userRepo repository.UserRepository, lockRepo repository.PeriodLockRepository, auditRepo repository.AuditRepository) *LogReviewHandler {
+	return &LogReviewHandler{
+		projectRepo,
+		dailyLogRepo,
+		reviewRepo,
+		budgetRepo,
+		userRepo,
+		lockRepo,
+		auditRepo,
+	}
+}
+
+// SubmitLog submit draft or rejected log for review, reviewer_id is optional
+func (h *LogReviewHandler) SubmitLog(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	submitInput := new(models.SubmitLogInput)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(submitInput); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	log, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log data on project not found/ User is not log owner")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if (log.Status != models.LogStatusDraft) && (log.Status != models.LogStatusRejected) {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log sudah "+log.Status)
+	}
+
+	if submitInput.ReviewerId != 0 {
+		if submitInput.ReviewerId == user.Id {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Reviewer tidak boleh pemilik log")
+		}
+
+		reviewer, err := h.userRepo.FindByID(tx, submitInput.ReviewerId)
+		if err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Reviewer not found")
+		}
+
+		if (reviewer.Role != 1) && (reviewer.Role != 3) {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Reviewer harus admin atau superadmin")
+		}
+	}
+
+	if err := h.dailyLogRepo.UpdateStatus(tx, logId, models.LogStatusSubmitted, submitInput.ReviewerId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.reviewRepo.Create(tx, logId, user.Id, models.LogStatusSubmitted, ""); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, models.AuditSubmit, log, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Submit Daily Log")
+}
+
+// ApproveLog approve submitted log, the log is counted on the stats
+func (h *LogReviewHandler) ApproveLog(c *fiber.Ctx) error {
+	return h.reviewLog(c, models.LogStatusApproved)
+}
+
+// RejectLog reject submitted log with a comment, the owner can edit and submit the log again
+func (h *LogReviewHandler) RejectLog(c *fiber.Ctx) error {
+	return h.reviewLog(c, models.LogStatusRejected)
+}
+
+func (h *LogReviewHandler) reviewLog(c *fiber.Ctx, status string) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	reviewInput := new(models.ReviewLogInput)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(reviewInput); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	if err := utils.ValidateStruct(reviewInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Comment max 1000 characters")
+	}
+
+	comment := strings.TrimSpace(reviewInput.Comment)
+	if (status == models.LogStatusRejected) && (comment == "") {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Alasan penolakan wajib diisi")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	project, log, err := h.findProjectLog(tx, projectID, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if log.Status != models.LogStatusSubmitted {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log belum disubmit atau sudah direview")
+	}
+
+	if project.CreatedBy == user.Id {
+		return utils.ErrorJSON(c, fiber.StatusForbidden, "Pemilik log tidak bisa mereview log sendiri")
+	}
+
+	// log without reviewer is reviewed by any super admin
+	if (user.Role != 3) && (log.ReviewerId.Int64 != int64(user.Id)) {
+		return utils.ErrorJSON(c, fiber.StatusForbidden, "User is not the log reviewer")
+	}
+
+	// the review change the counted logs of the month
+	if err := ensurePeriodOpen(tx, h.lockRepo, projectID, log.LogDate); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	if err := h.dailyLogRepo.UpdateStatus(tx, logId, status, 0); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.reviewRepo.Create(tx, logId, user.Id, status, comment); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// approved log is counted on the budget usage
+	if status == models.LogStatusApproved {
+		if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	action := models.AuditApprove
+	if status == models.LogStatusRejected {
+		action = models.AuditReject
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, action, log, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Daily log "+status)
+}
+
+// GetLogReviews get review history of the log, only for the project owner, the reviewer and super admin
+func (h *LogReviewHandler) GetLogReviews(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, log, err := h.findProjectLog(tx, projectID, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	isReviewer := (user.Role == 3) || (log.ReviewerId.Int64 == int64(user.Id))
+	if (project.CreatedBy != user.Id) && !isReviewer {
+		return utils.ErrorJSON(c, fiber.StatusForbidden, "User is not the log owner or reviewer")
+	}
+
+	reviews, err := h.reviewRepo.FindByLog(tx, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Log Reviews", fiber.Map{
+		"status":     log.Status,
+		"can_review": isReviewer && (project.CreatedBy != user.Id) && (log.Status == models.LogStatusSubmitted),
+		"reviews":    reviews,
+	})
+}
+
+// GetPendingLogReviews get submitted logs assigned to the user, super admin get all submitted logs
+func (h *LogReviewHandler) GetPendingLogReviews(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	if user.Role == 3 {
+		user.Id = 0
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	pending, err := h.reviewRepo.FindPending(tx, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Pending Log Reviews", pending)
+}
+
+// GetLogReviewers get users that can be assigned to review the user logs
+func (h *LogReviewHandler) GetLogReviewers(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	reviewers, err := h.reviewRepo.FindReviewers(tx, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Log Reviewers", reviewers)
+}
+
+// findProjectLog get the project and its log
+func (h *LogReviewHandler) findProjectLog(tx *sql.Tx, projectID int, logId int) (models.Project, models.DailyLog, error) {
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return project, models.DailyLog{}, fiber.NewError(fiber.StatusBadRequest, "Project not found")
+		}
+
+		return project, models.DailyLog{}, err
+	}
+
+	log, err := h.dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return project, log, fiber.NewError(fiber.StatusNotFound, "Log not found")
+		}
+
+		return project, log, err
+	}
+
+	if log.ProjectId != projectID {
+		return project, log, fiber.NewError(fiber.StatusBadRequest, "Log id not found on this project")
+	}
+
+	return project, log, nil
+}
+
+// ensureLogEditable log waiting for review can not be changed
+func ensureLogEditable(log models.DailyLog) error {
+	if log.Status == models.LogStatusSubmitted {
+		return fiber.NewError(fiber.StatusBadRequest, "Log sedang direview, tidak bisa diubah")
+	}
+
+	return nil
+}
+
+// reopenLog move changed approved or rejected log back to draft, so the change is not counted until approved again
+func reopenLog(tx *sql.Tx, dailyLogRepo repository.DailyLogRepository, log models.DailyLog) error {
+	if (log.Status != models.LogStatusApproved) && (log.Status != models.LogStatusRejected) {
+		return nil
+	}
+
+	return dailyLogRepo.UpdateStatus(tx, log.Id, models.LogStatusDraft, 0)
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/events"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"reflect"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// LogRevisionHandler handle the version history of daily logs, every change of a log is stored as a new version
+type LogRevisionHandler struct {
+	projectRepo  repository.ProjectRepository
+	dailyLogRepo repository.DailyLogRepository
+	revisionRepo repository.LogRevisionRepository
+	budgetRepo   repository.BudgetRepository
+	rateRepo     repository.ExchangeRateRepository
+	lockRepo     repository.PeriodLockRepository
+	auditRepo    repository.AuditRepository
+}
+
+func NewLogRevisionHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, revisionRepo repository.LogRevisionRepository, budgetRepo repository.BudgetRepository, rateRepo repository.ExchangeRateRepository, lockRepo repository.PeriodLockRepository, auditRepo repository.AuditRepository) *LogRevisionHandler {
+	return &LogRevisionHandler{
+		projectRepo,
+		dailyLogRepo,
+		revisionRepo,
+		budgetRepo,
+		rateRepo,
+		lockRepo,
+		auditRepo,
+	}
+}
+
+// GetLogHistory get all versions of the log with the changed fields of every version, newest first
+func (h *LogRevisionHandler) GetLogHistory(c *fiber.Ctx) error {
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err = h.projectRepo.FindByID(tx, projectID); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	log, err := h.dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Log not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if log.ProjectId != projectID {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log id not found on this project")
+	}
+
+	revisions, err := h.revisionRepo.FindByLog(tx, logId)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Log History", fiber.Map{
+		"current":   logSnapshot(log),
+		"revisions": revisions,
+	})
+}
+
+// RestoreLogVersion set the log data back to a previous version as a new version. The current attachment is kept
+// because the old file may already be deleted, and income and expense of log with line items stay derived from the items
+func (h *LogRevisionHandler) RestoreLogVersion(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	logId, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid log ID")
+	}
+
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid version")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollbackOnError(tx, c)
+
+	logData, err := h.dailyLogRepo.FindIfProjectAndLogOwner(tx, projectID, logId, user.Id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Log data on project not found/ User is not log owner")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	revision, err := h.revisionRepo.FindByVersion(tx, logId, version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Log version not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	snapshot := revision.Snapshot
+	if err := ensureLogEditable(logData); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	for _, date := range []string{logData.LogDate, snapshot.LogDate} {
+		if err := ensurePeriodOpen(tx, h.lockRepo, projectID, date); err != nil {
+			return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+	}
+
+	checkLogDate, err := h.dailyLogRepo.FindByDate(tx, snapshot.LogDate, projectID)
+	if (err != nil) && (err != sql.ErrNoRows) {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if (checkLogDate.Id != logId) && (checkLogDate.Id != 0) {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Daily log with the version date already exist")
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, snapshot.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	logInput := models.DailyLogInput{
+		ProjectId:     projectID,
+		LogDate:       snapshot.LogDate,
+		Description:   snapshot.Description,
+		Issues:        snapshot.Issues,
+		Income:        snapshot.Income,
+		Expense:       snapshot.Expense,
+		Currency:      snapshot.Currency,
+		File:          logData.File.String,
+		FileStatus:    logData.FileStatus.String,
+		FileThumbnail: logData.FileThumbnail.String,
+		FileSize:      logData.FileSize,
+	}
+
+	if logData.HasLineItems {
+		logInput.Income = logData.Income
+		logInput.Expense = logData.Expense
+	}
+
+	if err := h.dailyLogRepo.Update(tx, &logInput, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := reopenLog(tx, h.dailyLogRepo, logData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogRevision(tx, h.revisionRepo, h.dailyLogRepo, logData, logId, user.Id, models.LogRevisionRestored); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordLogAudit(c, tx, h.auditRepo, h.dailyLogRepo, models.AuditRestore, logData, logId); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.LogUpdated, projectID, logId, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, projectID); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Restore Daily Log Version "+strconv.Itoa(version))
+}
+
+// recordLogRevision store the log after a change as a new version with the changed fields. before is the log before
+// the change (empty when the log is created), it is stored first as the baseline of log that has no version yet.
+// A change that does not change the snapshot is not stored unless it is a restore
+func recordLogRevision(tx *sql.Tx, revisionRepo repository.LogRevisionRepository, dailyLogRepo repository.DailyLogRepository, before models.DailyLog, logId int, userId int, action string) error {
+	var previous *models.DailyLogSnapshot
+
+	latest, err := revisionRepo.FindLatest(tx, logId)
+	if err == nil {
+		previous = &latest.Snapshot
+	} else if err != sql.ErrNoRows {
+		return err
+	} else if before.Id != 0 {
+		baseline := models.DailyLogRevision{
+			DailyLogId: logId,
+			Action:     models.LogRevisionCreated,
+			Snapshot:   logSnapshot(before),
+		}
+
+		if err := revisionRepo.Create(tx, &baseline); err != nil {
+			return err
+		}
+
+		previous = &baseline.Snapshot
+	}
+
+	current, err := dailyLogRepo.FindByID(tx, logId)
+	if err != nil {
+		return err
+	}
+
+	revision := models.DailyLogRevision{
+		DailyLogId: logId,
+		Action:     action,
+		Snapshot:   logSnapshot(current),
+		UserId:     sql.NullInt64{Int64: int64(userId), Valid: userId != 0},
+	}
+
+	if previous != nil {
+		revision.Changes, err = diffSnapshots(*previous, revision.Snapshot)
+		if err != nil {
+			return err
+		}
+
+		if (len(revision.Changes) == 0) && (action != models.LogRevisionRestored) {
+			return nil
+		}
+	}
+
+	return revisionRepo.Create(tx, &revision)
+}
+
+func logSnapshot(log models.DailyLog) models.DailyLogSnapshot {
+	logDate := log.LogDate
+	if len(logDate) > len(dateLayout) {
+		logDate = logDate[:len(dateLayout)]
+	}
+
+	return models.DailyLogSnapshot{
+		LogDate:     logDate,
+		Description: log.Description,
+		Issues:      log.Issues,
+		Income:      log.Income,
+		Expense:     log.Expense,
+		Currency:    log.Currency,
+		File:        log.File.String,
+		FileSize:    log.FileSize,
+	}
+}
+
+// diffSnapshots get the old and new value of every field that changed, keyed by the json field name
+func diffSnapshots(old models.DailyLogSnapshot, new models.DailyLogSnapshot) (map[string]models.FieldChange, error) {
+	oldFields, err := snapshotFields(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := snapshotFields(new)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.FieldChange{}
+	for field, value := range newFields {
+		if !reflect.DeepEqual(oldFields[field], value) {
+			changes[field] = models.FieldChange{Old: oldFields[field], New: value}
+		}
+	}
+
+	return changes, nil
+}
+
+func snapshotFields(snapshot models.DailyLogSnapshot) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
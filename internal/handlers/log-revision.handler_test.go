@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	base := models.DailyLogSnapshot{
+		LogDate:     "2024-05-01",
+		Description: "Pengecoran lantai 2",
+		Income:      0,
+		Expense:     1500000,
+		Currency:    "IDR",
+		File:        "web/uploads/1/2024-05-01/nota.pdf",
+		FileSize:    2048,
+	}
+
+	tests := []struct {
+		name    string
+		old     models.DailyLogSnapshot
+		new     func(snapshot models.DailyLogSnapshot) models.DailyLogSnapshot
+		changes map[string]models.FieldChange
+	}{
+		{
+			name:    "no change",
+			old:     base,
+			new:     func(s models.DailyLogSnapshot) models.DailyLogSnapshot { return s },
+			changes: map[string]models.FieldChange{},
+		},
+		{
+			name: "text field",
+			old:  base,
+			new: func(s models.DailyLogSnapshot) models.DailyLogSnapshot {
+				s.Issues = "Hujan"
+				return s
+			},
+			changes: map[string]models.FieldChange{
+				"issues": {Old: "", New: "Hujan"},
+			},
+		},
+		{
+			name: "numbers are compared by value",
+			old:  base,
+			new: func(s models.DailyLogSnapshot) models.DailyLogSnapshot {
+				s.Expense = 1750000
+				s.Income = 100
+				return s
+			},
+			changes: map[string]models.FieldChange{
+				"expense": {Old: float64(1500000), New: float64(1750000)},
+				"income":  {Old: float64(0), New: float64(100)},
+			},
+		},
+		{
+			name: "file removed",
+			old:  base,
+			new: func(s models.DailyLogSnapshot) models.DailyLogSnapshot {
+				s.File = ""
+				s.FileSize = 0
+				return s
+			},
+			changes: map[string]models.FieldChange{
+				"file":      {Old: "web/uploads/1/2024-05-01/nota.pdf", New: ""},
+				"file_size": {Old: float64(2048), New: float64(0)},
+			},
+		},
+		{
+			name: "from empty snapshot only set fields change",
+			old:  models.DailyLogSnapshot{},
+			new: func(s models.DailyLogSnapshot) models.DailyLogSnapshot {
+				return models.DailyLogSnapshot{LogDate: "2024-05-02", Currency: "USD"}
+			},
+			changes: map[string]models.FieldChange{
+				"log_date": {Old: "", New: "2024-05-02"},
+				"currency": {Old: "", New: "USD"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := diffSnapshots(tt.old, tt.new(base))
+			require.NoError(t, err)
+			assert.Equal(t, tt.changes, changes)
+		})
+	}
+}
+
+func TestLogSnapshot(t *testing.T) {
+	snapshot := logSnapshot(models.DailyLog{
+		LogDate:     "2024-05-01T00:00:00Z",
+		Description: "Pengecoran",
+		Expense:     1000,
+		Currency:    "IDR",
+		File:        sql.NullString{String: "web/uploads/1/2024-05-01/nota.pdf", Valid: true},
+		FileSize:    2048,
+		FileStatus:  sql.NullString{String: "clean", Valid: true},
+	})
+
+	assert.Equal(t, models.DailyLogSnapshot{
+		LogDate:     "2024-05-01",
+		Description: "Pengecoran",
+		Expense:     1000,
+		Currency:    "IDR",
+		File:        "web/uploads/1/2024-05-01/nota.pdf",
+		FileSize:    2048,
+	}, snapshot)
+}
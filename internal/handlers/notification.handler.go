@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NotificationHandler list and read the in-app notifications of the request user
+type NotificationHandler struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationHandler(notificationRepo repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo}
+}
+
+// GetNotifications get the notifications of the user newest first, unread=true only get unread notifications
+func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	perPage, err := strconv.Atoi(c.Query("per_page", "10"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	page, err := strconv.Atoi(c.Query("page", "1"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid page value")
+	}
+
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	notifications, total, err := h.notificationRepo.FindWithPagination(tx, user.Id, perPage, page, unreadOnly)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithPagination(c, fiber.StatusOK, "Get Notifications", total, page, perPage, "notifications", notifications)
+}
+
+// GetUnreadNotificationCount get the number shown on the navbar bell
+func (h *NotificationHandler) GetUnreadNotificationCount(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	unread, err := h.notificationRepo.CountUnread(tx, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Unread Notification Count", fiber.Map{"unread": unread})
+}
+
+func (h *NotificationHandler) ReadNotification(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	read, err := h.notificationRepo.MarkRead(tx, id, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !read {
+		return utils.ErrorJSON(c, fiber.StatusNotFound, "Notification not found")
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Read Notification")
+}
+
+func (h *NotificationHandler) ReadAllNotifications(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err := h.notificationRepo.MarkAllRead(tx, user.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Read All Notifications")
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// PeriodLockHandler handle closed accounting months. The project owner or super admin lock a month of the project,
+// super admin also lock a month of every project and is the only one that can reopen a month
+type PeriodLockHandler struct {
+	projectRepo repository.ProjectRepository
+	lockRepo    repository.PeriodLockRepository
+	auditRepo   repository.AuditRepository
+}
+
+func NewPeriodLockHandler(projectRepo repository.ProjectRepository, lockRepo repository.PeriodLockRepository, auditRepo repository.AuditRepository) *PeriodLockHandler {
+	return &PeriodLockHandler{
+		projectRepo,
+		lockRepo,
+		auditRepo,
+	}
+}
+
+// GetPeriodLocks get locks and history of the project with the global locks, only the global ones without project_id
+func (h *PeriodLockHandler) GetPeriodLocks(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := periodLockProjectID(c)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if projectID != 0 {
+		if _, err := h.projectRepo.FindByID(tx, projectID); err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+			}
+
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	locks, err := h.lockRepo.FindLocks(tx, projectID)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	events, err := h.lockRepo.FindEvents(tx, projectID)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Period Locks", fiber.Map{
+		"can_reopen": user.Role == 3,
+		"locks":      locks,
+		"events":     events,
+	})
+}
+
+// LockPeriod lock a month (YYYY-MM) of the project, or of every project without project_id
+func (h *PeriodLockHandler) LockPeriod(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := periodLockProjectID(c)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	lockInput := new(models.PeriodLockInput)
+	if err := c.BodyParser(lockInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := utils.ValidateStruct(lockInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Period must be YYYY-MM")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if projectID != 0 {
+		// admin can only lock own project
+		if user.Role == 3 {
+			_, err = h.projectRepo.FindByID(tx, projectID)
+		} else {
+			_, err = h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id)
+		}
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found/ User is not project owner")
+			}
+
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	locked, err := h.lockRepo.Lock(tx, projectID, lockInput.Period, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !locked {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Periode "+lockInput.Period+" sudah dikunci")
+	}
+
+	if err := h.lockRepo.CreateEvent(tx, projectID, lockInput.Period, models.PeriodLocked, "", user.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// project_id 0 is the global lock
+	lockData := fiber.Map{"project_id": projectID, "period": lockInput.Period}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditLock, models.AuditEntityPeriodLock, nil, nil, lockData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Lock Period")
+}
+
+// ReopenPeriod unlock a locked month with a reason
+func (h *PeriodLockHandler) ReopenPeriod(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	reopenInput := new(models.PeriodReopenInput)
+	if err := c.BodyParser(reopenInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	reopenInput.Reason = strings.TrimSpace(reopenInput.Reason)
+	if err := utils.ValidateStruct(reopenInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Tag() {
+			case "required":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Alasan membuka periode wajib diisi")
+			case "max":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Reason max 1000 characters")
+			}
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	lock, err := h.lockRepo.FindLockByID(tx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Period lock not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.lockRepo.Delete(tx, id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := h.lockRepo.CreateEvent(tx, int(lock.ProjectId.Int64), lock.Period, models.PeriodReopened, reopenInput.Reason, user.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	reopenData := fiber.Map{"reason": reopenInput.Reason}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditReopen, models.AuditEntityPeriodLock, id, lock, reopenData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Reopen Period")
+}
+
+// periodLockProjectID get project_id param, 0 is the global lock
+func periodLockProjectID(c *fiber.Ctx) (int, error) {
+	if c.Params("project_id") == "" {
+		return 0, nil
+	}
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	return projectID, nil
+}
+
+// ensurePeriodOpen log dated in a locked month can not be created, changed or deleted. The date is the input date
+// (YYYY-MM-DD) or the log date from the database, a date that can not be parsed is rejected
+func ensurePeriodOpen(tx *sql.Tx, lockRepo repository.PeriodLockRepository, projectID int, logDate string) error {
+	date, err := time.Parse(dateLayout, logDate)
+	if err != nil {
+		if date, err = time.Parse(time.RFC3339, logDate); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Log date must be YYYY-MM-DD")
+		}
+	}
+
+	locked, err := lockRepo.IsLocked(tx, projectID, date.Format(dateLayout))
+	if err != nil {
+		return err
+	}
+
+	if locked {
+		return fiber.NewError(fiber.StatusBadRequest, "Periode "+date.Format("2006-01")+" sudah ditutup, log tidak bisa diubah")
+	}
+
+	return nil
+}
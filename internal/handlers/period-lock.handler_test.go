@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// lockedMonths is a period lock repository where the dates of the listed months are locked
+type lockedMonths struct {
+	repository.PeriodLockRepository
+	months  map[string]bool
+	checked []string
+}
+
+func (r *lockedMonths) IsLocked(tx *sql.Tx, projectId int, date string) (bool, error) {
+	r.checked = append(r.checked, date)
+	return r.months[date[:7]], nil
+}
+
+func TestEnsurePeriodOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		logDate string
+		checked string // date passed to IsLocked, empty when the date is rejected first
+		status  int    // 0 is open
+	}{
+		{"open month", "2024-06-15", "2024-06-15", 0},
+		{"locked month", "2024-05-31", "2024-05-31", fiber.StatusBadRequest},
+		{"database timestamp", "2024-05-01T00:00:00Z", "2024-05-01", fiber.StatusBadRequest},
+		{"empty date", "", "", fiber.StatusBadRequest},
+		{"short date", "2024-5-1", "", fiber.StatusBadRequest},
+		{"invalid day", "2024-02-30", "", fiber.StatusBadRequest},
+		{"trailing text", "2024-06-15abc", "", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockRepo := &lockedMonths{months: map[string]bool{"2024-05": true}}
+
+			err := ensurePeriodOpen(nil, lockRepo, 1, tt.logDate)
+			if tt.status == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.status, utils.ErrorStatus(err, fiber.StatusInternalServerError))
+			}
+
+			if tt.checked == "" {
+				assert.Empty(t, lockRepo.checked)
+			} else {
+				assert.Equal(t, []string{tt.checked}, lockRepo.checked)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"fiber-prjct-management-web/internal/events"
 	"fiber-prjct-management-web/internal/models"
 	"fiber-prjct-management-web/internal/repository"
 	"fiber-prjct-management-web/pkg/database"
 	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -15,12 +17,18 @@ import (
 type ProjectHandler struct {
 	projectRepo  repository.ProjectRepository
 	dailyLogRepo repository.DailyLogRepository
+	budgetRepo   repository.BudgetRepository
+	rateRepo     repository.ExchangeRateRepository
+	auditRepo    repository.AuditRepository
 }
 
-func NewProjectHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository) *ProjectHandler {
+func NewProjectHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, rateRepo repository.ExchangeRateRepository, auditRepo repository.AuditRepository) *ProjectHandler {
 	return &ProjectHandler{
 		projectRepo,
 		dailyLogRepo,
+		budgetRepo,
+		rateRepo,
+		auditRepo,
 	}
 }
 
@@ -114,12 +122,21 @@ func (h *ProjectHandler) GetProjectsStats(c *fiber.Ctx) error {
 		user.Id = 0
 	}
 
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
 	tx, err := database.DB.Begin()
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 	defer utils.CommitOrRollback(tx, c)
 
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
 	// project stats by status
 	projectStatusStats, err := h.projectRepo.FindProjectStatusStats(tx, user.Id)
 	if err != nil {
@@ -127,7 +144,7 @@ func (h *ProjectHandler) GetProjectsStats(c *fiber.Ctx) error {
 	}
 
 	// project stats general
-	projectStats, err := h.projectRepo.FindProjectsStats(tx, user.Id)
+	projectStats, err := h.projectRepo.FindProjectsStats(tx, user.Id, opts)
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
@@ -138,6 +155,37 @@ func (h *ProjectHandler) GetProjectsStats(c *fiber.Ctx) error {
 	})
 }
 
+// GetProjectsCategoryStats get expense per category of all projects owned by the user, super admin get all projects
+func (h *ProjectHandler) GetProjectsCategoryStats(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	if user.Role == 3 {
+		user.Id = 0
+	}
+
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	categoryStats, err := h.dailyLogRepo.FindCategoryStats(tx, 0, user.Id, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Projects Category Stats", categoryStats)
+}
+
 func (h *ProjectHandler) CreateProject(c *fiber.Ctx) error {
 
 	userData := c.Locals("user").(models.UserSession)
@@ -165,6 +213,16 @@ func (h *ProjectHandler) CreateProject(c *fiber.Ctx) error {
 	}
 	defer utils.CommitOrRollback(tx, c)
 
+	// project without currency use the reporting currency
+	projectInput.Currency = utils.NormalizeCurrency(projectInput.Currency)
+	if projectInput.Currency == "" {
+		projectInput.Currency = utils.ReportingCurrency()
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, projectInput.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
 	// input id admin created
 	projectInput.CreatedBy = userData.Id
 
@@ -172,6 +230,19 @@ func (h *ProjectHandler) CreateProject(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
+	project, err := h.projectRepo.FindByID(tx, projectInput.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityProject, project.Id, nil, project); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.ProjectChanged, project.Id, project.Id, project); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Create Project")
 }
 
@@ -205,7 +276,7 @@ func (h *ProjectHandler) EditProject(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	defer utils.CommitOrRollbackOnError(tx, c)
 
 	checkProjectOwner, err := h.projectRepo.FindIfProjectOwner(tx, projectId, user.Id)
 	if err != nil {
@@ -218,15 +289,63 @@ func (h *ProjectHandler) EditProject(c *fiber.Ctx) error {
 
 	projectInput.CreatedBy = checkProjectOwner.CreatedBy
 
+	// keep the project currency when not set
+	projectInput.Currency = utils.NormalizeCurrency(projectInput.Currency)
+	if projectInput.Currency == "" {
+		projectInput.Currency = checkProjectOwner.Currency
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, projectInput.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// budget change is recorded as revision, the old budget is kept until the revision is approved
+	message := "Edit Project"
+	if projectInput.Budget != checkProjectOwner.Budget {
+		revision, err := requestBudgetRevision(tx, h.budgetRepo, checkProjectOwner, projectInput.Budget, projectInput.BudgetReason, user.Id)
+		if err != nil {
+			return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+		}
+
+		if revision.Status == models.BudgetRevisionPending {
+			projectInput.Budget = checkProjectOwner.Budget
+			message = "Edit Project, perubahan budget menunggu persetujuan superadmin"
+		}
+	}
+
 	if err := h.projectRepo.Update(tx, projectInput, checkProjectOwner.Id); err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	return utils.RespondMessage(c, fiber.StatusOK, "Edit Project")
+	// project budget may changed
+	if err := syncBudgetAlerts(tx, h.dailyLogRepo, h.budgetRepo, checkProjectOwner.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	project, err := h.projectRepo.FindByID(tx, checkProjectOwner.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdate, models.AuditEntityProject, project.Id, checkProjectOwner, project); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	change := events.Change{Before: checkProjectOwner, After: project}
+	if err := publishEvent(c, tx, events.ProjectChanged, project.Id, project.Id, change); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if project.Status != checkProjectOwner.Status {
+		if err := publishEvent(c, tx, events.ProjectStatusChanged, project.Id, project.Id, change); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, message)
 }
 
 func (h *ProjectHandler) DeleteProject(c *fiber.Ctx) error {
-	var log_files []string
 	user := c.Locals("user").(models.UserSession)
 
 	id, err := strconv.Atoi(c.Params("id"))
@@ -238,9 +357,10 @@ func (h *ProjectHandler) DeleteProject(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
-	defer utils.CommitOrRollback(tx, c)
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
 
-	_, err = h.projectRepo.FindIfProjectOwner(tx, id, user.Id)
+	project, err := h.projectRepo.FindIfProjectOwner(tx, id, user.Id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
@@ -256,13 +376,9 @@ func (h *ProjectHandler) DeleteProject(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	if len(logs) > 0 {
-		// add all files name to array first bcs need confirm to delete project and logs first before delete files
-		for _, log := range logs {
-			if log.File.String != "" {
-				log_files = append(log_files, log.File.String)
-			}
-		}
+	// files deleted after the project deletion committed
+	for _, log := range logs {
+		files.Delete(log.File.String, log.FileThumbnail.String)
 	}
 
 	// delete project with all logs
@@ -270,12 +386,69 @@ func (h *ProjectHandler) DeleteProject(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// delete all files
-	if len(log_files) > 0 {
-		for _, file := range log_files {
-			utils.DeleteFile(file)
-		}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityProject, id, project, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := publishEvent(c, tx, events.ProjectChanged, id, id, project); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	}
 
 	return utils.RespondMessage(c, fiber.StatusOK, "Delete Project")
 }
+
+// UpdateStorageQuota set project storage quota in MB, empty quota reset project to default quota
+func (h *ProjectHandler) UpdateStorageQuota(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	quotaInput := new(models.StorageQuotaInput)
+	if err := c.BodyParser(quotaInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := utils.ValidateStruct(quotaInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, fmt.Sprintf("Quota must be 0 (unlimited) to %d MB", utils.MaxStorageQuotaMB))
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err := h.projectRepo.FindByID(tx, id); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	before, err := h.projectRepo.FindStorageUsage(tx, id, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	var quota sql.NullInt64
+	if quotaInput.QuotaMB != nil {
+		quota = sql.NullInt64{Int64: *quotaInput.QuotaMB * 1024 * 1024, Valid: true}
+	}
+
+	if err := h.projectRepo.UpdateStorageQuota(tx, id, quota); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	usage, err := h.projectRepo.FindStorageUsage(tx, id, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdateStorageQuota, models.AuditEntityProject, id, before, usage); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Update Storage Quota", usage)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageQuotaInput(t *testing.T) {
+	quota := func(mb int64) *int64 { return &mb }
+
+	tests := []struct {
+		name    string
+		quotaMB *int64
+		valid   bool
+	}{
+		{"default quota", nil, true},
+		{"unlimited", quota(0), true},
+		{"quota", quota(1024), true},
+		{"largest quota", quota(utils.MaxStorageQuotaMB), true},
+		{"negative", quota(-1), false},
+		{"overflow bytes", quota(utils.MaxStorageQuotaMB + 1), false},
+	}
+
+	for _, tt := range tests {
+		err := utils.ValidateStruct(models.StorageQuotaInput{QuotaMB: tt.quotaMB})
+		assert.Equal(t, tt.valid, err == nil, tt.name)
+	}
+}
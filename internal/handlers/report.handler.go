@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/report"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReportHandler generate the printable financial report of a project and manage the email digest subscriptions
+type ReportHandler struct {
+	projectRepo      repository.ProjectRepository
+	dailyLogRepo     repository.DailyLogRepository
+	budgetRepo       repository.BudgetRepository
+	subscriptionRepo repository.ReportSubscriptionRepository
+	rateRepo         repository.ExchangeRateRepository
+	auditRepo        repository.AuditRepository
+}
+
+func NewReportHandler(projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, subscriptionRepo repository.ReportSubscriptionRepository, rateRepo repository.ExchangeRateRepository, auditRepo repository.AuditRepository) *ReportHandler {
+	return &ReportHandler{
+		projectRepo,
+		dailyLogRepo,
+		budgetRepo,
+		subscriptionRepo,
+		rateRepo,
+		auditRepo,
+	}
+}
+
+// GetProjectReport download the pdf report of the project from from_date until to_date, default is the current month
+// until today. Amounts are in currency query, default is the project currency, and include_pending also count the logs
+// that are not approved yet
+func (h *ReportHandler) GetProjectReport(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	opts, err := statsOptions(c)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	now := time.Now()
+	fromDate := c.Query("from_date", now.Format("2006-01")+"-01")
+	toDate := c.Query("to_date", now.Format("2006-01-02"))
+
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "from_date must be YYYY-MM-DD")
+	}
+
+	to, err := time.Parse("2006-01-02", toDate)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "to_date must be YYYY-MM-DD")
+	}
+
+	if to.Before(from) {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "to_date must be after from_date")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	project, err := h.projectRepo.FindByID(tx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusNotFound, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	// superadmin can get report of all projects, admin only their own project
+	if (user.Role != 3) && (project.CreatedBy != user.Id) {
+		return utils.ErrorJSON(c, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if c.Query("currency") == "" {
+		opts.Currency = project.Currency
+	}
+
+	if err := checkCurrency(tx, h.rateRepo, opts.Currency); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	projectReport, err := report.BuildProjectReport(tx, h.dailyLogRepo, h.budgetRepo, project, fromDate, toDate, opts)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	var pdf bytes.Buffer
+	if err := report.WriteProjectReportPDF(&pdf, projectReport); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="project-%d-report-%s-%s.pdf"`, projectID, fromDate, toDate))
+
+	return c.Send(pdf.Bytes())
+}
+
+// GetReportSubscriptions get the email digest subscriptions of the user
+func (h *ReportHandler) GetReportSubscriptions(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	subscriptions, err := h.subscriptionRepo.FindByUser(tx, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Report Subscriptions", subscriptions)
+}
+
+// CreateReportSubscription subscribe the user to weekly or monthly digest of a project, or of all their projects when
+// project_id is 0. The first digest is sent on the next monday or the first day of the next month
+func (h *ReportHandler) CreateReportSubscription(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	subscriptionInput := new(models.ReportSubscriptionInput)
+	if err := c.BodyParser(subscriptionInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := utils.ValidateStruct(subscriptionInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Frequency must be weekly or monthly")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if subscriptionInput.ProjectId != 0 {
+		// admin can only subscribe own project
+		if user.Role == 3 {
+			_, err = h.projectRepo.FindByID(tx, subscriptionInput.ProjectId)
+		} else {
+			_, err = h.projectRepo.FindIfProjectOwner(tx, subscriptionInput.ProjectId, user.Id)
+		}
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found/ User is not project owner")
+			}
+
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
+	nextSendAt := report.NextDigestAt(subscriptionInput.Frequency, time.Now())
+	id, created, err := h.subscriptionRepo.Create(tx, user.Id, subscriptionInput.ProjectId, subscriptionInput.Frequency, nextSendAt)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !created {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Langganan laporan ini sudah ada")
+	}
+
+	subscriptionData := fiber.Map{"project_id": subscriptionInput.ProjectId, "frequency": subscriptionInput.Frequency}
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityReportSubscription, id, nil, subscriptionData); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Create Report Subscription")
+}
+
+// DeleteReportSubscription unsubscribe the user from a digest
+func (h *ReportHandler) DeleteReportSubscription(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	deleted, err := h.subscriptionRepo.Delete(tx, id, user.Id)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if !deleted {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Report subscription not found")
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityReportSubscription, id, nil, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Delete Report Subscription")
+}
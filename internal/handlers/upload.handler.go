@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/utils"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// UploadHandler handle resumable chunked upload, the file is sent in chunks with the offset where the chunk start
+// and verified with sha256 checksum when all chunks received. Completed upload is attached to a daily log with upload_id.
+// Only creating and deleting an upload is audited, not every received chunk
+type UploadHandler struct {
+	projectRepo repository.ProjectRepository
+	uploadRepo  repository.UploadRepository
+	auditRepo   repository.AuditRepository
+}
+
+func NewUploadHandler(projectRepo repository.ProjectRepository, uploadRepo repository.UploadRepository, auditRepo repository.AuditRepository) *UploadHandler {
+	return &UploadHandler{
+		projectRepo,
+		uploadRepo,
+		auditRepo,
+	}
+}
+
+func (h *UploadHandler) CreateUpload(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	uploadInput := new(models.UploadInput)
+	if err := c.BodyParser(uploadInput); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := utils.ValidateStruct(uploadInput); err != nil {
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Field() {
+			case "Filename":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Filename is required max 255 characters")
+			case "ContentType":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Content type is required")
+			case "Size":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Size is required")
+			case "Checksum":
+				return utils.ErrorJSON(c, fiber.StatusBadRequest, "Checksum must be sha256 hex")
+			}
+		}
+	}
+
+	if err := utils.ValidateUploadFile(uploadInput.ContentType, uploadInput.Size); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	if _, err := h.projectRepo.FindIfProjectOwner(tx, projectID, user.Id); err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Project not found")
+		}
+
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// reject early so user don't upload file that can't be attached, quota is checked again when the upload attached
+	storage, err := h.projectRepo.FindStorageUsage(tx, projectID, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := utils.CheckStorageQuota(storage.UsedBytes, storage.EffectiveQuota, uploadInput.Size); err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusBadRequest), err.Error())
+	}
+
+	upload := models.Upload{
+		Id:          uuid.New().String(),
+		ProjectId:   projectID,
+		CreatedBy:   user.Id,
+		Filename:    uploadInput.Filename,
+		ContentType: uploadInput.ContentType,
+		Size:        uploadInput.Size,
+		Checksum:    strings.ToLower(uploadInput.Checksum),
+		Status:      utils.UploadStatusUploading,
+	}
+
+	if err := h.uploadRepo.Create(tx, &upload, utils.UploadExpiry); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	upload, err = h.uploadRepo.FindByID(tx, upload.Id, false)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityUpload, upload.Id, nil, upload); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	setUploadHeaders(c, upload)
+	return utils.RespondWithData(c, fiber.StatusCreated, "Create Upload", upload)
+}
+
+// GetUpload get upload offset to resume the upload, also used for HEAD request
+func (h *UploadHandler) GetUpload(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	upload, err := h.findOwnUpload(c, tx, false)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	setUploadHeaders(c, upload)
+	return utils.RespondWithData(c, fiber.StatusOK, "Get Upload", upload)
+}
+
+// PatchUpload write a chunk on the Upload-Offset header position, the offset must be the same with current upload offset
+func (h *UploadHandler) PatchUpload(c *fiber.Ctx) error {
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if (err != nil) || (offset < 0) {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Upload-Offset header is required")
+	}
+
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return utils.ErrorJSON(c, fiber.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+	}
+
+	chunk := c.Body()
+	if len(chunk) == 0 {
+		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Chunk is empty")
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	defer utils.CommitOrRollback(tx, c)
+
+	// lock the upload so chunks of the same upload are written one by one
+	upload, err := h.findOwnUpload(c, tx, true)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	setUploadHeaders(c, upload)
+
+	if upload.Status == utils.UploadStatusCompleted {
+		return utils.ErrorJSON(c, fiber.StatusConflict, "Upload already completed")
+	}
+
+	if offset != upload.Offset {
+		return utils.ErrorJSON(c, fiber.StatusConflict, "Upload-Offset is not match, current offset is "+strconv.FormatInt(upload.Offset, 10))
+	}
+
+	if offset+int64(len(chunk)) > upload.Size {
+		return utils.ErrorJSON(c, fiber.StatusRequestEntityTooLarge, "Chunk exceed the upload size")
+	}
+
+	newOffset, err := utils.WriteChunk(upload.Id, offset, chunk)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	upload.Offset = newOffset
+	if upload.Offset == upload.Size {
+		checksum, err := utils.FileChecksum(utils.ChunkFile(upload.Id))
+		if err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+
+		// corrupted upload is restarted from the beginning
+		if checksum != upload.Checksum {
+			if err := utils.DeleteFile(utils.ChunkFile(upload.Id)); err != nil {
+				return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+			}
+
+			if err := h.uploadRepo.UpdateOffset(tx, upload.Id, 0, utils.UploadStatusUploading); err != nil {
+				return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+			}
+
+			c.Set("Upload-Offset", "0")
+			return utils.ErrorJSON(c, fiber.StatusUnprocessableEntity, "Checksum is not match, upload must be restarted")
+		}
+
+		upload.Status = utils.UploadStatusCompleted
+	}
+
+	if err := h.uploadRepo.UpdateOffset(tx, upload.Id, upload.Offset, upload.Status); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	setUploadHeaders(c, upload)
+	return utils.RespondWithData(c, fiber.StatusOK, "Upload Chunk", upload)
+}
+
+func (h *UploadHandler) DeleteUpload(c *fiber.Ctx) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+	files := utils.NewFileTx()
+	defer utils.CommitOrRollbackWithFiles(tx, c, files)
+
+	upload, err := h.findOwnUpload(c, tx, true)
+	if err != nil {
+		return utils.ErrorJSON(c, utils.ErrorStatus(err, fiber.StatusInternalServerError), err.Error())
+	}
+
+	files.Delete(utils.ChunkFile(upload.Id))
+
+	if err := h.uploadRepo.Delete(tx, upload.Id); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityUpload, upload.Id, upload, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.RespondMessage(c, fiber.StatusOK, "Delete Upload")
+}
+
+// findOwnUpload get upload from the url that created by the user on the project
+func (h *UploadHandler) findOwnUpload(c *fiber.Ctx, tx *sql.Tx, forUpdate bool) (models.Upload, error) {
+	user := c.Locals("user").(models.UserSession)
+
+	projectID, err := strconv.Atoi(c.Params("project_id"))
+	if err != nil {
+		return models.Upload{}, fiber.NewError(fiber.StatusBadRequest, "Invalid project ID")
+	}
+
+	if _, err := uuid.Parse(c.Params("id")); err != nil {
+		return models.Upload{}, fiber.NewError(fiber.StatusBadRequest, "Invalid upload ID")
+	}
+
+	upload, err := h.uploadRepo.FindByID(tx, c.Params("id"), forUpdate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return upload, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+		}
+
+		return upload, err
+	}
+
+	if (upload.ProjectId != projectID) || (upload.CreatedBy != user.Id) {
+		return upload, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+	}
+
+	return upload, nil
+}
+
+func setUploadHeaders(c *fiber.Ctx, upload models.Upload) {
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"fiber-prjct-management-web/internal/events"
 	"fiber-prjct-management-web/internal/models"
 	"fiber-prjct-management-web/internal/repository"
 	"fiber-prjct-management-web/pkg/database"
@@ -15,11 +16,12 @@ import (
 )
 
 type UserHandler struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
 }
 
-func NewUserHandler(userRepo repository.UserRepository) *UserHandler {
-	return &UserHandler{userRepo}
+func NewUserHandler(userRepo repository.UserRepository, auditRepo repository.AuditRepository) *UserHandler {
+	return &UserHandler{userRepo, auditRepo}
 }
 
 func (h *UserHandler) ViewUser(c *fiber.Ctx) error {
@@ -152,6 +154,15 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
 	}
 
+	newUser, err := h.userRepo.FindByUsername(tx, userInput.Username)
+	if err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditCreate, models.AuditEntityUser, newUser.Id, nil, newUser); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "User created successfully")
 }
 
@@ -205,10 +216,16 @@ func (h *UserHandler) EditUserPassword(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
 	}
 
+	// only the change is recorded, never the password
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdatePassword, models.AuditEntityUser, userInput.Id, nil, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Edit User Password")
 }
 
 func (h *UserHandler) EditUser(c *fiber.Ctx) error {
+	user := c.Locals("user").(models.UserSession)
 	userInput := new(models.UpdateUserInput)
 
 	userId, err := strconv.Atoi(c.Params("id"))
@@ -233,6 +250,12 @@ func (h *UserHandler) EditUser(c *fiber.Ctx) error {
 		}
 	}
 
+	if userInput.Email != nil {
+		if err := utils.GetValidator().Var(*userInput.Email, "omitempty,email,max=255"); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusBadRequest, "Email tidak valid")
+		}
+	}
+
 	tx, err := database.DB.Begin()
 	if err != nil {
 		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
@@ -259,8 +282,12 @@ func (h *UserHandler) EditUser(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, "Username already exists")
 	}
 
+	before := userUpdate
 	userUpdate.Username = userInput.Username
 	userUpdate.Role = userInput.Role
+	if userInput.Email != nil {
+		userUpdate.Email = *userInput.Email
+	}
 
 	err = h.userRepo.Update(tx, &userUpdate)
 	if err != nil {
@@ -268,6 +295,17 @@ func (h *UserHandler) EditUser(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditUpdate, models.AuditEntityUser, userId, before, userUpdate); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	// super admin changing another user account notify the user
+	if user.Id != userId {
+		if err := publishEvent(c, tx, events.UserChangedByAdmin, 0, userId, events.Change{Before: before, After: userUpdate}); err != nil {
+			return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		}
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Edit User")
 }
 
@@ -297,5 +335,9 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		return utils.ErrorJSON(c, fiber.StatusBadRequest, err.Error())
 	}
 
+	if err := recordAudit(c, tx, h.auditRepo, models.AuditDelete, models.AuditEntityUser, id, check_user, nil); err != nil {
+		return utils.ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+	}
+
 	return utils.RespondMessage(c, fiber.StatusOK, "Delete User")
 }
@@ -0,0 +1,126 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/checkpoint"
+	"fmt"
+)
+
+// CreateAuditCheckpoint verify the audit chain and sign its head, nil checkpoint is returned when checkpoint is
+// disabled or there is no new event since the last checkpoint. A broken chain is never signed
+func CreateAuditCheckpoint(db *sql.DB, auditRepo repository.AuditRepository) (*checkpoint.Checkpoint, error) {
+	if checkpoint.Default == nil {
+		return nil, nil
+	}
+
+	report, err := VerifyAuditChain(db, auditRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.Valid {
+		return nil, fmt.Errorf("audit chain broken at event %d: %s", report.BrokenEventId, report.Reason)
+	}
+
+	if report.LastEventId == 0 {
+		return nil, nil
+	}
+
+	checkpoints, err := checkpoint.Default.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if (len(checkpoints) > 0) && (checkpoints[len(checkpoints)-1].EventId == report.LastEventId) {
+		return nil, nil
+	}
+
+	signed := checkpoint.Default.Sign(report.LastEventId, report.LastHash)
+	if err := checkpoint.Default.Append(signed); err != nil {
+		return nil, err
+	}
+
+	return &signed, nil
+}
+
+// VerifyAuditChain walk the audit chain from the first event and report the first event that was changed or that
+// follow a deleted event, then check the signed checkpoints so deleted events at the end of the chain are also found
+func VerifyAuditChain(db *sql.DB, auditRepo repository.AuditRepository) (models.AuditVerifyReport, error) {
+	report := models.AuditVerifyReport{Valid: true, Checkpoints: -1}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	err = auditRepo.EachLink(tx, func(link models.AuditChainLink) error {
+		if !report.Valid {
+			return nil
+		}
+
+		switch {
+		case link.PrevHash != report.LastHash:
+			report.Valid = false
+			report.BrokenEventId = link.Id
+			report.Reason = "previous hash does not match, an event before it was changed or deleted"
+		case link.Hash != link.ComputedHash:
+			report.Valid = false
+			report.BrokenEventId = link.Id
+			report.Reason = "hash does not match, the event was changed"
+		default:
+			report.CheckedEvents++
+			report.LastEventId = link.Id
+			report.LastHash = link.Hash
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	if !report.Valid || (checkpoint.Default == nil) {
+		return report, nil
+	}
+
+	checkpoints, err := checkpoint.Default.ReadAll()
+	if err != nil {
+		return report, err
+	}
+
+	report.Checkpoints = 0
+	for _, signed := range checkpoints {
+		if !checkpoint.Default.Verify(signed) {
+			report.Valid = false
+			report.BrokenEventId = signed.EventId
+			report.Reason = "checkpoint signature is invalid"
+			return report, nil
+		}
+
+		hash, err := auditRepo.FindHash(tx, signed.EventId)
+		if (err != nil) && (err != sql.ErrNoRows) {
+			return report, err
+		}
+
+		if err == sql.ErrNoRows {
+			report.Valid = false
+			report.BrokenEventId = signed.EventId
+			report.Reason = "checkpointed event was deleted"
+			return report, nil
+		}
+
+		if hash != signed.Hash {
+			report.Valid = false
+			report.BrokenEventId = signed.EventId
+			report.Reason = "hash does not match the signed checkpoint, the chain was rewritten"
+			return report, nil
+		}
+
+		report.Checkpoints++
+	}
+
+	return report, nil
+}
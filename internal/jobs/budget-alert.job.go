@@ -0,0 +1,68 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/mailer"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"log"
+	"time"
+)
+
+// budgetAlertMaxAge is how long an unsent alert is still emailed, older alert is raised when email was disabled
+const budgetAlertMaxAge = 24 * time.Hour
+
+// SendBudgetAlertMails email new budget alerts to the project owner, nothing is sent when mailer is disabled
+func SendBudgetAlertMails(db *sql.DB, budgetRepo repository.BudgetRepository) error {
+	if mailer.Default == nil {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	alerts, err := budgetRepo.FindUnsentAlerts(tx, budgetAlertMaxAge)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, alert := range alerts {
+		// owner without email is skipped, the alert is still shown on the web
+		if alert.OwnerEmail != "" {
+			subject, body := budgetAlertMail(alert)
+
+			// failed email is retried on the next tick
+			if err := mailer.Default.Send([]string{alert.OwnerEmail}, subject, body); err != nil {
+				log.Printf("send budget alert %d error: %v", alert.Id, err)
+				continue
+			}
+		}
+
+		if err := budgetRepo.MarkAlertEmailed(tx, alert.Id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func budgetAlertMail(alert models.BudgetAlert) (string, string) {
+	scope := "anggaran proyek"
+	if alert.CategoryId.Valid {
+		scope = "anggaran kategori " + alert.CategoryName
+	}
+
+	subject := fmt.Sprintf("[%s] Penggunaan %s mencapai %d%%", alert.ProjectName, scope, alert.Threshold)
+	// alert amounts are in the reporting currency
+	currency := utils.ReportingCurrency()
+	body := fmt.Sprintf("Penggunaan %s pada proyek %s sudah mencapai %.2f%% (batas peringatan %d%%).\n\nAnggaran: %s %d\nPengeluaran: %s %d\nSisa: %s %d\n",
+		scope, alert.ProjectName, alert.UsagePercentage, alert.Threshold, currency, alert.Budget, currency, alert.Actual, currency, alert.Budget-alert.Actual)
+
+	return subject, body
+}
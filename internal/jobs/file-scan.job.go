@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+	"log"
+)
+
+// RescanPendingFiles rescan files that still pending, usually because the scanner was not reachable on upload or the
+// file is uploaded before scanning is enabled. Files are scanned outside of transaction so a slow scanner does not
+// hold the logs, every result is saved with its own short transaction
+func RescanPendingFiles(db *sql.DB, dailyLogRepo repository.DailyLogRepository) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	logs, err := dailyLogRepo.FindPendingFileScan(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, dailyLog := range logs {
+		path, status := utils.ScanFile(dailyLog.File.String)
+
+		// scanner still not available or the clean file can not be released yet, try again on next tick
+		if status == utils.FileStatusPending {
+			break
+		}
+
+		thumbnail := ""
+		if (status == utils.FileStatusClean) && utils.IsThumbnailSupported(path) {
+			if thumbnail, err = utils.GenerateThumbnail(path); err != nil {
+				log.Println("generate thumbnail error: ", err)
+			}
+		}
+
+		if err := saveFileScan(db, dailyLogRepo, dailyLog, path, status, thumbnail); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveFileScan save the scan result of the log file, the thumbnail is removed when the result is not saved. When the
+// log file changed while it was scanned, the moved file is not referenced by the log anymore and removed too
+func saveFileScan(db *sql.DB, dailyLogRepo repository.DailyLogRepository, dailyLog models.DailyLog, path string, status string, thumbnail string) error {
+	files := utils.NewFileTx()
+	files.Stage(thumbnail)
+
+	tx, err := db.Begin()
+	if err != nil {
+		files.Rollback()
+		return err
+	}
+
+	updated, err := dailyLogRepo.UpdateFileStatus(tx, dailyLog.Id, dailyLog.File.String, path, status, thumbnail)
+	if err != nil {
+		tx.Rollback()
+		files.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		files.Rollback()
+		return err
+	}
+
+	if !updated {
+		if path != dailyLog.File.String {
+			files.Stage(path)
+		}
+
+		files.Rollback()
+		return nil
+	}
+
+	files.Commit()
+	return nil
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/repository"
+	"log"
+	"time"
+)
+
+// finishedJobMaxAge is how long done jobs are kept on the jobs page
+const finishedJobMaxAge = 7 * 24 * time.Hour
+
+// DeleteFinishedJobs remove old done jobs, failed jobs are kept until they are retried
+func DeleteFinishedJobs(db *sql.DB, jobRepo repository.JobRepository) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleted, err := jobRepo.DeleteFinished(tx, finishedJobMaxAge)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("deleted %d finished jobs", deleted)
+	}
+
+	return tx.Commit()
+}
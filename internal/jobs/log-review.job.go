@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/mailer"
+	"fmt"
+	"log"
+	"time"
+)
+
+// logReviewMaxAge is how long an unsent review is still emailed, older review is made when email was disabled
+const logReviewMaxAge = 24 * time.Hour
+
+// SendLogReviewMails email approved and rejected logs to the project owner, nothing is sent when mailer is disabled
+func SendLogReviewMails(db *sql.DB, reviewRepo repository.LogReviewRepository) error {
+	if mailer.Default == nil {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	reviews, err := reviewRepo.FindUnnotified(tx, logReviewMaxAge)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, review := range reviews {
+		// owner without email is skipped, the review is still shown on the web
+		if review.OwnerEmail != "" {
+			subject, body := logReviewMail(review)
+
+			// failed email is retried on the next tick
+			if err := mailer.Default.Send([]string{review.OwnerEmail}, subject, body); err != nil {
+				log.Printf("send log review %d error: %v", review.Id, err)
+				continue
+			}
+		}
+
+		if err := reviewRepo.MarkNotified(tx, review.Id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func logReviewMail(review models.LogReview) (string, string) {
+	logDate := review.LogDate
+	if len(logDate) > 10 {
+		logDate = logDate[:10]
+	}
+
+	result := "disetujui"
+	if review.Action == models.LogStatusRejected {
+		result = "ditolak"
+	}
+
+	subject := fmt.Sprintf("[%s] Log %s %s", review.ProjectName, logDate, result)
+	body := fmt.Sprintf("Log harian proyek %s tanggal %s %s oleh %s.\n", review.ProjectName, logDate, result, review.Username)
+	if review.Comment.String != "" {
+		body += "\nKomentar:\n" + review.Comment.String + "\n"
+	}
+
+	if review.Action == models.LogStatusRejected {
+		body += "\nPerbaiki log lalu submit ulang agar dihitung pada statistik proyek.\n"
+	}
+
+	return subject, body
+}
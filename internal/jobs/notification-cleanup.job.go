@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/repository"
+	"log"
+	"time"
+)
+
+// readNotificationMaxAge is how long read notifications are kept
+const readNotificationMaxAge = 90 * 24 * time.Hour
+
+// DeleteReadNotifications remove old read notifications, unread notifications are kept until they are read
+func DeleteReadNotifications(db *sql.DB, notificationRepo repository.NotificationRepository) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleted, err := notificationRepo.DeleteReadOlderThan(tx, readNotificationMaxAge)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("deleted %d read notifications", deleted)
+	}
+
+	return tx.Commit()
+}
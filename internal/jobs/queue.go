@@ -0,0 +1,305 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// job types run by the queue
+const (
+	JobFileScan            = "file_scan"
+	JobUploadCleanup       = "upload_cleanup"
+	JobBudgetAlertMail     = "budget_alert_mail"
+	JobLogReviewMail       = "log_review_mail"
+	JobAuditCheckpoint     = "audit_checkpoint"
+	JobReportDigest        = "report_digest"
+	JobStorageGC           = "storage_gc"
+	JobCleanup             = "job_cleanup"
+	JobNotificationCleanup = "notification_cleanup"
+)
+
+// DefaultMaxAttempts is how many times a job is run before it is failed
+const DefaultMaxAttempts = 5
+
+// jobLockTimeout is how long a job can be running before it is counted as failed, the app was stopped while running it
+const jobLockTimeout = 30 * time.Minute
+
+// retry backoff start from retryBaseDelay and double every attempt until retryMaxDelay
+const (
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = time.Hour
+)
+
+// Handler run one job, returned error make the job retried later
+type Handler func(payload json.RawMessage) error
+
+type schedule struct {
+	name    string
+	jobType string
+	cron    string
+	spec    cron.Schedule
+}
+
+// Queue run the jobs saved on the jobs table. Every app instance can start a queue, a job or a due schedule is only
+// picked by one of them
+type Queue struct {
+	db        *sql.DB
+	jobRepo   repository.JobRepository
+	handlers  map[string]Handler
+	schedules []schedule
+	workerId  string
+}
+
+func NewQueue(db *sql.DB, jobRepo repository.JobRepository) *Queue {
+	hostname, _ := os.Hostname()
+
+	return &Queue{
+		db:       db,
+		jobRepo:  jobRepo,
+		handlers: map[string]Handler{},
+		workerId: fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}
+}
+
+// Register set the handler of the job type, a job without handler is failed
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// Schedule enqueue the job type on the cron expression, standard 5 fields or a descriptor like @hourly or @every 5m.
+// A schedule is skipped while its previous job is not finished
+func (q *Queue) Schedule(name string, expression string, jobType string) error {
+	spec, err := cron.ParseStandard(expression)
+	if err != nil {
+		return fmt.Errorf("invalid cron of schedule %s: %w", name, err)
+	}
+
+	q.schedules = append(q.schedules, schedule{name, jobType, expression, spec})
+	return nil
+}
+
+// Start save the schedules and start the scheduler and the workers that poll the queue every poll interval
+func (q *Queue) Start(workers int, pollInterval time.Duration) error {
+	if err := q.saveSchedules(); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := q.enqueueDueSchedules(); err != nil {
+				log.Println("enqueue job schedules error: ", err)
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			workerId := fmt.Sprintf("%s:%d", q.workerId, worker)
+
+			for {
+				found, err := q.runNext(workerId)
+				if err != nil {
+					log.Println("run job error: ", err)
+				}
+
+				// keep running while there are due jobs
+				if !found {
+					time.Sleep(pollInterval)
+				}
+			}
+		}(i + 1)
+	}
+
+	return nil
+}
+
+func (q *Queue) saveSchedules() error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	names := []string{}
+	for _, s := range q.schedules {
+		if err := q.jobRepo.SaveSchedule(tx, s.name, s.jobType, s.cron, s.spec.Next(time.Now())); err != nil {
+			return err
+		}
+		names = append(names, s.name)
+	}
+
+	// schedule removed from the code is not run anymore
+	if err := q.jobRepo.DeleteSchedulesExcept(tx, names); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// enqueueDueSchedules add a job for every due schedule and release the jobs of stopped workers
+func (q *Queue) enqueueDueSchedules() error {
+	now := time.Now()
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	released, err := q.jobRepo.ReleaseStale(tx, jobLockTimeout)
+	if err != nil {
+		return err
+	}
+
+	if released > 0 {
+		log.Printf("released %d stale jobs", released)
+	}
+
+	dueSchedules, err := q.jobRepo.ClaimDueSchedules(tx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, dueSchedule := range dueSchedules {
+		spec, err := cron.ParseStandard(dueSchedule.Cron)
+		if err != nil {
+			return err
+		}
+
+		unfinished, err := q.jobRepo.HasUnfinished(tx, dueSchedule.Name)
+		if err != nil {
+			return err
+		}
+
+		// missed runs are not enqueued again, next run is counted from now
+		if !unfinished {
+			if _, err := q.jobRepo.Enqueue(tx, dueSchedule.JobType, nil, now, DefaultMaxAttempts, dueSchedule.Name); err != nil {
+				return err
+			}
+		}
+
+		if err := q.jobRepo.UpdateScheduleRun(tx, dueSchedule.Name, spec.Next(now)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// runNext claim and run the next due job, false is returned when there is no due job
+func (q *Queue) runNext(workerId string) (bool, error) {
+	now := time.Now()
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	job, err := q.jobRepo.Claim(tx, workerId, now)
+	if err != nil {
+		tx.Rollback()
+
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// job is committed as running before it is run, so the lock is not held while the job run
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	runErr := q.run(job)
+
+	tx, err = q.db.Begin()
+	if err != nil {
+		return true, err
+	}
+	defer tx.Rollback()
+
+	if runErr == nil {
+		if err := q.jobRepo.Complete(tx, job.Id); err != nil {
+			return true, err
+		}
+
+		return true, tx.Commit()
+	}
+
+	status, err := q.jobRepo.Fail(tx, job.Id, runErr.Error(), time.Now().Add(retryDelay(job.Attempts)))
+	if err != nil {
+		return true, err
+	}
+
+	log.Printf("job %d (%s) attempt %d/%d error, %s: %v", job.Id, job.Type, job.Attempts, job.MaxAttempts, status, runErr)
+
+	return true, tx.Commit()
+}
+
+func (q *Queue) run(job models.Job) (err error) {
+	handler, ok := q.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %s", job.Type)
+	}
+
+	// panic of a job fail the job instead of stopping the worker
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+
+	return handler(job.Payload)
+}
+
+// retryDelay get the delay before the next attempt after the attempts
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; (i < attempts) && (delay < retryMaxDelay); i++ {
+		delay *= 2
+	}
+
+	if delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+
+	return delay
+}
+
+// Enqueue add a job that run as soon as a worker is free
+func Enqueue(tx *sql.Tx, jobRepo repository.JobRepository, jobType string, payload interface{}) (int64, error) {
+	return jobRepo.Enqueue(tx, jobType, payload, time.Now(), DefaultMaxAttempts, "")
+}
+
+// QueueWorkers get number of job workers of the app instance, default is 2
+func QueueWorkers() int {
+	workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if (err != nil) || (workers <= 0) {
+		return 2
+	}
+
+	return workers
+}
+
+// QueuePollInterval get how often idle workers check the queue, default is 5s
+func QueuePollInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("JOB_POLL_INTERVAL"))
+	if (err != nil) || (interval <= 0) {
+		return 5 * time.Second
+	}
+
+	return interval
+}
@@ -0,0 +1,91 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fiber-prjct-management-web/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, retryDelay(tt.attempts), "attempts %d", tt.attempts)
+	}
+}
+
+func TestQueueRun(t *testing.T) {
+	queue := NewQueue(nil, nil)
+
+	var received string
+	queue.Register("echo", func(payload json.RawMessage) error {
+		return json.Unmarshal(payload, &received)
+	})
+	queue.Register("fail", func(payload json.RawMessage) error {
+		return errors.New("mail server down")
+	})
+	queue.Register("panic", func(payload json.RawMessage) error {
+		var m map[string]int
+		m["boom"] = 1
+		return nil
+	})
+
+	require.NoError(t, queue.run(models.Job{Type: "echo", Payload: json.RawMessage(`"hello"`)}))
+	assert.Equal(t, "hello", received)
+
+	assert.EqualError(t, queue.run(models.Job{Type: "fail"}), "mail server down")
+	assert.ErrorContains(t, queue.run(models.Job{Type: "panic"}), "job panic: assignment to entry in nil map")
+	assert.EqualError(t, queue.run(models.Job{Type: "unknown"}), "no handler for job type unknown")
+}
+
+func TestSchedule(t *testing.T) {
+	queue := NewQueue(nil, nil)
+
+	for _, expression := range []string{"* * * * *", "*/10 * * * *", "30 3 * * *", "@hourly", "@every 5m"} {
+		assert.NoError(t, queue.Schedule("valid", expression, JobCleanup), expression)
+	}
+
+	assert.ErrorContains(t, queue.Schedule("broken", "every day", JobCleanup), "invalid cron of schedule broken")
+	assert.Len(t, queue.schedules, 5)
+}
+
+func TestQueueEnv(t *testing.T) {
+	tests := []struct {
+		workers      string
+		pollInterval string
+		wantWorkers  int
+		wantInterval time.Duration
+	}{
+		{"", "", 2, 5 * time.Second},
+		{"4", "1s", 4, time.Second},
+		{"0", "0s", 2, 5 * time.Second},
+		{"-1", "-5s", 2, 5 * time.Second},
+		{"many", "soon", 2, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("JOB_WORKERS", tt.workers)
+		t.Setenv("JOB_POLL_INTERVAL", tt.pollInterval)
+
+		assert.Equal(t, tt.wantWorkers, QueueWorkers(), "JOB_WORKERS=%q", tt.workers)
+		assert.Equal(t, tt.wantInterval, QueuePollInterval(), "JOB_POLL_INTERVAL=%q", tt.pollInterval)
+	}
+}
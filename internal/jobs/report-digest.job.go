@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"bytes"
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/report"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/mailer"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SendReportDigests email every due subscription and move it to the next send time, nothing is sent when mailer is
+// disabled. Each digest use its own transaction, so a failed digest is retried on the next run without blocking the
+// others
+func SendReportDigests(db *sql.DB, subscriptionRepo repository.ReportSubscriptionRepository, projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository) error {
+	if mailer.Default == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	subscriptions, err := subscriptionRepo.FindDue(tx, now)
+	tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if err := sendReportDigest(db, subscriptionRepo, projectRepo, dailyLogRepo, budgetRepo, subscription, now); err != nil {
+			log.Printf("send report digest %d error: %v", subscription.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func sendReportDigest(db *sql.DB, subscriptionRepo repository.ReportSubscriptionRepository, projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, subscription models.ReportSubscription, now time.Time) error {
+	sendAt, err := time.ParseInLocation("2006-01-02T15:04:05Z", subscription.NextSendAt, time.Local)
+	if err != nil {
+		sendAt = now
+	}
+
+	// the digest cover the period of its schedule, missed schedules are not sent again
+	fromDate, toDate := report.DigestPeriod(subscription.Frequency, sendAt)
+	nextSendAt := report.NextDigestAt(subscription.Frequency, now)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	digest, err := report.BuildDigest(tx, projectRepo, dailyLogRepo, budgetRepo, subscription, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+
+	// user without email or without project is skipped until the next schedule
+	sent := false
+	if (subscription.UserEmail != "") && (len(digest.Projects) > 0) {
+		attachments := []mailer.Attachment{}
+		projectName := ""
+
+		if subscription.ProjectId.Valid {
+			projectName = digest.Projects[0].Project.Name
+
+			var pdf bytes.Buffer
+			if err := report.WriteProjectReportPDF(&pdf, digest.Projects[0]); err != nil {
+				return err
+			}
+
+			attachments = append(attachments, mailer.Attachment{
+				Filename:    fmt.Sprintf("project-%d-report-%s-%s.pdf", subscription.ProjectId.Int64, fromDate, toDate),
+				ContentType: "application/pdf",
+				Data:        pdf.Bytes(),
+			})
+		}
+
+		subject, body := report.DigestMail(digest, projectName)
+		if err := mailer.Default.SendWithAttachments([]string{subscription.UserEmail}, subject, body, attachments); err != nil {
+			return err
+		}
+		sent = true
+	}
+
+	if err := subscriptionRepo.MarkSent(tx, subscription.Id, sent, nextSendAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type StorageCheckOptions struct {
+	DeleteOrphans bool
+	// orphan newer than grace period is never deleted, the file can be an upload that the transaction not committed yet
+	GracePeriod time.Duration
+}
+
+// StorageGCDeleteOrphans tell if the scheduled storage check delete orphaned files, only with
+// STORAGE_GC_DELETE_ORPHANS=true. By default it only report them, files uploaded outside the app are not referenced
+// by any log and would be deleted too
+func StorageGCDeleteOrphans() bool {
+	deleteOrphans, _ := strconv.ParseBool(os.Getenv("STORAGE_GC_DELETE_ORPHANS"))
+	return deleteOrphans
+}
+
+// StorageCheck reconcile files on disk with the daily logs file references, report missing and orphaned files
+// and delete orphaned files older than grace period if the option is enabled
+func StorageCheck(db *sql.DB, dailyLogRepo repository.DailyLogRepository, opts StorageCheckOptions) (models.StorageCheckReport, error) {
+	report := models.StorageCheckReport{
+		MissingFiles:  []models.StorageFileReference{},
+		OrphanedFiles: []models.StorageOrphanFile{},
+	}
+
+	// list disk first, so file uploaded between listing and reading references is not reported as orphan
+	files, err := utils.ListStoredFiles()
+	if err != nil {
+		return report, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+
+	refs, err := dailyLogRepo.FindFileReferences(tx)
+	if err != nil {
+		tx.Rollback()
+		return report, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+
+	report.TotalFiles = len(files)
+	report.TotalReferences = len(refs)
+
+	onDisk := map[string]bool{}
+	for _, file := range files {
+		onDisk[filepath.Clean(file.Path)] = true
+	}
+
+	referenced := map[string]bool{}
+	for _, ref := range refs {
+		path := filepath.Clean(ref.Path)
+		referenced[path] = true
+
+		if !onDisk[path] {
+			report.MissingFiles = append(report.MissingFiles, ref)
+		}
+	}
+
+	for _, file := range files {
+		if referenced[filepath.Clean(file.Path)] {
+			continue
+		}
+
+		orphan := models.StorageOrphanFile{
+			Path:    file.Path,
+			Size:    file.Size,
+			ModTime: file.ModTime.Format(time.RFC3339),
+		}
+
+		if opts.DeleteOrphans && (time.Since(file.ModTime) > opts.GracePeriod) {
+			if err := utils.DeleteFile(file.Path); err != nil {
+				return report, err
+			}
+
+			orphan.Deleted = true
+			report.DeletedFiles++
+			report.DeletedBytes += file.Size
+		}
+
+		report.OrphanedFiles = append(report.OrphanedFiles, orphan)
+	}
+
+	return report, nil
+}
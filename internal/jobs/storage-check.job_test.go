@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageGCDeleteOrphans(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"", false},
+		{"false", false},
+		{"yes", false},
+		{"true", true},
+		{"1", true},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("STORAGE_GC_DELETE_ORPHANS", tt.value)
+
+		assert.Equal(t, tt.want, StorageGCDeleteOrphans(), "STORAGE_GC_DELETE_ORPHANS=%q", tt.value)
+	}
+}
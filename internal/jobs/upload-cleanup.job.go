@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+)
+
+// CleanupExpiredUploads remove chunked uploads that expired before attached to a log
+func CleanupExpiredUploads(db *sql.DB, uploadRepo repository.UploadRepository) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	uploads, err := uploadRepo.FindExpired(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, upload := range uploads {
+		if err := uploadRepo.Delete(tx, upload.Id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// chunk files are removed after the uploads deleted from database, this also remove chunk files
+	// of uploads that already deleted together with the project
+	return utils.RemoveStaleChunks(utils.UploadExpiry)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+
+	AuditUpdatePassword     = "update_password"
+	AuditUpdateStorageQuota = "update_storage_quota"
+	AuditDeleteFile         = "delete_file"
+	AuditSubmit             = "submit"
+	AuditApprove            = "approve"
+	AuditReject             = "reject"
+	AuditRestore            = "restore"
+	AuditLock               = "lock"
+	AuditReopen             = "reopen"
+	AuditImport             = "import"
+	AuditRetry              = "retry"
+	AuditRun                = "run"
+)
+
+const (
+	AuditEntityUser            = "user"
+	AuditEntityProject         = "project"
+	AuditEntityDailyLog        = "daily_log"
+	AuditEntityLineItem        = "line_item"
+	AuditEntityExpenseCategory = "expense_category"
+	AuditEntityCategoryBudget  = "category_budget"
+	AuditEntityBudgetRevision  = "budget_revision"
+	AuditEntityExchangeRate    = "exchange_rate"
+	AuditEntityPeriodLock      = "period_lock"
+	AuditEntityUpload          = "upload"
+
+	AuditEntityReportSubscription = "report_subscription"
+	AuditEntityJob                = "job"
+	AuditEntityJobSchedule        = "job_schedule"
+)
+
+// AuditEvent is a change made by a user, Before and After are the json of the entity before and after the change
+type AuditEvent struct {
+	Id         int64           `json:"id"`
+	ActorId    sql.NullInt64   `json:"actor_id"`
+	ActorName  string          `json:"actor_name"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityId   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Ip         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	RequestId  string          `json:"request_id"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+// AuditFilter empty field is not filtered, dates are YYYY-MM-DD and inclusive
+type AuditFilter struct {
+	Search     string
+	ActorId    int
+	Action     string
+	EntityType string
+	EntityId   string
+	FromDate   string
+	ToDate     string
+}
+
+// AuditChainLink is an event of the chain with the hash calculated again from the stored event
+type AuditChainLink struct {
+	Id           int64
+	PrevHash     string
+	Hash         string
+	ComputedHash string
+}
+
+// AuditVerifyReport is the result of walking the audit chain, BrokenEventId is the first event that break the chain
+type AuditVerifyReport struct {
+	Valid         bool   `json:"valid"`
+	CheckedEvents int64  `json:"checked_events"`
+	LastEventId   int64  `json:"last_event_id"`
+	LastHash      string `json:"last_hash"`
+	BrokenEventId int64  `json:"broken_event_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	// Checkpoints is the number of verified signed checkpoints, -1 when checkpoint is disabled
+	Checkpoints int `json:"checkpoints"`
+}
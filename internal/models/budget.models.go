@@ -0,0 +1,63 @@
+package models
+
+import "database/sql"
+
+type CategoryBudgetInput struct {
+	CategoryId int `json:"category_id" validate:"required"`
+	Amount     int `json:"amount" validate:"min=0"`
+}
+
+// BudgetUsage is budget vs actual expense of a category, category 0 is the whole project
+type BudgetUsage struct {
+	Currency        string  `json:"currency"`
+	CategoryId      int     `json:"category_id"`
+	CategoryName    string  `json:"category_name"`
+	Budget          int     `json:"budget"`
+	Actual          int     `json:"actual"`
+	Remaining       int     `json:"remaining"`
+	UsagePercentage float64 `json:"usage_percentage"`
+}
+
+type BudgetAlert struct {
+	Id              int            `json:"id"`
+	ProjectId       int            `json:"project_id"`
+	ProjectName     string         `json:"project_name"`
+	CategoryId      sql.NullInt64  `json:"category_id"` // null is the whole project budget
+	CategoryName    string         `json:"category_name"`
+	Threshold       int            `json:"threshold"`
+	UsagePercentage float64        `json:"usage_percentage"`
+	Budget          int            `json:"budget"`
+	Actual          int            `json:"actual"`
+	IsRead          bool           `json:"is_read"`
+	EmailedAt       sql.NullString `json:"emailed_at"`
+	CreatedAt       string         `json:"created_at"`
+	OwnerEmail      string         `json:"-"`
+}
+
+const (
+	BudgetRevisionPending  = "pending"
+	BudgetRevisionApproved = "approved"
+	BudgetRevisionRejected = "rejected"
+)
+
+// BudgetRevision is a change of the project budget, the budget is changed when the revision is approved
+type BudgetRevision struct {
+	Id              int            `json:"id"`
+	ProjectId       int            `json:"project_id"`
+	ProjectName     string         `json:"project_name"`
+	OldBudget       int            `json:"old_budget"`
+	NewBudget       int            `json:"new_budget"`
+	Reason          string         `json:"reason"`
+	Status          string         `json:"status"`
+	RequestedBy     sql.NullInt64  `json:"requested_by"`
+	RequestedByName string         `json:"requested_by_name"`
+	ReviewedBy      sql.NullInt64  `json:"reviewed_by"`
+	ReviewedByName  string         `json:"reviewed_by_name"`
+	ReviewNote      sql.NullString `json:"review_note"`
+	ReviewedAt      sql.NullString `json:"reviewed_at"`
+	CreatedAt       string         `json:"created_at"`
+}
+
+type BudgetReviewInput struct {
+	Note string `json:"note" validate:"max=255"`
+}
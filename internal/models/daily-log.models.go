@@ -3,30 +3,54 @@ package models
 import "database/sql"
 
 type DailyLog struct {
-	Id          int            `json:"id"`
-	ProjectId   int            `json:"project_id"`
-	LogDate     string         `json:"log_date"`
-	Description string         `json:"description"`
-	Issues      string         `json:"issues"`
-	Income      int            `json:"income"`
-	Expense     int            `json:"expense"`
-	File        sql.NullString `json:"file"`
-	CreatedAt   string         `json:"created_at"`
-	UpdatedAt   string         `json:"updated_at"`
-	ProjectName string         `json:"project_name"`
+	Id            int            `json:"id"`
+	ProjectId     int            `json:"project_id"`
+	LogDate       string         `json:"log_date"`
+	Description   string         `json:"description"`
+	Issues        string         `json:"issues"`
+	Income        int            `json:"income"`
+	Expense       int            `json:"expense"`
+	Currency      string         `json:"currency"`
+	File          sql.NullString `json:"file"`
+	FileStatus    sql.NullString `json:"file_status"`
+	FileThumbnail sql.NullString `json:"file_thumbnail"`
+	FileSize      int64          `json:"file_size"`
+	ItemCount     int            `json:"item_count"`
+	HasLineItems  bool           `json:"has_line_items"` // log that ever had line items has income and expense derived from the items
+	Status        string         `json:"status"`
+	ReviewerId    sql.NullInt64  `json:"reviewer_id"`
+	SubmittedAt   sql.NullString `json:"submitted_at"`
+	ReviewedAt    sql.NullString `json:"reviewed_at"`
+	CreatedAt     string         `json:"created_at"`
+	UpdatedAt     string         `json:"updated_at"`
+	ProjectName   string         `json:"project_name"`
 }
 
 type DailyLogInput struct {
-	ProjectId   int    `form:"project_id" json:"project_id" validate:"required"`
-	LogDate     string `form:"log_date" json:"log_date" validate:"required"`
-	Description string `form:"description" json:"description"`
-	Issues      string `form:"issues" json:"issues"`
-	Income      int    `form:"income" json:"income" validate:"min=0"`
-	Expense     int    `form:"expense" json:"expense" validate:"min=0"`
-	File        string `form:"file" json:"file"`
+	ProjectId     int    `form:"project_id" json:"project_id" validate:"required"`
+	LogDate       string `form:"log_date" json:"log_date" validate:"required"`
+	Description   string `form:"description" json:"description"`
+	Issues        string `form:"issues" json:"issues"`
+	Income        int    `form:"income" json:"income" validate:"min=0"`
+	Expense       int    `form:"expense" json:"expense" validate:"min=0"`
+	Currency      string `form:"currency" json:"currency"`
+	File          string `form:"file" json:"file"`
+	FileStatus    string `json:"file_status"`
+	FileThumbnail string `json:"file_thumbnail"`
+	FileSize      int64  `json:"file_size"`
+}
+
+// StatsOptions is how stats are calculated, all amounts are converted into Currency. When AsOf (YYYY-MM-DD) is set
+// only logs until the date are counted against the budget in effect on the date. Only approved logs are counted
+// unless IncludePending is set, which also count draft and submitted logs
+type StatsOptions struct {
+	Currency       string
+	AsOf           string
+	IncludePending bool
 }
 
 type DailyLogStats struct {
+	Currency              string         `json:"currency"`
 	TotalIncome           int            `json:"total_income"`
 	TotalExpense          int            `json:"total_expense"`
 	Budget                int            `json:"budget"`
@@ -44,4 +68,30 @@ type DailyLogStatsCumulative struct {
 	Income          int            `json:"income"`
 	Expense         int            `json:"expense"`
 	CumulativeSaldo int            `json:"cumulative_saldo"`
+	// budget in effect on the log date and the cumulative expense usage of it
+	Budget                int     `json:"budget"`
+	BudgetUsagePercentage float64 `json:"budget_usage_percentage"`
+}
+
+type CategoryTotal struct {
+	CategoryId   int     `json:"category_id"` // 0 is expense without category
+	CategoryName string  `json:"category_name"`
+	Total        int     `json:"total"`
+	Percentage   float64 `json:"percentage"`   // share of total expense
+	BudgetUsage  float64 `json:"budget_usage"` // share of budget
+}
+
+type CategoryMonthlyTotal struct {
+	Month        string `json:"month"` // YYYY-MM
+	CategoryId   int    `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Total        int    `json:"total"`
+}
+
+type CategoryStats struct {
+	Currency     string                 `json:"currency"`
+	Budget       int                    `json:"budget"`
+	TotalExpense int                    `json:"total_expense"`
+	Categories   []CategoryTotal        `json:"categories"`
+	Monthly      []CategoryMonthlyTotal `json:"monthly"`
 }
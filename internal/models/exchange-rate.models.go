@@ -0,0 +1,20 @@
+package models
+
+// ExchangeRate is the rate of 1 base currency in quote currency on the rate date
+type ExchangeRate struct {
+	Id            int     `json:"id"`
+	BaseCurrency  string  `json:"base_currency"`
+	QuoteCurrency string  `json:"quote_currency"`
+	RateDate      string  `json:"rate_date"`
+	Rate          float64 `json:"rate"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+}
+
+type ExchangeRateInput struct {
+	BaseCurrency  string  `json:"base_currency" validate:"required,len=3,alpha"`
+	QuoteCurrency string  `json:"quote_currency" validate:"required,len=3,alpha,nefield=BaseCurrency"`
+	RateDate      string  `json:"rate_date" validate:"required,datetime=2006-01-02"`
+	Rate          float64 `json:"rate" validate:"gt=0"`
+	CreatedBy     int     `json:"created_by"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "database/sql"
+
+type ExpenseCategory struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+	IsActive    bool           `json:"is_active"`
+	CreatedAt   string         `json:"created_at"`
+	UpdatedAt   string         `json:"updated_at"`
+}
+
+type ExpenseCategoryInput struct {
+	Id          int    `json:"-"` // set on create
+	Name        string `json:"name" validate:"required,min=3,max=100"`
+	Description string `json:"description" validate:"max=255"`
+	IsActive    *bool  `json:"is_active"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// ProjectForecast is the projection of the project spend from the daily burn rate until the project end date.
+// Nil values can not be calculated, e.g. project without end date has no spend at completion
+type ProjectForecast struct {
+	ProjectId int    `json:"project_id"`
+	Currency  string `json:"currency"`
+	AsOf      string `json:"as_of"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+
+	Budget        int     `json:"budget"`
+	ActualSpend   int     `json:"actual_spend"`
+	ElapsedDays   int     `json:"elapsed_days"`
+	PlannedDays   *int    `json:"planned_days"`
+	RemainingDays *int    `json:"remaining_days"`
+	DailyBurnRate float64 `json:"daily_burn_rate"`
+
+	ProjectedSpendAtCompletion *float64 `json:"projected_spend_at_completion"`
+	VarianceAtCompletion       *float64 `json:"variance_at_completion"` // budget - projected spend, negative is over budget
+	PlannedSpendToDate         *float64 `json:"planned_spend_to_date"`  // budget spread evenly from start to end date
+	SpendVariance              *float64 `json:"spend_variance"`         // planned spend to date - actual spend
+	BudgetRunOutDate           *string  `json:"budget_run_out_date"`
+	BudgetExceeded             bool     `json:"budget_exceeded"`
+
+	// earned value indexes need task progress, nil until projects have tasks
+	CPI  *float64 `json:"cpi"`
+	SPI  *float64 `json:"spi"`
+	Note string   `json:"note"`
+
+	Series []ForecastPoint `json:"series"`
+}
+
+// ForecastPoint is the projected cumulative expense on a date
+type ForecastPoint struct {
+	Date              string  `json:"date"`
+	CumulativeExpense float64 `json:"cumulative_expense"`
+}
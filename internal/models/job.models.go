@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a background job of the queue, Attempts is the number of runs started including the running one
+type Job struct {
+	Id           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"`
+	Attempts     int             `json:"attempts"`
+	MaxAttempts  int             `json:"max_attempts"`
+	RunAt        string          `json:"run_at"`
+	LockedAt     sql.NullString  `json:"locked_at"`
+	LockedBy     string          `json:"locked_by"`
+	LastError    string          `json:"last_error"`
+	ScheduleName sql.NullString  `json:"schedule_name"`
+	CreatedAt    string          `json:"created_at"`
+	FinishedAt   sql.NullString  `json:"finished_at"`
+}
+
+// JobSchedule is a recurring job, Cron is a standard 5 fields cron expression or a descriptor like @every 5m
+type JobSchedule struct {
+	Name      string         `json:"name"`
+	JobType   string         `json:"job_type"`
+	Cron      string         `json:"cron"`
+	NextRunAt string         `json:"next_run_at"`
+	LastRunAt sql.NullString `json:"last_run_at"`
+}
+
+// JobFilter empty field is not filtered
+type JobFilter struct {
+	Status string
+	Type   string
+}
+
+// JobStatusCount is the number of jobs per status, shown on the jobs page
+type JobStatusCount struct {
+	Status string `json:"status"`
+	Total  int    `json:"total"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "database/sql"
+
+// type of line item
+const (
+	LineItemIncome  = "income"
+	LineItemExpense = "expense"
+)
+
+type LineItem struct {
+	Id           int            `json:"id"`
+	DailyLogId   int            `json:"daily_log_id"`
+	Type         string         `json:"type"`
+	CategoryId   sql.NullInt64  `json:"category_id"`
+	CategoryName sql.NullString `json:"category_name"`
+	Description  string         `json:"description"`
+	Quantity     float64        `json:"quantity"`
+	UnitPrice    int            `json:"unit_price"`
+	Amount       int            `json:"amount"`
+	CreatedAt    string         `json:"created_at"`
+	UpdatedAt    string         `json:"updated_at"`
+}
+
+type LineItemInput struct {
+	Id          int     `json:"-"` // set on create
+	DailyLogId  int     `json:"daily_log_id"`
+	Type        string  `json:"type" validate:"required,oneof=income expense"`
+	CategoryId  int     `json:"category_id" validate:"min=0"` // 0 is without category, only allowed for income
+	Description string  `json:"description" validate:"max=255"`
+	Quantity    float64 `json:"quantity" validate:"gt=0"`
+	UnitPrice   int     `json:"unit_price" validate:"min=0"`
+	Amount      int     `json:"amount"` // quantity * unit price, calculated on server
+}
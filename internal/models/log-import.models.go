@@ -0,0 +1,22 @@
+package models
+
+// LogImportFields are the daily log fields that can be mapped from the columns of an import file
+var LogImportFields = []string{"log_date", "description", "issues", "income", "expense", "currency"}
+
+// LogImportRowError is the validation errors of a row, Row is the row number in the file with the header as row 1
+type LogImportRowError struct {
+	Row     int      `json:"row"`
+	LogDate string   `json:"log_date"`
+	Errors  []string `json:"errors"`
+}
+
+// LogImportReport is the result of a daily log import, nothing is imported when a row has errors
+type LogImportReport struct {
+	DryRun    bool                `json:"dry_run"`
+	Valid     bool                `json:"valid"`
+	TotalRows int                 `json:"total_rows"`
+	ValidRows int                 `json:"valid_rows"`
+	Imported  int                 `json:"imported"`
+	Columns   map[string]string   `json:"columns"` // field to the file column it is read from
+	RowErrors []LogImportRowError `json:"row_errors"`
+}
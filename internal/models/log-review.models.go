@@ -0,0 +1,55 @@
+package models
+
+import "database/sql"
+
+const (
+	LogStatusDraft     = "draft"
+	LogStatusSubmitted = "submitted"
+	LogStatusApproved  = "approved"
+	LogStatusRejected  = "rejected"
+)
+
+// LogReview is a submit, approve or reject of a daily log
+type LogReview struct {
+	Id         int            `json:"id"`
+	DailyLogId int            `json:"daily_log_id"`
+	UserId     sql.NullInt64  `json:"user_id"`
+	Username   string         `json:"username"`
+	Action     string         `json:"action"`
+	Comment    sql.NullString `json:"comment"`
+	CreatedAt  string         `json:"created_at"`
+
+	// used for the notification email
+	ProjectId   int    `json:"-"`
+	ProjectName string `json:"-"`
+	LogDate     string `json:"-"`
+	OwnerEmail  string `json:"-"`
+}
+
+// PendingLogReview is a submitted log waiting for review
+type PendingLogReview struct {
+	DailyLogId  int            `json:"daily_log_id"`
+	ProjectId   int            `json:"project_id"`
+	ProjectName string         `json:"project_name"`
+	LogDate     string         `json:"log_date"`
+	Income      int            `json:"income"`
+	Expense     int            `json:"expense"`
+	Currency    string         `json:"currency"`
+	ReviewerId  sql.NullInt64  `json:"reviewer_id"`
+	SubmittedAt sql.NullString `json:"submitted_at"`
+}
+
+// LogReviewer is an admin or super admin that can be assigned to review a log
+type LogReviewer struct {
+	Id       int    `json:"id"`
+	Username string `json:"username"`
+	Role     int    `json:"role"`
+}
+
+type SubmitLogInput struct {
+	ReviewerId int `json:"reviewer_id"`
+}
+
+type ReviewLogInput struct {
+	Comment string `json:"comment" validate:"max=1000"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "database/sql"
+
+const (
+	LogRevisionCreated  = "created"
+	LogRevisionUpdated  = "updated"
+	LogRevisionRestored = "restored"
+)
+
+// DailyLogSnapshot is the log data stored on every revision
+type DailyLogSnapshot struct {
+	LogDate     string `json:"log_date"`
+	Description string `json:"description"`
+	Issues      string `json:"issues"`
+	Income      int    `json:"income"`
+	Expense     int    `json:"expense"`
+	Currency    string `json:"currency"`
+	File        string `json:"file"`
+	FileSize    int64  `json:"file_size"`
+}
+
+// FieldChange is the value of a field before and after a revision
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type DailyLogRevision struct {
+	Id         int                    `json:"id"`
+	DailyLogId int                    `json:"daily_log_id"`
+	Version    int                    `json:"version"`
+	Action     string                 `json:"action"`
+	Snapshot   DailyLogSnapshot       `json:"snapshot"`
+	Changes    map[string]FieldChange `json:"changes"`
+	UserId     sql.NullInt64          `json:"user_id"`
+	Username   string                 `json:"username"`
+	CreatedAt  string                 `json:"created_at"`
+}
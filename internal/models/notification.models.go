@@ -0,0 +1,16 @@
+package models
+
+import "database/sql"
+
+// Notification is shown on the navbar bell of the user, Link is the page opened by the notification
+type Notification struct {
+	Id        int64         `json:"id"`
+	UserId    int           `json:"user_id"`
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Message   string        `json:"message"`
+	Link      string        `json:"link"`
+	ProjectId sql.NullInt64 `json:"project_id"`
+	IsRead    bool          `json:"is_read"`
+	CreatedAt string        `json:"created_at"`
+}
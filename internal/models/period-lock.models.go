@@ -0,0 +1,38 @@
+package models
+
+import "database/sql"
+
+const (
+	PeriodLocked   = "locked"
+	PeriodReopened = "reopened"
+)
+
+// PeriodLock is a closed month of a project, or of every project when ProjectId is null
+type PeriodLock struct {
+	Id           int           `json:"id"`
+	ProjectId    sql.NullInt64 `json:"project_id"`
+	Period       string        `json:"period"` // YYYY-MM
+	LockedBy     sql.NullInt64 `json:"locked_by"`
+	LockedByName string        `json:"locked_by_name"`
+	CreatedAt    string        `json:"created_at"`
+}
+
+// PeriodLockEvent is a lock or reopen of a period
+type PeriodLockEvent struct {
+	Id        int            `json:"id"`
+	ProjectId sql.NullInt64  `json:"project_id"`
+	Period    string         `json:"period"` // YYYY-MM
+	Action    string         `json:"action"`
+	Reason    sql.NullString `json:"reason"`
+	UserId    sql.NullInt64  `json:"user_id"`
+	Username  string         `json:"username"`
+	CreatedAt string         `json:"created_at"`
+}
+
+type PeriodLockInput struct {
+	Period string `json:"period" validate:"required,datetime=2006-01"`
+}
+
+type PeriodReopenInput struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
@@ -10,6 +10,7 @@ type Project struct {
 	EndDate       sql.NullString `json:"end_date"`
 	Status        int            `json:"status"`
 	Budget        int            `json:"budget"`
+	Currency      string         `json:"currency"`
 	CreatedBy     int            `json:"created_by"`
 	CreatedByName string         `json:"created_by_name"`
 	CreatedAt     string         `json:"created_at"`
@@ -17,6 +18,7 @@ type Project struct {
 }
 
 type ProjectInput struct {
+	Id          int    `json:"-"` // set on create
 	Name        string `json:"name" validate:"required,min=5,max=50"`
 	Description string `json:"description" validate:"required,min=5,max=255"`
 	StartDate   string `json:"start_date"`
@@ -24,9 +26,13 @@ type ProjectInput struct {
 	Status      int    `json:"status" validate:"required"`
 	CreatedBy   int    `json:"created_by"`
 	Budget      int    `json:"budget"`
+	Currency    string `json:"currency"`
+	// BudgetReason is required when the budget is changed, recorded on the budget revision
+	BudgetReason string `json:"budget_reason"`
 }
 
 type ProjectStats struct {
+	Currency               string         `json:"currency"`
 	TotalProjects          int            `json:"total_project"`
 	TotalProjectsDone      int            `json:"total_project_done"`
 	TotalProjectsOnGoing   int            `json:"total_project_ongoing"`
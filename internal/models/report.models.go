@@ -0,0 +1,61 @@
+package models
+
+import "database/sql"
+
+const (
+	ReportWeekly  = "weekly"
+	ReportMonthly = "monthly"
+)
+
+// ProjectReport is the financial report of a project for FromDate until ToDate, all amounts are in Stats.Currency
+type ProjectReport struct {
+	Project        Project `json:"project"`
+	FromDate       string  `json:"from_date"`
+	ToDate         string  `json:"to_date"`
+	IncludePending bool    `json:"include_pending"`
+	// Stats count all logs until ToDate, the period amounts only the logs of the range
+	Stats             DailyLogStats `json:"stats"`
+	OpeningBalance    int           `json:"opening_balance"`
+	PeriodIncome      int           `json:"period_income"`
+	PeriodExpense     int           `json:"period_expense"`
+	PeriodWorkingDays int           `json:"period_working_days"`
+	// Cumulative is the cumulative balance of the log dates in the range
+	Cumulative  []DailyLogStatsCumulative `json:"cumulative"`
+	BudgetUsage []BudgetUsage             `json:"budget_usage"`
+	// Issues and Attachments are the logs of the range with issues and with file, ordered by log date
+	Issues      []DailyLog `json:"issues"`
+	Attachments []DailyLog `json:"attachments"`
+	GeneratedAt string     `json:"generated_at"`
+}
+
+// ReportSubscription is an email digest of a project, or of all projects of the user when ProjectId is null
+type ReportSubscription struct {
+	Id          int            `json:"id"`
+	UserId      int            `json:"user_id"`
+	ProjectId   sql.NullInt64  `json:"project_id"`
+	ProjectName string         `json:"project_name"`
+	Frequency   string         `json:"frequency"`
+	NextSendAt  string         `json:"next_send_at"`
+	LastSentAt  sql.NullString `json:"last_sent_at"`
+	CreatedAt   string         `json:"created_at"`
+	// owner of the subscription, only used by the digest worker
+	UserRole  int    `json:"-"`
+	UserEmail string `json:"-"`
+}
+
+type ReportSubscriptionInput struct {
+	ProjectId int    `json:"project_id"` // 0 is all projects
+	Frequency string `json:"frequency" validate:"required,oneof=weekly monthly"`
+}
+
+// ReportDigest is the content of a subscription email, Projects has the report of every project of the digest and
+// ProjectStats is only set on the digest of all projects
+type ReportDigest struct {
+	Frequency    string          `json:"frequency"`
+	FromDate     string          `json:"from_date"`
+	ToDate       string          `json:"to_date"`
+	Currency     string          `json:"currency"`
+	ProjectStats *ProjectStats   `json:"project_stats"`
+	Projects     []ProjectReport `json:"projects"`
+	NewestLogs   []DailyLog      `json:"newest_logs"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "database/sql"
+
+type StorageFileReference struct {
+	LogId     int    `json:"log_id"`
+	ProjectId int    `json:"project_id"`
+	Path      string `json:"path"`
+}
+
+type StorageOrphanFile struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime string `json:"mod_time"`
+	Deleted bool   `json:"deleted"`
+}
+
+type StorageCheckReport struct {
+	TotalFiles      int                    `json:"total_files"`
+	TotalReferences int                    `json:"total_references"`
+	MissingFiles    []StorageFileReference `json:"missing_files"`
+	OrphanedFiles   []StorageOrphanFile    `json:"orphaned_files"`
+	DeletedFiles    int                    `json:"deleted_files"`
+	DeletedBytes    int64                  `json:"deleted_bytes"`
+}
+
+type ProjectStorageUsage struct {
+	ProjectId       int           `json:"project_id"`
+	ProjectName     string        `json:"project_name"`
+	UsedBytes       int64         `json:"used_bytes"`
+	FileCount       int           `json:"file_count"`
+	QuotaBytes      sql.NullInt64 `json:"quota_bytes"` // null mean the project use default quota
+	EffectiveQuota  int64         `json:"effective_quota"`
+	UsagePercentage float64       `json:"usage_percentage"`
+}
+
+type StorageUsageStats struct {
+	TotalUsedBytes int64                 `json:"total_used_bytes"`
+	TotalFiles     int                   `json:"total_files"`
+	DefaultQuota   int64                 `json:"default_quota"`
+	TopProjects    []ProjectStorageUsage `json:"top_projects"`
+}
+
+type StorageQuotaInput struct {
+	QuotaMB *int64 `json:"quota_mb" validate:"omitempty,min=0,max=8796093022207"` // null reset to default quota, 0 is unlimited, max is utils.MaxStorageQuotaMB
+}
@@ -0,0 +1,23 @@
+package models
+
+type Upload struct {
+	Id          string `json:"id"`
+	ProjectId   int    `json:"project_id"`
+	CreatedBy   int    `json:"created_by"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Offset      int64  `json:"offset"`
+	Checksum    string `json:"checksum"`
+	Status      string `json:"status"`
+	ExpiresAt   string `json:"expires_at"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+type UploadInput struct {
+	Filename    string `json:"filename" validate:"required,max=255"`
+	ContentType string `json:"content_type" validate:"required"`
+	Size        int64  `json:"size" validate:"required,min=1"`
+	Checksum    string `json:"checksum" validate:"required,len=64,hexadecimal"`
+}
@@ -4,6 +4,7 @@ type User struct {
 	Id        int    `json:"id"`
 	Username  string `json:"username"`
 	Password  string `json:"password"`
+	Email     string `json:"email"`
 	Role      int    `json:"role"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
@@ -17,8 +18,9 @@ type CreateUserInput struct {
 }
 
 type UpdateUserInput struct {
-	Username string `json:"username" validate:"required,min=5,max=50,alphanum"`
-	Role     int    `json:"role"`
+	Username string  `json:"username" validate:"required,min=5,max=50,alphanum"`
+	Role     int     `json:"role"`
+	Email    *string `json:"email"` // nil keep the current email, empty remove it
 }
 
 type UserSession struct {
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// digestHour is the hour digests are sent, weekly on monday and monthly on the first day of the month
+const digestHour = 7
+
+// newestLogsLimit limit logs listed on a digest
+const newestLogsLimit = 10
+
+// NextDigestAt get the first send time of the frequency after the time
+func NextDigestAt(frequency string, after time.Time) time.Time {
+	if frequency == models.ReportMonthly {
+		next := time.Date(after.Year(), after.Month(), 1, digestHour, 0, 0, 0, after.Location())
+		if !next.After(after) {
+			next = next.AddDate(0, 1, 0)
+		}
+
+		return next
+	}
+
+	daysToMonday := (int(time.Monday) - int(after.Weekday()) + 7) % 7
+	next := time.Date(after.Year(), after.Month(), after.Day()+daysToMonday, digestHour, 0, 0, 0, after.Location())
+	if !next.After(after) {
+		next = next.AddDate(0, 0, 7)
+	}
+
+	return next
+}
+
+// DigestPeriod get the dates (YYYY-MM-DD) covered by the digest sent at sendAt, the 7 days or the month before it
+func DigestPeriod(frequency string, sendAt time.Time) (string, string) {
+	day := time.Date(sendAt.Year(), sendAt.Month(), sendAt.Day(), 0, 0, 0, 0, sendAt.Location())
+
+	if frequency == models.ReportMonthly {
+		from := time.Date(day.Year(), day.Month()-1, 1, 0, 0, 0, 0, day.Location())
+		return from.Format("2006-01-02"), from.AddDate(0, 1, -1).Format("2006-01-02")
+	}
+
+	return day.AddDate(0, 0, -7).Format("2006-01-02"), day.AddDate(0, 0, -1).Format("2006-01-02")
+}
+
+// BuildDigest collect the digest of the subscription from fromDate until toDate. A project digest is in the project
+// currency, the digest of all projects is in the reporting currency. Projects is empty when the user can no longer
+// access the project or has no project
+func BuildDigest(tx *sql.Tx, projectRepo repository.ProjectRepository, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, subscription models.ReportSubscription, fromDate string, toDate string) (models.ReportDigest, error) {
+	digest := models.ReportDigest{
+		Frequency:  subscription.Frequency,
+		FromDate:   fromDate,
+		ToDate:     toDate,
+		Currency:   utils.ReportingCurrency(),
+		Projects:   []models.ProjectReport{},
+		NewestLogs: []models.DailyLog{},
+	}
+
+	// superadmin get all projects, admin only their own projects
+	userId := subscription.UserId
+	if subscription.UserRole == 3 {
+		userId = 0
+	}
+
+	projects := []models.Project{}
+	if subscription.ProjectId.Valid {
+		project, err := projectRepo.FindByID(tx, int(subscription.ProjectId.Int64))
+		if err != nil {
+			return digest, err
+		}
+
+		if (userId != 0) && (project.CreatedBy != userId) {
+			return digest, nil
+		}
+
+		digest.Currency = project.Currency
+		projects = append(projects, project)
+	} else {
+		opts := models.StatsOptions{Currency: digest.Currency, AsOf: toDate}
+		projectStats, err := projectRepo.FindProjectsStats(tx, userId, opts)
+		if err != nil {
+			return digest, err
+		}
+		digest.ProjectStats = &projectStats
+
+		err = projectRepo.Each(tx, "", "", "", "", userId, func(project models.Project) error {
+			projects = append(projects, project)
+			return nil
+		})
+		if err != nil {
+			return digest, err
+		}
+	}
+
+	opts := models.StatsOptions{Currency: digest.Currency}
+	for _, project := range projects {
+		projectReport, err := BuildProjectReport(tx, dailyLogRepo, budgetRepo, project, fromDate, toDate, opts)
+		if err != nil {
+			return digest, err
+		}
+
+		digest.Projects = append(digest.Projects, projectReport)
+	}
+
+	projectId := int(subscription.ProjectId.Int64)
+	logs, _, err := dailyLogRepo.FindWithPagination(tx, newestLogsLimit, 1, "", projectId, fromDate, toDate, userId, subscription.UserRole)
+	if err != nil {
+		return digest, err
+	}
+
+	if logs != nil {
+		digest.NewestLogs = logs
+	}
+
+	return digest, nil
+}
+
+// DigestMail get the subject and plain text body of the digest email
+func DigestMail(digest models.ReportDigest, projectName string) (string, string) {
+	frequency := "Mingguan"
+	if digest.Frequency == models.ReportMonthly {
+		frequency = "Bulanan"
+	}
+
+	scope := "Semua Project"
+	if projectName != "" {
+		scope = projectName
+	}
+
+	subject := fmt.Sprintf("[Laporan %s] %s %s s/d %s", frequency, scope, digest.FromDate, digest.ToDate)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Laporan %s %s\nPeriode %s s/d %s, jumlah dalam %s\n", strings.ToLower(frequency), scope, digest.FromDate, digest.ToDate, digest.Currency)
+
+	if digest.ProjectStats != nil {
+		stats := digest.ProjectStats
+		fmt.Fprintf(&body, "\nTotal project: %d (%d berjalan, %d selesai)\nTotal budget: %s\n",
+			stats.TotalProjects, stats.TotalProjectsOnGoing, stats.TotalProjectsDone, utils.FormatAmount(stats.TotalBudgetAllProjects, digest.Currency))
+	}
+
+	for _, projectReport := range digest.Projects {
+		stats := projectReport.Stats
+		closingBalance := projectReport.OpeningBalance + projectReport.PeriodIncome - projectReport.PeriodExpense
+
+		fmt.Fprintf(&body, "\n%s (%s)\n", projectReport.Project.Name, ProjectStatusName(projectReport.Project.Status))
+		fmt.Fprintf(&body, "  Pemasukan: %s\n", utils.FormatAmount(projectReport.PeriodIncome, digest.Currency))
+		fmt.Fprintf(&body, "  Pengeluaran: %s\n", utils.FormatAmount(projectReport.PeriodExpense, digest.Currency))
+		fmt.Fprintf(&body, "  Saldo akhir: %s\n", utils.FormatAmount(closingBalance, digest.Currency))
+		fmt.Fprintf(&body, "  Budget: %s (%.2f%% terpakai)\n", utils.FormatAmount(stats.Budget, digest.Currency), stats.BudgetUsagePercentage)
+
+		for _, usage := range projectReport.BudgetUsage {
+			fmt.Fprintf(&body, "    - %s: %s dari %s (%.2f%%)\n", usage.CategoryName, utils.FormatAmount(usage.Actual, digest.Currency), utils.FormatAmount(usage.Budget, digest.Currency), usage.UsagePercentage)
+		}
+
+		if len(projectReport.Issues) > 0 {
+			fmt.Fprintf(&body, "  Kendala: %d log\n", len(projectReport.Issues))
+		}
+	}
+
+	if len(digest.NewestLogs) > 0 {
+		body.WriteString("\nLog terbaru:\n")
+		for _, log := range digest.NewestLogs {
+			fmt.Fprintf(&body, "- %s %s: %s (+%s / -%s, %s)\n", logDate(log.LogDate), log.ProjectName, log.Description,
+				utils.FormatAmount(log.Income, log.Currency), utils.FormatAmount(log.Expense, log.Currency), log.Status)
+		}
+	}
+
+	if projectName != "" {
+		body.WriteString("\nLaporan PDF project terlampir.\n")
+	}
+
+	body.WriteString("\nBerhenti berlangganan dari halaman profil user.\n")
+
+	return subject, body.String()
+}
@@ -0,0 +1,373 @@
+package report
+
+import (
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/pkg/utils"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// bottom margin of the report pages, content is moved to the next page before it
+const reportBottomMargin = 15
+
+type projectPDF struct {
+	pdf       *fpdf.Fpdf
+	translate func(string) string
+	report    models.ProjectReport
+	currency  string
+}
+
+// WriteProjectReportPDF write the report as A4 pdf: project info and budget, summary of the period and until the end of
+// the period, cumulative balance chart, budget per category, issues and attachment index
+func WriteProjectReportPDF(w io.Writer, report models.ProjectReport) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, reportBottomMargin)
+	pdf.AliasNbPages("")
+	pdf.SetTitle("Laporan Keuangan - "+report.Project.Name, true)
+
+	doc := &projectPDF{
+		pdf:       pdf,
+		translate: pdf.UnicodeTranslatorFromDescriptor(""),
+		report:    report,
+		currency:  report.Stats.Currency,
+	}
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(150, 5, doc.fit(doc.translate(report.Project.Name+" - "+report.FromDate+" s/d "+report.ToDate), 148), "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+
+	doc.header()
+	doc.summary()
+	doc.balanceChart()
+	doc.budgetUsage()
+	doc.issues()
+	doc.attachments()
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
+
+func (d *projectPDF) header() {
+	project := d.report.Project
+
+	d.pdf.SetFont("Helvetica", "B", 16)
+	d.pdf.CellFormat(0, 8, "Laporan Keuangan Project", "", 1, "L", false, 0, "")
+	d.pdf.SetFont("Helvetica", "", 9)
+	d.pdf.CellFormat(0, 5, d.translate(fmt.Sprintf("Periode %s s/d %s, dibuat %s", d.report.FromDate, d.report.ToDate, d.report.GeneratedAt)), "", 1, "L", false, 0, "")
+	d.pdf.Ln(4)
+
+	budgetUsage := fmt.Sprintf("%s (%.2f%% terpakai)", utils.FormatAmount(d.report.Stats.Budget, d.currency), d.report.Stats.BudgetUsagePercentage)
+	rows := [][2]string{
+		{"Project", project.Name},
+		{"Deskripsi", project.Description},
+		{"Pemilik", project.CreatedByName},
+		{"Status", ProjectStatusName(project.Status)},
+		{"Tanggal", reportDate(project.StartDate.String) + " s/d " + reportDate(project.EndDate.String)},
+		{"Budget per " + d.report.ToDate, budgetUsage},
+		{"Mata uang laporan", d.currency},
+	}
+
+	for _, row := range rows {
+		d.pdf.SetFont("Helvetica", "B", 9)
+		d.pdf.CellFormat(45, 6, d.translate(row[0]), "", 0, "L", false, 0, "")
+		d.pdf.SetFont("Helvetica", "", 9)
+		d.pdf.MultiCell(0, 6, d.translate(row[1]), "", "L", false)
+	}
+
+	if !d.report.IncludePending {
+		d.pdf.SetFont("Helvetica", "I", 8)
+		d.pdf.CellFormat(0, 5, "Statistik hanya menghitung log yang sudah disetujui.", "", 1, "L", false, 0, "")
+	}
+}
+
+func (d *projectPDF) summary() {
+	d.section("Ringkasan")
+
+	stats := d.report.Stats
+	closingBalance := d.report.OpeningBalance + d.report.PeriodIncome - d.report.PeriodExpense
+	widths := []float64{70, 55, 55}
+
+	d.tableHeader(widths, []string{"", "Periode", "s/d " + d.report.ToDate})
+	rows := [][]string{
+		{"Saldo awal", utils.FormatAmount(d.report.OpeningBalance, d.currency), "-"},
+		{"Pemasukan", utils.FormatAmount(d.report.PeriodIncome, d.currency), utils.FormatAmount(stats.TotalIncome, d.currency)},
+		{"Pengeluaran", utils.FormatAmount(d.report.PeriodExpense, d.currency), utils.FormatAmount(stats.TotalExpense, d.currency)},
+		{"Saldo akhir", utils.FormatAmount(closingBalance, d.currency), utils.FormatAmount(stats.Balance, d.currency)},
+		{"Hari kerja", strconv.Itoa(d.report.PeriodWorkingDays), strconv.Itoa(stats.TotalWorkingDays)},
+		{"Sisa budget", "-", utils.FormatAmount(stats.Budget-stats.TotalExpense, d.currency)},
+	}
+
+	for _, row := range rows {
+		d.tableRow(widths, row, []string{"L", "R", "R"})
+	}
+}
+
+// balanceChart draw the cumulative balance of the period as line chart, starting from the opening balance
+func (d *projectPDF) balanceChart() {
+	d.section("Saldo Kumulatif")
+
+	if len(d.report.Cumulative) == 0 {
+		d.note("Tidak ada log pada periode ini.")
+		return
+	}
+
+	const (
+		chartHeight = 65
+		labelWidth  = 28
+	)
+
+	d.ensureSpace(chartHeight + 12)
+
+	left, _, right, _ := d.pdf.GetMargins()
+	pageWidth, _ := d.pdf.GetPageSize()
+	x := left + labelWidth
+	y := d.pdf.GetY() + 2
+	width := pageWidth - right - x
+
+	from, _ := time.Parse("2006-01-02", d.report.FromDate)
+	to, _ := time.Parse("2006-01-02", d.report.ToDate)
+	days := to.Sub(from).Hours() / 24
+	if days < 1 {
+		days = 1
+	}
+
+	type point struct {
+		day   float64
+		value int
+	}
+	points := []point{{0, d.report.OpeningBalance}}
+	for _, stat := range d.report.Cumulative {
+		date, _ := time.Parse("2006-01-02", logDate(stat.LogDate.String))
+		points = append(points, point{date.Sub(from).Hours() / 24, stat.CumulativeSaldo})
+	}
+
+	// the y axis always include 0, so it is clear when the balance is negative
+	minValue, maxValue := 0, 0
+	for _, p := range points {
+		if p.value < minValue {
+			minValue = p.value
+		}
+		if p.value > maxValue {
+			maxValue = p.value
+		}
+	}
+	if minValue == maxValue {
+		maxValue = minValue + 1
+	}
+
+	posX := func(day float64) float64 { return x + width*day/days }
+	posY := func(value int) float64 {
+		return y + chartHeight - chartHeight*float64(value-minValue)/float64(maxValue-minValue)
+	}
+
+	// grid with the amount labels
+	d.pdf.SetFont("Helvetica", "", 7)
+	d.pdf.SetLineWidth(0.1)
+	d.pdf.SetDrawColor(210, 210, 210)
+	for i := 0; i <= 4; i++ {
+		value := minValue + (maxValue-minValue)*i/4
+		lineY := posY(value)
+		d.pdf.Line(x, lineY, x+width, lineY)
+		d.pdf.SetXY(left, lineY-2)
+		d.pdf.CellFormat(labelWidth-2, 4, utils.FormatAmount(value, ""), "", 0, "R", false, 0, "")
+	}
+
+	d.pdf.SetDrawColor(0, 0, 0)
+	d.pdf.Rect(x, y, width, chartHeight, "D")
+	d.pdf.Line(x, posY(0), x+width, posY(0))
+
+	d.pdf.SetDrawColor(32, 107, 196)
+	d.pdf.SetFillColor(32, 107, 196)
+	d.pdf.SetLineWidth(0.5)
+	for i, p := range points {
+		if i > 0 {
+			d.pdf.Line(posX(points[i-1].day), posY(points[i-1].value), posX(p.day), posY(p.value))
+		}
+		d.pdf.Circle(posX(p.day), posY(p.value), 0.7, "F")
+	}
+
+	d.pdf.SetLineWidth(0.2)
+	d.pdf.SetDrawColor(0, 0, 0)
+
+	d.pdf.SetXY(x, y+chartHeight+1)
+	d.pdf.CellFormat(width/2, 4, d.report.FromDate, "", 0, "L", false, 0, "")
+	d.pdf.CellFormat(width/2, 4, d.report.ToDate, "", 1, "R", false, 0, "")
+	d.pdf.SetX(left)
+}
+
+func (d *projectPDF) budgetUsage() {
+	if len(d.report.BudgetUsage) == 0 {
+		return
+	}
+
+	d.section("Budget per Kategori")
+
+	widths := []float64{60, 35, 35, 35, 15}
+	headers := []string{"Kategori", "Budget", "Realisasi", "Sisa", "%"}
+	aligns := []string{"L", "R", "R", "R", "R"}
+
+	d.tableHeader(widths, headers)
+	for _, usage := range d.report.BudgetUsage {
+		if d.ensureSpace(6) {
+			d.tableHeader(widths, headers)
+		}
+
+		d.tableRow(widths, []string{
+			usage.CategoryName,
+			utils.FormatAmount(usage.Budget, d.currency),
+			utils.FormatAmount(usage.Actual, d.currency),
+			utils.FormatAmount(usage.Remaining, d.currency),
+			fmt.Sprintf("%.2f", usage.UsagePercentage),
+		}, aligns)
+	}
+}
+
+func (d *projectPDF) issues() {
+	d.section("Kendala")
+
+	if len(d.report.Issues) == 0 {
+		d.note("Tidak ada kendala pada periode ini.")
+		return
+	}
+
+	for _, log := range d.report.Issues {
+		d.ensureSpace(12)
+		d.pdf.SetFont("Helvetica", "B", 9)
+		d.pdf.CellFormat(0, 5, d.translate(logDate(log.LogDate)+" - "+log.Description), "", 1, "L", false, 0, "")
+		d.pdf.SetFont("Helvetica", "", 9)
+		d.pdf.MultiCell(0, 5, d.translate(log.Issues), "", "L", false)
+		d.pdf.Ln(2)
+	}
+}
+
+func (d *projectPDF) attachments() {
+	d.section("Indeks Lampiran")
+
+	if len(d.report.Attachments) == 0 {
+		d.note("Tidak ada lampiran pada periode ini.")
+		return
+	}
+
+	widths := []float64{10, 25, 95, 25, 25}
+	headers := []string{"No", "Tanggal", "File", "Status", "Ukuran"}
+	aligns := []string{"R", "L", "L", "L", "R"}
+
+	d.tableHeader(widths, headers)
+	for i, log := range d.report.Attachments {
+		if d.ensureSpace(6) {
+			d.tableHeader(widths, headers)
+		}
+
+		status := log.FileStatus.String
+		if status == "" {
+			status = utils.FileStatusPending
+		}
+
+		d.tableRow(widths, []string{strconv.Itoa(i + 1), logDate(log.LogDate), filepath.Base(log.File.String), status, utils.FormatBytes(log.FileSize)}, aligns)
+	}
+}
+
+func (d *projectPDF) section(title string) {
+	d.pdf.Ln(5)
+	d.ensureSpace(20)
+	d.pdf.SetFont("Helvetica", "B", 12)
+	d.pdf.CellFormat(0, 7, d.translate(title), "B", 1, "L", false, 0, "")
+	d.pdf.Ln(2)
+}
+
+func (d *projectPDF) note(text string) {
+	d.pdf.SetFont("Helvetica", "I", 9)
+	d.pdf.CellFormat(0, 6, d.translate(text), "", 1, "L", false, 0, "")
+}
+
+func (d *projectPDF) tableHeader(widths []float64, headers []string) {
+	d.pdf.SetFont("Helvetica", "B", 9)
+	d.pdf.SetFillColor(230, 230, 230)
+	for i, header := range headers {
+		d.pdf.CellFormat(widths[i], 6, d.translate(header), "1", 0, "L", true, 0, "")
+	}
+	d.pdf.Ln(-1)
+}
+
+// tableRow write one line row, text longer than the column is cut
+func (d *projectPDF) tableRow(widths []float64, row []string, aligns []string) {
+	d.pdf.SetFont("Helvetica", "", 9)
+	for i, text := range row {
+		d.pdf.CellFormat(widths[i], 6, d.fit(d.translate(text), widths[i]-2), "1", 0, aligns[i], false, 0, "")
+	}
+	d.pdf.Ln(-1)
+}
+
+// ensureSpace move to the next page when height does not fit on the current page, it returns true on new page
+func (d *projectPDF) ensureSpace(height float64) bool {
+	_, pageHeight := d.pdf.GetPageSize()
+	if d.pdf.GetY()+height <= pageHeight-reportBottomMargin {
+		return false
+	}
+
+	d.pdf.AddPage()
+	return true
+}
+
+func (d *projectPDF) fit(text string, width float64) string {
+	if d.pdf.GetStringWidth(text) <= width {
+		return text
+	}
+
+	for len(text) > 0 && d.pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+
+	return text + "..."
+}
+
+func reportDate(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return logDate(value)
+}
+
+// ProjectStatusName get the label of the project status
+func ProjectStatusName(status int) string {
+	switch status {
+	case 1:
+		return "Not Started"
+	case 2:
+		return "On-Going"
+	case 3:
+		return "Done"
+	default:
+		return "Pending"
+	}
+}
+
+// UserRoleName get the label of the user role
+func UserRoleName(role int) string {
+	switch role {
+	case 1:
+		return "Admin"
+	case 2:
+		return "User"
+	case 3:
+		return "Super Admin"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,82 @@
+package report
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/internal/repository"
+	"strings"
+	"time"
+)
+
+// BuildProjectReport collect the report data of the project from fromDate until toDate (YYYY-MM-DD). Stats are counted
+// until toDate with opts, so the cumulative balance and budget usage include the logs before the range
+func BuildProjectReport(tx *sql.Tx, dailyLogRepo repository.DailyLogRepository, budgetRepo repository.BudgetRepository, project models.Project, fromDate string, toDate string, opts models.StatsOptions) (models.ProjectReport, error) {
+	opts.AsOf = toDate
+
+	report := models.ProjectReport{
+		Project:        project,
+		FromDate:       fromDate,
+		ToDate:         toDate,
+		IncludePending: opts.IncludePending,
+		Cumulative:     []models.DailyLogStatsCumulative{},
+		Issues:         []models.DailyLog{},
+		Attachments:    []models.DailyLog{},
+		GeneratedAt:    time.Now().Format("2006-01-02 15:04"),
+	}
+
+	stats, err := dailyLogRepo.FindStats(tx, project.Id, opts)
+	if err != nil {
+		return report, err
+	}
+	report.Stats = stats
+
+	cumulative, err := dailyLogRepo.FindStatsCumulative(tx, project.Id, opts)
+	if err != nil {
+		return report, err
+	}
+
+	// the cumulative stats start from the first log, the logs before the range are the opening balance
+	for _, point := range cumulative {
+		if logDate(point.LogDate.String) < fromDate {
+			report.OpeningBalance = point.CumulativeSaldo
+			continue
+		}
+
+		report.PeriodIncome += point.Income
+		report.PeriodExpense += point.Expense
+		report.PeriodWorkingDays++
+		report.Cumulative = append(report.Cumulative, point)
+	}
+
+	report.BudgetUsage, err = budgetRepo.FindUsage(tx, project.Id, opts)
+	if err != nil {
+		return report, err
+	}
+
+	logs := []models.DailyLog{}
+	err = dailyLogRepo.Each(tx, "", project.Id, fromDate, toDate, 0, 0, func(log models.DailyLog) error {
+		logs = append(logs, log)
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// logs are ordered by newest date, the report list them from the oldest
+	for i := len(logs) - 1; i >= 0; i-- {
+		if strings.TrimSpace(logs[i].Issues) != "" {
+			report.Issues = append(report.Issues, logs[i])
+		}
+
+		if logs[i].File.String != "" {
+			report.Attachments = append(report.Attachments, logs[i])
+		}
+	}
+
+	return report, nil
+}
+
+// logDate get YYYY-MM-DD of the date scanned from the database
+func logDate(value string) string {
+	return strings.Split(value, "T")[0]
+}
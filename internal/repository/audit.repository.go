@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/models"
+	"strconv"
+)
+
+type AuditRepository interface {
+	Create(tx *sql.Tx, event *models.AuditEvent) error
+	FindWithPagination(tx *sql.Tx, size int, page int, filter models.AuditFilter) ([]models.AuditEvent, int, error)
+	Each(tx *sql.Tx, filter models.AuditFilter, fn func(event models.AuditEvent) error) error
+	EachLink(tx *sql.Tx, fn func(link models.AuditChainLink) error) error
+	FindHead(tx *sql.Tx) (int64, string, error)
+	FindHash(tx *sql.Tx, id int64) (string, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+const auditColumns = "id, actor_id, actor_name, action, entity_type, entity_id, before_data, after_data, ip, user_agent, request_id, prev_hash, hash, created_at"
+
+// auditHashExpr is the hash of an audit event row. The event is hashed as a json array so a value can not be moved
+// to the next field, jsonb text is used because the stored jsonb is normalized
+const auditHashExpr = `encode(sha256(convert_to(jsonb_build_array(
+	prev_hash, id, actor_id, actor_name, action, entity_type, entity_id, before_data, after_data, ip, user_agent, request_id,
+	to_char(created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US')
+)::text, 'UTF8')), 'hex')`
+
+// auditChainLock is the advisory lock key held while an event is appended, so the events are chained one by one. The
+// chain need a single order of every event, so it is one global lock. Handlers append the events just before commit
+// (utils.BeforeCommit), so a request only wait for the append and commit of the others, not their whole transaction
+const auditChainLock = 7310042
+
+func scanAuditEvent(row rowScanner) (models.AuditEvent, error) {
+	var (
+		event  models.AuditEvent
+		before []byte
+		after  []byte
+	)
+
+	if err := row.Scan(&event.Id, &event.ActorId, &event.ActorName, &event.Action, &event.EntityType, &event.EntityId, &before, &after, &event.Ip, &event.UserAgent, &event.RequestId, &event.PrevHash, &event.Hash, &event.CreatedAt); err != nil {
+		return event, err
+	}
+
+	if before != nil {
+		event.Before = json.RawMessage(before)
+	}
+
+	if after != nil {
+		event.After = json.RawMessage(after)
+	}
+
+	return event, nil
+}
+
+// auditFilterQuery build the where conditions of the filter, starting with " and"
+func auditFilterQuery(filter models.AuditFilter) (string, []interface{}) {
+	query := ""
+	paramData := []interface{}{}
+
+	if filter.Search != "" {
+		paramData = append(paramData, "%"+filter.Search+"%")
+		param := "$" + strconv.Itoa(len(paramData))
+		query += " and (actor_name ilike " + param + " or entity_id ilike " + param + " or action ilike " + param + ")"
+	}
+
+	if filter.ActorId != 0 {
+		paramData = append(paramData, filter.ActorId)
+		query += " and actor_id = $" + strconv.Itoa(len(paramData))
+	}
+
+	if filter.Action != "" {
+		paramData = append(paramData, filter.Action)
+		query += " and action = $" + strconv.Itoa(len(paramData))
+	}
+
+	if filter.EntityType != "" {
+		paramData = append(paramData, filter.EntityType)
+		query += " and entity_type = $" + strconv.Itoa(len(paramData))
+	}
+
+	if filter.EntityId != "" {
+		paramData = append(paramData, filter.EntityId)
+		query += " and entity_id = $" + strconv.Itoa(len(paramData))
+	}
+
+	if filter.FromDate != "" {
+		paramData = append(paramData, filter.FromDate)
+		query += " and created_at >= $" + strconv.Itoa(len(paramData)) + "::date"
+	}
+
+	if filter.ToDate != "" {
+		paramData = append(paramData, filter.ToDate)
+		query += " and created_at < $" + strconv.Itoa(len(paramData)) + "::date + 1"
+	}
+
+	return query, paramData
+}
+
+// Create append the event to the chain. The chain lock is held until the transaction end, so the next event is only
+// chained after this one committed or rolled back. It should be the last write of the transaction
+func (r *auditRepository) Create(tx *sql.Tx, event *models.AuditEvent) error {
+	if _, err := tx.Exec("select pg_advisory_xact_lock($1)", auditChainLock); err != nil {
+		return err
+	}
+
+	_, prevHash, err := r.FindHead(tx)
+	if err != nil {
+		return err
+	}
+	event.PrevHash = prevHash
+
+	query := `
+		insert into audit_events (actor_id, actor_name, action, entity_type, entity_id, before_data, after_data, ip, user_agent, request_id, prev_hash)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		returning id, created_at
+	`
+
+	// nil json is stored as sql null instead of json null
+	var before, after interface{}
+	if event.Before != nil {
+		before = []byte(event.Before)
+	}
+
+	if event.After != nil {
+		after = []byte(event.After)
+	}
+
+	if err := tx.QueryRow(query, event.ActorId, event.ActorName, event.Action, event.EntityType, event.EntityId, before, after, event.Ip, event.UserAgent, event.RequestId, event.PrevHash).Scan(&event.Id, &event.CreatedAt); err != nil {
+		return err
+	}
+
+	// hash is calculated from the stored row, the same way the chain is verified
+	return tx.QueryRow("update audit_events set hash = "+auditHashExpr+" where id = $1 returning hash", event.Id).Scan(&event.Hash)
+}
+
+// FindWithPagination get filtered events, newest first
+func (r *auditRepository) FindWithPagination(tx *sql.Tx, size int, page int, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	var total int
+	events := []models.AuditEvent{}
+
+	filterQuery, paramData := auditFilterQuery(filter)
+
+	// count total row before pagination
+	if err := tx.QueryRow("select count(id) from audit_events where 1=1"+filterQuery, paramData...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	paramData = append(paramData, size, (page-1)*size)
+	query := "select " + auditColumns + " from audit_events where 1=1" + filterQuery +
+		" order by id desc limit $" + strconv.Itoa(len(paramData)-1) + " offset $" + strconv.Itoa(len(paramData))
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, total, nil
+}
+
+// Each call fn for every filtered event, newest first, without loading all of them into memory
+func (r *auditRepository) Each(tx *sql.Tx, filter models.AuditFilter, fn func(event models.AuditEvent) error) error {
+	filterQuery, paramData := auditFilterQuery(filter)
+
+	rows, err := tx.Query("select "+auditColumns+" from audit_events where 1=1"+filterQuery+" order by id desc", paramData...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// EachLink call fn for every event of the chain in id order with the hash calculated again from the stored event
+func (r *auditRepository) EachLink(tx *sql.Tx, fn func(link models.AuditChainLink) error) error {
+	rows, err := tx.Query("select id, prev_hash, hash, " + auditHashExpr + " from audit_events order by id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link models.AuditChainLink
+
+		if err := rows.Scan(&link.Id, &link.PrevHash, &link.Hash, &link.ComputedHash); err != nil {
+			return err
+		}
+
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// FindHead get id and hash of the last event, 0 and empty hash when there is no event
+func (r *auditRepository) FindHead(tx *sql.Tx) (int64, string, error) {
+	var (
+		id   int64
+		hash string
+	)
+
+	err := tx.QueryRow("select id, hash from audit_events order by id desc limit 1").Scan(&id, &hash)
+	if (err != nil) && (err != sql.ErrNoRows) {
+		return 0, "", err
+	}
+
+	return id, hash, nil
+}
+
+func (r *auditRepository) FindHash(tx *sql.Tx, id int64) (string, error) {
+	var hash string
+
+	if err := tx.QueryRow("select hash from audit_events where id = $1", id).Scan(&hash); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
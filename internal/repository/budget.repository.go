@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"strconv"
+	"time"
+)
+
+type BudgetRepository interface {
+	FindUsage(tx *sql.Tx, projectId int, opts models.StatsOptions) ([]models.BudgetUsage, error)
+	UpsertCategoryBudget(tx *sql.Tx, projectId int, budget *models.CategoryBudgetInput) error
+	DeleteCategoryBudget(tx *sql.Tx, projectId int, categoryId int) error
+	FindAlerts(tx *sql.Tx, projectId int, userId int, unreadOnly bool) ([]models.BudgetAlert, error)
+	FindAlertByID(tx *sql.Tx, id int) (models.BudgetAlert, error)
+	CreateAlert(tx *sql.Tx, alert *models.BudgetAlert) (bool, error)
+	DeleteAlertsAbove(tx *sql.Tx, projectId int, categoryId int, usage float64) error
+	MarkAlertRead(tx *sql.Tx, id int) error
+	FindUnsentAlerts(tx *sql.Tx, maxAge time.Duration) ([]models.BudgetAlert, error)
+	MarkAlertEmailed(tx *sql.Tx, id int) error
+	FindRevisions(tx *sql.Tx, projectId int, status string) ([]models.BudgetRevision, error)
+	FindRevisionByID(tx *sql.Tx, id int, forUpdate bool) (models.BudgetRevision, error)
+	CreateRevision(tx *sql.Tx, revision *models.BudgetRevision) error
+	ReviewRevision(tx *sql.Tx, id int, status string, reviewerId int, note string) error
+}
+
+type budgetRepository struct {
+	db *sql.DB
+}
+
+func NewBudgetRepository(db *sql.DB) BudgetRepository {
+	return &budgetRepository{db}
+}
+
+// FindUsage get budget vs actual expense of every category that has budget or expense on the project,
+// expense of logs without line items is not included because it has no category. Budget is converted with the
+// current rate and expense with the rate on the log date
+func (r *budgetRepository) FindUsage(tx *sql.Tx, projectId int, opts models.StatsOptions) ([]models.BudgetUsage, error) {
+	usages := []models.BudgetUsage{}
+
+	query := `
+		WITH actuals AS (
+			SELECT li.category_id, ROUND(SUM(fx_convert(li.amount, dl.currency, $2, dl.log_date)))::bigint as actual
+			FROM log_line_items li JOIN daily_logs dl ON dl.id = li.daily_log_id
+			WHERE dl.project_id = $1 AND li.type = 'expense' AND li.category_id IS NOT NULL AND ` + logStatusFilter("dl", opts) + `
+			GROUP BY li.category_id
+		)
+		SELECT ec.id, ec.name, COALESCE(ROUND(fx_convert(b.amount, p.currency, $2, CURRENT_DATE))::bigint, 0), COALESCE(a.actual, 0)
+		FROM expense_categories ec
+		LEFT JOIN project_category_budgets b ON b.category_id = ec.id AND b.project_id = $1
+		LEFT JOIN projects p ON p.id = b.project_id
+		LEFT JOIN actuals a ON a.category_id = ec.id
+		WHERE b.id IS NOT NULL OR a.actual IS NOT NULL
+		ORDER BY ec.name
+	`
+
+	rows, err := tx.Query(query, projectId, opts.Currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		usage := models.BudgetUsage{Currency: opts.Currency}
+
+		if err := rows.Scan(&usage.CategoryId, &usage.CategoryName, &usage.Budget, &usage.Actual); err != nil {
+			return nil, err
+		}
+
+		usage.Remaining = usage.Budget - usage.Actual
+		usage.UsagePercentage = percentage(usage.Actual, usage.Budget)
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+func (r *budgetRepository) UpsertCategoryBudget(tx *sql.Tx, projectId int, budget *models.CategoryBudgetInput) error {
+	query := `
+		insert into project_category_budgets (project_id, category_id, amount) values ($1, $2, $3)
+		on conflict (project_id, category_id) do update set amount = excluded.amount, updated_at = now()
+	`
+	if _, err := tx.Exec(query, projectId, budget.CategoryId, budget.Amount); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *budgetRepository) DeleteCategoryBudget(tx *sql.Tx, projectId int, categoryId int) error {
+	if _, err := tx.Exec("delete from project_category_budgets where project_id = $1 and category_id = $2", projectId, categoryId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindAlerts get alerts of the project, or all projects of the user when projectId is 0, or all projects when both are 0
+func (r *budgetRepository) FindAlerts(tx *sql.Tx, projectId int, userId int, unreadOnly bool) ([]models.BudgetAlert, error) {
+	alerts := []models.BudgetAlert{}
+
+	query := `
+		select a.id, a.project_id, p.name, a.category_id, coalesce(ec.name, ''), a.threshold, a.usage_percentage,
+			a.budget, a.actual, a.is_read, a.emailed_at, a.created_at
+		from budget_alerts a
+		join projects p on p.id = a.project_id
+		left join expense_categories ec on ec.id = a.category_id
+		where 1=1`
+	paramData := []interface{}{}
+
+	if projectId != 0 {
+		paramData = append(paramData, projectId)
+		query += " and a.project_id = $" + strconv.Itoa(len(paramData))
+	}
+
+	if userId != 0 {
+		paramData = append(paramData, userId)
+		query += " and p.created_by = $" + strconv.Itoa(len(paramData))
+	}
+
+	if unreadOnly {
+		query += " and a.is_read = false"
+	}
+
+	query += " order by a.created_at desc, a.id desc limit 100"
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alert models.BudgetAlert
+
+		if err := rows.Scan(&alert.Id, &alert.ProjectId, &alert.ProjectName, &alert.CategoryId, &alert.CategoryName, &alert.Threshold, &alert.UsagePercentage,
+			&alert.Budget, &alert.Actual, &alert.IsRead, &alert.EmailedAt, &alert.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+func (r *budgetRepository) FindAlertByID(tx *sql.Tx, id int) (models.BudgetAlert, error) {
+	var alert models.BudgetAlert
+
+	query := `
+		select a.id, a.project_id, p.name, a.category_id, a.threshold, a.usage_percentage, a.budget, a.actual, a.is_read, a.created_at
+		from budget_alerts a join projects p on p.id = a.project_id
+		where a.id = $1
+	`
+	if err := tx.QueryRow(query, id).Scan(&alert.Id, &alert.ProjectId, &alert.ProjectName, &alert.CategoryId, &alert.Threshold, &alert.UsagePercentage,
+		&alert.Budget, &alert.Actual, &alert.IsRead, &alert.CreatedAt); err != nil {
+		return alert, err
+	}
+
+	return alert, nil
+}
+
+// CreateAlert insert alert if the threshold of the scope has no alert yet, false is returned when it already has
+func (r *budgetRepository) CreateAlert(tx *sql.Tx, alert *models.BudgetAlert) (bool, error) {
+	query := `
+		insert into budget_alerts (project_id, category_id, threshold, usage_percentage, budget, actual) values ($1, $2, $3, $4, $5, $6)
+		on conflict (project_id, (coalesce(category_id, 0)), threshold) do nothing
+	`
+	result, err := tx.Exec(query, alert.ProjectId, alert.CategoryId, alert.Threshold, alert.UsagePercentage, alert.Budget, alert.Actual)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteAlertsAbove delete alerts of the scope with threshold higher than the usage, category 0 is the whole project
+func (r *budgetRepository) DeleteAlertsAbove(tx *sql.Tx, projectId int, categoryId int, usage float64) error {
+	if _, err := tx.Exec("delete from budget_alerts where project_id = $1 and coalesce(category_id, 0) = $2 and threshold > $3::numeric", projectId, categoryId, usage); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *budgetRepository) MarkAlertRead(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("update budget_alerts set is_read = true where id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindUnsentAlerts get alerts that not emailed yet and not older than maxAge with the project owner email,
+// locked so the alert is only sent once
+func (r *budgetRepository) FindUnsentAlerts(tx *sql.Tx, maxAge time.Duration) ([]models.BudgetAlert, error) {
+	alerts := []models.BudgetAlert{}
+
+	query := `
+		select a.id, a.project_id, p.name, a.category_id, coalesce(ec.name, ''), a.threshold, a.usage_percentage,
+			a.budget, a.actual, a.created_at, coalesce(u.email, '')
+		from budget_alerts a
+		join projects p on p.id = a.project_id
+		join users u on u.id = p.created_by
+		left join expense_categories ec on ec.id = a.category_id
+		where a.emailed_at is null and a.created_at > now() - make_interval(secs => $1::float8)
+		order by a.id
+		for update of a skip locked
+	`
+
+	rows, err := tx.Query(query, maxAge.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var alert models.BudgetAlert
+
+		if err := rows.Scan(&alert.Id, &alert.ProjectId, &alert.ProjectName, &alert.CategoryId, &alert.CategoryName, &alert.Threshold, &alert.UsagePercentage,
+			&alert.Budget, &alert.Actual, &alert.CreatedAt, &alert.OwnerEmail); err != nil {
+			return nil, err
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+func (r *budgetRepository) MarkAlertEmailed(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("update budget_alerts set emailed_at = now() where id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindRevisions get budget revisions of the project (all projects when projectId is 0), filtered by status when it is set
+func (r *budgetRepository) FindRevisions(tx *sql.Tx, projectId int, status string) ([]models.BudgetRevision, error) {
+	revisions := []models.BudgetRevision{}
+
+	query := `
+		select br.id, br.project_id, p.name, br.old_budget, br.new_budget, br.reason, br.status, br.requested_by, coalesce(ru.username, ''),
+			br.reviewed_by, coalesce(vu.username, ''), br.review_note, br.reviewed_at, br.created_at
+		from budget_revisions br
+		join projects p on p.id = br.project_id
+		left join users ru on ru.id = br.requested_by
+		left join users vu on vu.id = br.reviewed_by
+		where 1=1`
+	paramData := []interface{}{}
+
+	if projectId != 0 {
+		paramData = append(paramData, projectId)
+		query += " and br.project_id = $" + strconv.Itoa(len(paramData))
+	}
+
+	if status != "" {
+		paramData = append(paramData, status)
+		query += " and br.status = $" + strconv.Itoa(len(paramData))
+	}
+
+	query += " order by br.created_at desc, br.id desc limit 200"
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision models.BudgetRevision
+
+		if err := rows.Scan(&revision.Id, &revision.ProjectId, &revision.ProjectName, &revision.OldBudget, &revision.NewBudget, &revision.Reason, &revision.Status,
+			&revision.RequestedBy, &revision.RequestedByName, &revision.ReviewedBy, &revision.ReviewedByName, &revision.ReviewNote, &revision.ReviewedAt, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// FindRevisionByID get budget revision, forUpdate lock the revision so it is only reviewed once
+func (r *budgetRepository) FindRevisionByID(tx *sql.Tx, id int, forUpdate bool) (models.BudgetRevision, error) {
+	var revision models.BudgetRevision
+
+	query := `
+		select br.id, br.project_id, p.name, br.old_budget, br.new_budget, br.reason, br.status, br.requested_by, br.reviewed_by, br.review_note, br.reviewed_at, br.created_at
+		from budget_revisions br join projects p on p.id = br.project_id
+		where br.id = $1
+	`
+	if forUpdate {
+		query += " for update of br"
+	}
+
+	if err := tx.QueryRow(query, id).Scan(&revision.Id, &revision.ProjectId, &revision.ProjectName, &revision.OldBudget, &revision.NewBudget, &revision.Reason, &revision.Status,
+		&revision.RequestedBy, &revision.ReviewedBy, &revision.ReviewNote, &revision.ReviewedAt, &revision.CreatedAt); err != nil {
+		return revision, err
+	}
+
+	return revision, nil
+}
+
+// CreateRevision insert revision, approved revision is reviewed by the requester at once
+func (r *budgetRepository) CreateRevision(tx *sql.Tx, revision *models.BudgetRevision) error {
+	query := `
+		insert into budget_revisions (project_id, old_budget, new_budget, reason, status, requested_by, reviewed_by, reviewed_at)
+		values ($1, $2, $3, $4, $5, $6, case when $5 = 'approved' then $6 end, case when $5 = 'approved' then now() end)
+		returning id, created_at
+	`
+	if err := tx.QueryRow(query, revision.ProjectId, revision.OldBudget, revision.NewBudget, revision.Reason, revision.Status, revision.RequestedBy).Scan(&revision.Id, &revision.CreatedAt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *budgetRepository) ReviewRevision(tx *sql.Tx, id int, status string, reviewerId int, note string) error {
+	query := "update budget_revisions set status = $1, reviewed_by = $2, review_note = nullif($3, ''), reviewed_at = now() where id = $4"
+	if _, err := tx.Exec(query, status, reviewerId, note, id); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"database/sql"
 	"fiber-prjct-management-web/internal/models"
+	"math"
+	"sort"
 	"strconv"
 )
 
@@ -10,12 +12,19 @@ type DailyLogRepository interface {
 	Create(tx *sql.Tx, log *models.DailyLogInput) error
 	Update(tx *sql.Tx, log *models.DailyLogInput, logId int) error
 	Delete(tx *sql.Tx, id int) error
+	UpdateTotalsFromItems(tx *sql.Tx, id int) error
 	FindWithPagination(tx *sql.Tx, size int, page int, search string, projectId int, fromDate string, toDate string, userId int, userRole int) ([]models.DailyLog, int, error)
+	Each(tx *sql.Tx, search string, projectId int, fromDate string, toDate string, userId int, userRole int, fn func(log models.DailyLog) error) error
 	FindByID(tx *sql.Tx, id int) (models.DailyLog, error)
 	FindByDate(tx *sql.Tx, date string, projectId int) (models.DailyLog, error)
 	FindIfProjectAndLogOwner(tx *sql.Tx, projectId int, logId int, userId int) (models.DailyLog, error)
-	FindStats(tx *sql.Tx, projectId int) (models.DailyLogStats, error)
-	FindStatsCumulative(tx *sql.Tx, projectId int) ([]models.DailyLogStatsCumulative, error)
+	UpdateStatus(tx *sql.Tx, id int, status string, reviewerId int) error
+	FindStats(tx *sql.Tx, projectId int, opts models.StatsOptions) (models.DailyLogStats, error)
+	FindStatsCumulative(tx *sql.Tx, projectId int, opts models.StatsOptions) ([]models.DailyLogStatsCumulative, error)
+	FindCategoryStats(tx *sql.Tx, projectId int, userId int, opts models.StatsOptions) (models.CategoryStats, error)
+	FindPendingFileScan(tx *sql.Tx) ([]models.DailyLog, error)
+	UpdateFileStatus(tx *sql.Tx, id int, scannedFile string, file string, status string, thumbnail string) (bool, error)
+	FindFileReferences(tx *sql.Tx) ([]models.StorageFileReference, error)
 }
 
 type dailyLogRepository struct {
@@ -34,13 +43,82 @@ func (r *dailyLogRepository) FindWithPagination(tx *sql.Tx, size int, page int,
 	offset := (page - 1) * size
 
 	baseQueryCnt := "select count(dl.id) from daily_logs dl left join projects p on dl.project_id = p.id where 1=1"
-	baseQuery := `
+	baseQuery := dailyLogListQuery
+
+	paramQuery, dataQuery := dailyLogFilterQuery(search, projectId, fromDate, toDate, userId, userRole)
+	index := len(dataQuery) + 1
+
+	// count
+	baseQueryCnt += paramQuery
+	if err := tx.QueryRow(baseQueryCnt, dataQuery...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// all data
+	baseQuery += paramQuery + " order by log_date desc limit $" + strconv.Itoa(index) + " offset $" + strconv.Itoa(index+1)
+	dataQuery = append(dataQuery, size, offset)
+	rows, err := tx.Query(baseQuery, dataQuery...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanDailyLogListRow(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, total, nil
+}
+
+// Each call fn for every filtered log, newest date first, without loading all of them into memory
+func (r *dailyLogRepository) Each(tx *sql.Tx, search string, projectId int, fromDate string, toDate string, userId int, userRole int, fn func(log models.DailyLog) error) error {
+	paramQuery, dataQuery := dailyLogFilterQuery(search, projectId, fromDate, toDate, userId, userRole)
+
+	rows, err := tx.Query(dailyLogListQuery+paramQuery+" order by log_date desc", dataQuery...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		log, err := scanDailyLogListRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(log); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+const dailyLogListQuery = `
 		select 
-			dl.id, dl.project_id, dl.log_date, dl.description, dl.issues, dl.income, dl.expense, dl.file, dl.created_at, dl.updated_at, p.name
+			dl.id, dl.project_id, dl.log_date, dl.description, dl.issues, dl.income, dl.expense, dl.currency, dl.file, dl.file_status, dl.file_thumbnail, dl.file_size, 
+			(select count(li.id) from log_line_items li where li.daily_log_id = dl.id) as item_count, dl.has_line_items,
+			dl.status, dl.reviewer_id, dl.submitted_at, dl.reviewed_at,
+			dl.created_at, dl.updated_at, p.name
 		from 
 			daily_logs dl left join projects p on dl.project_id = p.id
 		where 1=1`
 
+func scanDailyLogListRow(row rowScanner) (models.DailyLog, error) {
+	var log models.DailyLog
+
+	err := row.Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.Currency, &log.File, &log.FileStatus, &log.FileThumbnail, &log.FileSize, &log.ItemCount, &log.HasLineItems, &log.Status, &log.ReviewerId, &log.SubmittedAt, &log.ReviewedAt, &log.CreatedAt, &log.UpdatedAt, &log.ProjectName)
+	return log, err
+}
+
+// dailyLogFilterQuery build the where conditions of the log list, starting with " and". Without project the logs are
+// from the user projects, except for superadmin
+func dailyLogFilterQuery(search string, projectId int, fromDate string, toDate string, userId int, userRole int) (string, []interface{}) {
 	paramQuery := ""
 	dataQuery := []interface{}{}
 	index := 1
@@ -66,61 +144,42 @@ func (r *dailyLogRepository) FindWithPagination(tx *sql.Tx, size int, page int,
 	if projectId != 0 {
 		paramQuery += " and dl.project_id=$" + strconv.Itoa(index)
 		dataQuery = append(dataQuery, projectId)
-		index++
 	} else {
 		if userRole != 3 { // 3 is superadmin
 			paramQuery += " and p.created_by=$" + strconv.Itoa(index)
 			dataQuery = append(dataQuery, userId)
-			index++
 		}
 	}
 
-	// count
-	baseQueryCnt += paramQuery
-	if err := tx.QueryRow(baseQueryCnt, dataQuery...).Scan(&total); err != nil {
-		return nil, 0, err
-	}
-
-	// all data
-	baseQuery += paramQuery + " order by log_date desc limit $" + strconv.Itoa(index) + " offset $" + strconv.Itoa(index+1)
-	dataQuery = append(dataQuery, size, offset)
-	index += 2
-	rows, err := tx.Query(baseQuery, dataQuery...)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var log models.DailyLog
-
-		err := rows.Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.File, &log.CreatedAt, &log.UpdatedAt, &log.ProjectName)
-		if err != nil {
-			return nil, 0, err
-		}
-
-		logs = append(logs, log)
-	}
-
-	return logs, total, nil
+	return paramQuery, dataQuery
 }
 
-func (r *dailyLogRepository) FindStats(tx *sql.Tx, projectId int) (models.DailyLogStats, error) {
+// FindStats get project stats, log amounts are converted with the rate on the log date and budget with the current rate.
+// With AsOf only logs until the date are counted and the budget is the one in effect on the date
+func (r *dailyLogRepository) FindStats(tx *sql.Tx, projectId int, opts models.StatsOptions) (models.DailyLogStats, error) {
 
-	var logStats models.DailyLogStats
+	logStats := models.DailyLogStats{Currency: opts.Currency}
 
 	query := `
-		WITH project_stats AS (
+		WITH logs AS (
 			SELECT 
-				COALESCE(SUM(income), 0) as total_income,
-				COALESCE(SUM(expense), 0) total_expense,
-				COUNT(id) as total_working_days,
-				MAX(CASE WHEN income = (SELECT MAX(income) FROM daily_logs WHERE project_id = $1) THEN log_date END) as highest_income_day,
-				MAX(CASE WHEN expense = (SELECT MAX(expense) FROM daily_logs WHERE project_id = $1) THEN log_date END) as highest_expense_day
+				log_date,
+				fx_convert(income, currency, $2, log_date) as income,
+				fx_convert(expense, currency, $2, log_date) as expense
 			FROM daily_logs
-			WHERE project_id = $1
+			WHERE project_id = $1 AND ($3::date IS NULL OR log_date <= $3::date) AND ` + logStatusFilter("daily_logs", opts) + `
+		), project_stats AS (
+			SELECT 
+				ROUND(COALESCE(SUM(income), 0))::bigint as total_income,
+				ROUND(COALESCE(SUM(expense), 0))::bigint total_expense,
+				COUNT(*) as total_working_days,
+				MAX(CASE WHEN income = (SELECT MAX(income) FROM logs) THEN log_date END) as highest_income_day,
+				MAX(CASE WHEN expense = (SELECT MAX(expense) FROM logs) THEN log_date END) as highest_expense_day
+			FROM logs
 		), project_info AS (
-			SELECT budget
+			SELECT ROUND(fx_convert(
+				CASE WHEN $3::date IS NULL THEN budget ELSE project_budget_at(id, $3::date) END, currency, $2, COALESCE($3::date, CURRENT_DATE)
+			))::bigint as budget
 			FROM projects
 			WHERE id = $1
 		)
@@ -140,7 +199,7 @@ func (r *dailyLogRepository) FindStats(tx *sql.Tx, projectId int) (models.DailyL
 			project_info pi
 	`
 
-	if err := tx.QueryRow(query, projectId).Scan(
+	if err := tx.QueryRow(query, projectId, opts.Currency, sql.NullString{String: opts.AsOf, Valid: opts.AsOf != ""}).Scan(
 		&logStats.TotalIncome,
 		&logStats.TotalExpense,
 		&logStats.Budget,
@@ -158,29 +217,51 @@ func (r *dailyLogRepository) FindStats(tx *sql.Tx, projectId int) (models.DailyL
 	return logStats, nil
 }
 
-func (r *dailyLogRepository) FindStatsCumulative(tx *sql.Tx, projectId int) ([]models.DailyLogStatsCumulative, error) {
+// FindStatsCumulative get income, expense and cumulative saldo per log date with the budget in effect on the date,
+// until AsOf date when it is set
+func (r *dailyLogRepository) FindStatsCumulative(tx *sql.Tx, projectId int, opts models.StatsOptions) ([]models.DailyLogStatsCumulative, error) {
 	logStats := []models.DailyLogStatsCumulative{}
 
 	query := `
+		WITH logs AS (
+			SELECT 
+				dl.log_date,
+				ROUND(fx_convert(dl.income, dl.currency, $2, dl.log_date))::bigint as income,
+				ROUND(fx_convert(dl.expense, dl.currency, $2, dl.log_date))::bigint as expense,
+				ROUND(fx_convert(project_budget_at(p.id, dl.log_date), p.currency, $2, dl.log_date))::bigint as budget
+			FROM daily_logs dl JOIN projects p ON p.id = dl.project_id
+			WHERE dl.project_id = $1 AND ($3::date IS NULL OR dl.log_date <= $3::date) AND ` + logStatusFilter("dl", opts) + `
+		), cumulative AS (
+			SELECT 
+				log_date,
+				income,
+				expense,
+				budget,
+				SUM(income - expense) OVER (ORDER BY log_date) as cumulative_saldo,
+				SUM(expense) OVER (ORDER BY log_date) as cumulative_expense
+			FROM logs
+		)
 		SELECT 
 			log_date,
 			income,
 			expense,
-			SUM(income - expense) OVER (ORDER BY log_date) as cumulative_saldo
-		FROM daily_logs
-		WHERE project_id = $1
+			cumulative_saldo,
+			budget,
+			CASE WHEN budget > 0 THEN ROUND((cumulative_expense::numeric / budget::numeric) * 100, 2) ELSE 0 END as budget_usage_percentage
+		FROM cumulative
 		ORDER BY log_date
 	`
 
-	rows, err := tx.Query(query, projectId)
+	rows, err := tx.Query(query, projectId, opts.Currency, sql.NullString{String: opts.AsOf, Valid: opts.AsOf != ""})
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var logStat models.DailyLogStatsCumulative
 
-		if err = rows.Scan(&logStat.LogDate, &logStat.Income, &logStat.Expense, &logStat.CumulativeSaldo); err != nil {
+		if err = rows.Scan(&logStat.LogDate, &logStat.Income, &logStat.Expense, &logStat.CumulativeSaldo, &logStat.Budget, &logStat.BudgetUsagePercentage); err != nil {
 			return nil, err
 		}
 
@@ -190,10 +271,126 @@ func (r *dailyLogRepository) FindStatsCumulative(tx *sql.Tx, projectId int) ([]m
 	return logStats, nil
 }
 
+// FindCategoryStats get expense per category and per month, for one project when projectId is set,
+// otherwise for all projects of the user (userId 0 is all projects). Expense of log without line items is uncategorized
+func (r *dailyLogRepository) FindCategoryStats(tx *sql.Tx, projectId int, userId int, opts models.StatsOptions) (models.CategoryStats, error) {
+	stats := models.CategoryStats{
+		Currency:   opts.Currency,
+		Categories: []models.CategoryTotal{},
+		Monthly:    []models.CategoryMonthlyTotal{},
+	}
+
+	scope := "true"
+	paramData := []interface{}{}
+	if projectId != 0 {
+		scope = "p.id = $1"
+		paramData = append(paramData, projectId)
+	} else if userId != 0 {
+		scope = "p.created_by = $1"
+		paramData = append(paramData, userId)
+	}
+
+	paramData = append(paramData, opts.Currency)
+	currencyParam := "$" + strconv.Itoa(len(paramData))
+
+	if err := tx.QueryRow("SELECT ROUND(COALESCE(SUM(fx_convert(p.budget, p.currency, "+currencyParam+", CURRENT_DATE)), 0))::bigint FROM projects p WHERE "+scope, paramData...).Scan(&stats.Budget); err != nil {
+		return stats, err
+	}
+
+	query := `
+		WITH scoped_logs AS (
+			SELECT dl.id, dl.log_date, dl.expense, dl.currency
+			FROM daily_logs dl JOIN projects p ON p.id = dl.project_id
+			WHERE ` + scope + ` AND ` + logStatusFilter("dl", opts) + `
+		), expenses AS (
+			SELECT sl.log_date, li.category_id, fx_convert(li.amount, sl.currency, ` + currencyParam + `, sl.log_date) as amount
+			FROM log_line_items li JOIN scoped_logs sl ON sl.id = li.daily_log_id
+			WHERE li.type = 'expense'
+			UNION ALL
+			SELECT sl.log_date, NULL, fx_convert(sl.expense, sl.currency, ` + currencyParam + `, sl.log_date)
+			FROM scoped_logs sl
+			WHERE sl.expense > 0 AND NOT EXISTS (SELECT 1 FROM log_line_items li WHERE li.daily_log_id = sl.id)
+		)
+		SELECT
+			TO_CHAR(DATE_TRUNC('month', e.log_date), 'YYYY-MM') as month,
+			COALESCE(ec.id, 0) as category_id,
+			COALESCE(ec.name, 'Tanpa Kategori') as category_name,
+			ROUND(SUM(e.amount))::bigint as total
+		FROM expenses e LEFT JOIN expense_categories ec ON ec.id = e.category_id
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 3
+	`
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	categoryIndex := map[int]int{}
+	for rows.Next() {
+		var monthly models.CategoryMonthlyTotal
+
+		if err := rows.Scan(&monthly.Month, &monthly.CategoryId, &monthly.CategoryName, &monthly.Total); err != nil {
+			return stats, err
+		}
+
+		stats.Monthly = append(stats.Monthly, monthly)
+		stats.TotalExpense += monthly.Total
+
+		i, ok := categoryIndex[monthly.CategoryId]
+		if !ok {
+			i = len(stats.Categories)
+			categoryIndex[monthly.CategoryId] = i
+			stats.Categories = append(stats.Categories, models.CategoryTotal{CategoryId: monthly.CategoryId, CategoryName: monthly.CategoryName})
+		}
+		stats.Categories[i].Total += monthly.Total
+	}
+
+	for i := range stats.Categories {
+		stats.Categories[i].Percentage = percentage(stats.Categories[i].Total, stats.TotalExpense)
+		stats.Categories[i].BudgetUsage = percentage(stats.Categories[i].Total, stats.Budget)
+	}
+
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		return stats.Categories[i].Total > stats.Categories[j].Total
+	})
+
+	return stats, nil
+}
+
+// logStatusFilter get the condition of logs counted on stats, only approved logs unless pending logs are included.
+// Rejected logs are never counted
+func logStatusFilter(alias string, opts models.StatsOptions) string {
+	if opts.IncludePending {
+		return alias + ".status <> '" + models.LogStatusRejected + "'"
+	}
+
+	return alias + ".status = '" + models.LogStatusApproved + "'"
+}
+
+// percentage round to 2 decimal like the stats query
+func percentage(value int, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+
+	return math.Round(float64(value)/float64(total)*10000) / 100
+}
+
 func (r *dailyLogRepository) FindByID(tx *sql.Tx, id int) (models.DailyLog, error) {
 	var log models.DailyLog
 
-	if err := tx.QueryRow("select id, project_id, log_date, description, issues, income, expense, file from daily_logs where id=$1", id).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.File); err != nil {
+	query := `
+		select 
+			id, project_id, log_date, description, issues, income, expense, currency, file, file_status, file_thumbnail, file_size,
+			(select count(li.id) from log_line_items li where li.daily_log_id = daily_logs.id) as item_count, has_line_items,
+			status, reviewer_id, submitted_at, reviewed_at
+		from daily_logs 
+		where id=$1
+	`
+
+	if err := tx.QueryRow(query, id).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.Currency, &log.File, &log.FileStatus, &log.FileThumbnail, &log.FileSize, &log.ItemCount, &log.HasLineItems, &log.Status, &log.ReviewerId, &log.SubmittedAt, &log.ReviewedAt); err != nil {
 		return log, err
 	}
 
@@ -203,7 +400,7 @@ func (r *dailyLogRepository) FindByID(tx *sql.Tx, id int) (models.DailyLog, erro
 func (r *dailyLogRepository) FindByDate(tx *sql.Tx, date string, projectId int) (models.DailyLog, error) {
 	var log models.DailyLog
 
-	if err := tx.QueryRow("select id, project_id, log_date, description, issues, income, expense, file from daily_logs where log_date=$1 and project_id=$2", date, projectId).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.File); err != nil {
+	if err := tx.QueryRow("select id, project_id, log_date, description, issues, income, expense, file, file_status, file_thumbnail, file_size from daily_logs where log_date=$1 and project_id=$2", date, projectId).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.File, &log.FileStatus, &log.FileThumbnail, &log.FileSize); err != nil {
 		return log, err
 	}
 
@@ -216,7 +413,10 @@ func (r *dailyLogRepository) FindIfProjectAndLogOwner(tx *sql.Tx, projectId int,
 
 	query := `
 		select 
-			dl.id, dl.project_id, dl.log_date, dl.description, dl.issues, dl.income, dl.expense, dl.file, p.created_by
+			dl.id, dl.project_id, dl.log_date, dl.description, dl.issues, dl.income, dl.expense, dl.currency, dl.file, dl.file_status, dl.file_thumbnail, dl.file_size, 
+			(select count(li.id) from log_line_items li where li.daily_log_id = dl.id) as item_count, dl.has_line_items,
+			dl.status, dl.reviewer_id, dl.submitted_at, dl.reviewed_at,
+			p.created_by
 		from daily_logs dl left join projects p on dl.project_id = p.id
 		where 
 			dl.id= $1
@@ -224,7 +424,7 @@ func (r *dailyLogRepository) FindIfProjectAndLogOwner(tx *sql.Tx, projectId int,
 			and p.created_by = $3
 	`
 
-	if err := tx.QueryRow(query, logId, projectId, userId).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.File, &createdBy); err != nil {
+	if err := tx.QueryRow(query, logId, projectId, userId).Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.Description, &log.Issues, &log.Income, &log.Expense, &log.Currency, &log.File, &log.FileStatus, &log.FileThumbnail, &log.FileSize, &log.ItemCount, &log.HasLineItems, &log.Status, &log.ReviewerId, &log.SubmittedAt, &log.ReviewedAt, &createdBy); err != nil {
 		return log, err
 	}
 
@@ -232,7 +432,7 @@ func (r *dailyLogRepository) FindIfProjectAndLogOwner(tx *sql.Tx, projectId int,
 }
 
 func (r *dailyLogRepository) Create(tx *sql.Tx, log *models.DailyLogInput) error {
-	if _, err := tx.Exec("insert into daily_logs (project_id, log_date, description, issues, income, expense, file) values ($1, $2, $3, $4, $5, $6, $7)", log.ProjectId, log.LogDate, log.Description, log.Issues, log.Income, log.Expense, log.File); err != nil {
+	if _, err := tx.Exec("insert into daily_logs (project_id, log_date, description, issues, income, expense, file, file_status, file_thumbnail, file_size, currency) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", log.ProjectId, log.LogDate, log.Description, log.Issues, log.Income, log.Expense, log.File, log.FileStatus, log.FileThumbnail, log.FileSize, log.Currency); err != nil {
 		return err
 	}
 
@@ -240,7 +440,49 @@ func (r *dailyLogRepository) Create(tx *sql.Tx, log *models.DailyLogInput) error
 }
 
 func (r *dailyLogRepository) Update(tx *sql.Tx, log *models.DailyLogInput, logId int) error {
-	if _, err := tx.Exec("update daily_logs set project_id=$1, log_date=$2, description=$3, issues=$4, income=$5, expense=$6, file=$7, updated_at=now() where id=$8", log.ProjectId, log.LogDate, log.Description, log.Issues, log.Income, log.Expense, log.File, logId); err != nil {
+	if _, err := tx.Exec("update daily_logs set project_id=$1, log_date=$2, description=$3, issues=$4, income=$5, expense=$6, file=$7, file_status=$8, file_thumbnail=$9, file_size=$10, currency=$11, updated_at=now() where id=$12", log.ProjectId, log.LogDate, log.Description, log.Issues, log.Income, log.Expense, log.File, log.FileStatus, log.FileThumbnail, log.FileSize, log.Currency, logId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateTotalsFromItems set log income and expense from the sum of its line items. The first item mark the log as
+// itemized, after that the totals stay the sum of the items and are 0 when the last item is deleted. A log that never
+// had items keep its own totals
+func (r *dailyLogRepository) UpdateTotalsFromItems(tx *sql.Tx, id int) error {
+	query := `
+		update daily_logs 
+		set 
+			income = (select coalesce(sum(amount), 0) from log_line_items where daily_log_id = $1 and type = 'income'),
+			expense = (select coalesce(sum(amount), 0) from log_line_items where daily_log_id = $1 and type = 'expense'),
+			has_line_items = true,
+			updated_at = now()
+		where id = $1 and (has_line_items or exists (select 1 from log_line_items where daily_log_id = $1))
+	`
+
+	if _, err := tx.Exec(query, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateStatus set log review status. Submit set the reviewer (0 is any super admin) and the submitted time,
+// approve and reject set the reviewed time and draft clear both
+func (r *dailyLogRepository) UpdateStatus(tx *sql.Tx, id int, status string, reviewerId int) error {
+	query := `
+		update daily_logs 
+		set 
+			status = $1::varchar,
+			reviewer_id = case when $1::varchar = 'submitted' then nullif($2, 0) else reviewer_id end,
+			submitted_at = case when $1::varchar = 'submitted' then now() when $1::varchar = 'draft' then null else submitted_at end,
+			reviewed_at = case when $1::varchar in ('approved', 'rejected') then now() else null end,
+			updated_at = now()
+		where id = $3
+	`
+
+	if _, err := tx.Exec(query, status, reviewerId, id); err != nil {
 		return err
 	}
 
@@ -254,3 +496,81 @@ func (r *dailyLogRepository) Delete(tx *sql.Tx, id int) error {
 
 	return nil
 }
+
+func (r *dailyLogRepository) FindPendingFileScan(tx *sql.Tx) ([]models.DailyLog, error) {
+	logs := []models.DailyLog{}
+
+	// file status null is for file uploaded before scanning is implemented
+	query := `
+		select 
+			id, project_id, log_date, file, file_status, file_thumbnail, file_size
+		from daily_logs
+		where 
+			file is not null and file <> ''
+			and (file_status is null or file_status = 'pending')
+		order by id
+	`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log models.DailyLog
+
+		if err := rows.Scan(&log.Id, &log.ProjectId, &log.LogDate, &log.File, &log.FileStatus, &log.FileThumbnail, &log.FileSize); err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// UpdateFileStatus set the scan result of the scanned file, the log is not updated when its file changed while the
+// file was scanned
+func (r *dailyLogRepository) UpdateFileStatus(tx *sql.Tx, id int, scannedFile string, file string, status string, thumbnail string) (bool, error) {
+	result, err := tx.Exec("update daily_logs set file=$1, file_status=$2, file_thumbnail=$3 where id=$4 and file=$5", file, status, thumbnail, id, scannedFile)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// FindFileReferences get all files path that referenced by daily logs, include the thumbnails
+func (r *dailyLogRepository) FindFileReferences(tx *sql.Tx) ([]models.StorageFileReference, error) {
+	refs := []models.StorageFileReference{}
+
+	query := `
+		select id, project_id, file from daily_logs where file is not null and file <> ''
+		union all
+		select id, project_id, file_thumbnail from daily_logs where file_thumbnail is not null and file_thumbnail <> ''
+	`
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref models.StorageFileReference
+
+		if err := rows.Scan(&ref.LogId, &ref.ProjectId, &ref.Path); err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fiber-prjct-management-web/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPendingFileScan(t *testing.T) {
+	db := openFakeDB(t, []map[string]driver.Value{
+		{
+			"id":             int64(1),
+			"project_id":     int64(2),
+			"log_date":       "2024-05-01T00:00:00Z",
+			"file":           "web/uploads/quarantine/2/2024-05-01/report.pdf",
+			"file_status":    "pending",
+			"file_thumbnail": nil,
+			"file_size":      int64(2048),
+		},
+		{
+			"id":             int64(3),
+			"project_id":     int64(2),
+			"log_date":       "2024-05-02T00:00:00Z",
+			"file":           "web/uploads/2/2024-05-02/photo.jpg",
+			"file_status":    nil,
+			"file_thumbnail": "web/uploads/thumbnails/2/2024-05-02/photo.jpg",
+			"file_size":      int64(4096),
+		},
+	})
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	logs, err := NewDailyLogRepository(db).FindPendingFileScan(tx)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+
+	assert.Equal(t, 1, logs[0].Id)
+	assert.Equal(t, "web/uploads/quarantine/2/2024-05-01/report.pdf", logs[0].File.String)
+	assert.Equal(t, "pending", logs[0].FileStatus.String)
+	assert.False(t, logs[0].FileThumbnail.Valid)
+	assert.Equal(t, int64(2048), logs[0].FileSize)
+
+	assert.Equal(t, 3, logs[1].Id)
+	assert.False(t, logs[1].FileStatus.Valid)
+	assert.Equal(t, "web/uploads/thumbnails/2/2024-05-02/photo.jpg", logs[1].FileThumbnail.String)
+	assert.Equal(t, int64(4096), logs[1].FileSize)
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		query   string
+		columns []string
+	}{
+		{"select id, name from projects", []string{"id", "name"}},
+		{"SELECT p.id, u.username FROM projects p", []string{"id", "username"}},
+		{"select coalesce(ru.username, '') as requested_by_name, count(id) from x", []string{"requested_by_name", "count(id)"}},
+		{"select\n\tid, file_status\nfrom daily_logs", []string{"id", "file_status"}},
+	}
+
+	for _, tt := range tests {
+		columns, err := selectColumns(tt.query)
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.columns, columns, tt.query)
+	}
+}
+
+func TestUpdateTotalsFromItems(t *testing.T) {
+	db := openTestDB(t)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var ownerId, projectId, itemizedLogId, plainLogId int
+	_, err = tx.Exec("insert into role (id, name) values (2, 'user')")
+	require.NoError(t, err)
+	require.NoError(t, tx.QueryRow("insert into users (username, password) values ('owner', '') returning id").Scan(&ownerId))
+	require.NoError(t, tx.QueryRow("insert into projects (name, created_by) values ('Gedung', $1) returning id", ownerId).Scan(&projectId))
+	require.NoError(t, tx.QueryRow("insert into daily_logs (project_id, log_date, income, expense) values ($1, '2024-05-01', 0, 0) returning id", projectId).Scan(&itemizedLogId))
+	require.NoError(t, tx.QueryRow("insert into daily_logs (project_id, log_date, income, expense) values ($1, '2024-05-02', 100, 40) returning id", projectId).Scan(&plainLogId))
+
+	logRepo := NewDailyLogRepository(db)
+	itemRepo := NewLineItemRepository(db)
+
+	income := models.LineItemInput{DailyLogId: itemizedLogId, Type: "income", Quantity: 1, UnitPrice: 500, Amount: 500}
+	expense := models.LineItemInput{DailyLogId: itemizedLogId, Type: "expense", CategoryId: 1, Quantity: 2, UnitPrice: 75, Amount: 150}
+	require.NoError(t, itemRepo.Create(tx, &income))
+	require.NoError(t, itemRepo.Create(tx, &expense))
+	require.NoError(t, logRepo.UpdateTotalsFromItems(tx, itemizedLogId))
+
+	log, err := logRepo.FindByID(tx, itemizedLogId)
+	require.NoError(t, err)
+	assert.True(t, log.HasLineItems)
+	assert.Equal(t, 500, log.Income)
+	assert.Equal(t, 150, log.Expense)
+
+	require.NoError(t, itemRepo.Delete(tx, income.Id))
+	require.NoError(t, itemRepo.Delete(tx, expense.Id))
+	require.NoError(t, logRepo.UpdateTotalsFromItems(tx, itemizedLogId))
+
+	log, err = logRepo.FindByID(tx, itemizedLogId)
+	require.NoError(t, err)
+	assert.True(t, log.HasLineItems, "log stay itemized after the last item is deleted")
+	assert.Equal(t, 0, log.ItemCount)
+	assert.Equal(t, 0, log.Income)
+	assert.Equal(t, 0, log.Expense)
+
+	require.NoError(t, logRepo.UpdateTotalsFromItems(tx, plainLogId))
+
+	log, err = logRepo.FindByID(tx, plainLogId)
+	require.NoError(t, err)
+	assert.False(t, log.HasLineItems)
+	assert.Equal(t, 100, log.Income, "log that never had items keep its totals")
+	assert.Equal(t, 40, log.Expense)
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"strconv"
+)
+
+type ExchangeRateRepository interface {
+	FindAll(tx *sql.Tx, currency string) ([]models.ExchangeRate, error)
+	FindByID(tx *sql.Tx, id int) (models.ExchangeRate, error)
+	Upsert(tx *sql.Tx, rate *models.ExchangeRateInput) error
+	Delete(tx *sql.Tx, id int) error
+	HasRate(tx *sql.Tx, from string, to string) (bool, error)
+	CountPairRates(tx *sql.Tx, from string, to string) (int, error)
+	FindCurrenciesLosingRate(tx *sql.Tx, id int, to string) ([]string, error)
+}
+
+type exchangeRateRepository struct {
+	db *sql.DB
+}
+
+func NewExchangeRateRepository(db *sql.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{db}
+}
+
+// FindAll get rates ordered by newest date, filtered by base or quote currency when currency is set
+func (r *exchangeRateRepository) FindAll(tx *sql.Tx, currency string) ([]models.ExchangeRate, error) {
+	rates := []models.ExchangeRate{}
+
+	query := "select id, base_currency, quote_currency, rate_date, rate, created_at, updated_at from exchange_rates"
+	paramData := []interface{}{}
+	if currency != "" {
+		paramData = append(paramData, currency)
+		query += " where base_currency = $" + strconv.Itoa(len(paramData)) + " or quote_currency = $" + strconv.Itoa(len(paramData))
+	}
+	query += " order by rate_date desc, base_currency, quote_currency limit 1000"
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rate models.ExchangeRate
+
+		if err := rows.Scan(&rate.Id, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.RateDate, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func (r *exchangeRateRepository) FindByID(tx *sql.Tx, id int) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+
+	if err := tx.QueryRow("select id, base_currency, quote_currency, rate_date, rate, created_at, updated_at from exchange_rates where id = $1", id).Scan(&rate.Id, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.RateDate, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt); err != nil {
+		return rate, err
+	}
+
+	return rate, nil
+}
+
+// Upsert insert rate or replace the rate of the same pair and date
+func (r *exchangeRateRepository) Upsert(tx *sql.Tx, rate *models.ExchangeRateInput) error {
+	query := `
+		insert into exchange_rates (base_currency, quote_currency, rate_date, rate, created_by) values ($1, $2, $3, $4, nullif($5, 0))
+		on conflict (base_currency, quote_currency, rate_date) do update set rate = excluded.rate, created_by = excluded.created_by, updated_at = now()
+	`
+	if _, err := tx.Exec(query, rate.BaseCurrency, rate.QuoteCurrency, rate.RateDate, rate.Rate, rate.CreatedBy); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *exchangeRateRepository) Delete(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("delete from exchange_rates where id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HasRate check if the currency can be converted, directly or with the inverse rate
+func (r *exchangeRateRepository) HasRate(tx *sql.Tx, from string, to string) (bool, error) {
+	if from == to {
+		return true, nil
+	}
+
+	total, err := r.CountPairRates(tx, from, to)
+	if err != nil {
+		return false, err
+	}
+
+	return total > 0, nil
+}
+
+// CountPairRates count rates of the pair on both direction
+func (r *exchangeRateRepository) CountPairRates(tx *sql.Tx, from string, to string) (int, error) {
+	var total int
+
+	query := "select count(id) from exchange_rates where (base_currency = $1 and quote_currency = $2) or (base_currency = $2 and quote_currency = $1)"
+	if err := tx.QueryRow(query, from, to).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// FindCurrenciesLosingRate find the currencies of the rate pair that are used by projects or logs and can not be
+// converted into the to currency anymore without the rate, fx_rate use the rates of both direction
+func (r *exchangeRateRepository) FindCurrenciesLosingRate(tx *sql.Tx, id int, to string) ([]string, error) {
+	currencies := []string{}
+
+	query := `
+		select pair.currency
+		from 
+			exchange_rates r 
+			cross join lateral (values (r.base_currency), (r.quote_currency)) as pair (currency)
+		where 
+			r.id = $1
+			and pair.currency <> $2
+			and (
+				exists (select 1 from projects p where p.currency = pair.currency)
+				or exists (select 1 from daily_logs dl where dl.currency = pair.currency)
+			)
+			and not exists (
+				select 1 from exchange_rates er
+				where 
+					er.id <> $1
+					and ((er.base_currency = pair.currency and er.quote_currency = $2) or (er.base_currency = $2 and er.quote_currency = pair.currency))
+			)
+		order by pair.currency
+	`
+
+	rows, err := tx.Query(query, id, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currency string
+
+		if err := rows.Scan(&currency); err != nil {
+			return nil, err
+		}
+
+		currencies = append(currencies, currency)
+	}
+
+	return currencies, rows.Err()
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCurrenciesLosingRate(t *testing.T) {
+	db := openTestDB(t)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var ownerId, projectId int
+	_, err = tx.Exec("insert into role (id, name) values (2, 'user')")
+	require.NoError(t, err)
+	require.NoError(t, tx.QueryRow("insert into users (username, password) values ('owner', '') returning id").Scan(&ownerId))
+	require.NoError(t, tx.QueryRow("insert into projects (name, created_by, currency) values ('Gedung', $1, 'USD') returning id", ownerId).Scan(&projectId))
+	_, err = tx.Exec("insert into daily_logs (project_id, log_date, currency) values ($1, '2024-05-01', 'SGD')", projectId)
+	require.NoError(t, err)
+
+	rates := map[string]int{}
+	for _, rate := range []struct{ name, base, quote, date string }{
+		{"usd", "USD", "IDR", "2024-05-01"},
+		{"sgd", "IDR", "SGD", "2024-05-01"}, // inverse direction
+		{"eur", "EUR", "IDR", "2024-05-01"}, // not used
+		{"usd-sgd", "USD", "SGD", "2024-05-01"},
+		{"sgd-old", "SGD", "IDR", "2024-04-01"},
+	} {
+		var id int
+		require.NoError(t, tx.QueryRow("insert into exchange_rates (base_currency, quote_currency, rate_date, rate) values ($1, $2, $3, 1) returning id", rate.base, rate.quote, rate.date).Scan(&id))
+		rates[rate.name] = id
+	}
+
+	repo := NewExchangeRateRepository(db)
+
+	tests := []struct {
+		rate string
+		want []string
+	}{
+		{"usd", []string{"USD"}},
+		{"sgd", []string{}},     // SGD still has the opposite rate
+		{"eur", []string{}},     // EUR is not used
+		{"usd-sgd", []string{}}, // both keep their rate to IDR
+	}
+
+	for _, tt := range tests {
+		currencies, err := repo.FindCurrenciesLosingRate(tx, rates[tt.rate], "IDR")
+		require.NoError(t, err, tt.rate)
+		assert.Equal(t, tt.want, currencies, tt.rate)
+	}
+
+	// without the older rate the inverse rate is the last one of SGD
+	require.NoError(t, repo.Delete(tx, rates["sgd-old"]))
+
+	currencies, err := repo.FindCurrenciesLosingRate(tx, rates["sgd"], "IDR")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SGD"}, currencies)
+}
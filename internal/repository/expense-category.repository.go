@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+)
+
+type ExpenseCategoryRepository interface {
+	FindAll(tx *sql.Tx, includeInactive bool) ([]models.ExpenseCategory, error)
+	FindByID(tx *sql.Tx, id int) (models.ExpenseCategory, error)
+	Create(tx *sql.Tx, category *models.ExpenseCategoryInput) error
+	Update(tx *sql.Tx, category *models.ExpenseCategoryInput, id int) error
+	Delete(tx *sql.Tx, id int) error
+	CountItems(tx *sql.Tx, id int) (int, error)
+}
+
+type expenseCategoryRepository struct {
+	db *sql.DB
+}
+
+func NewExpenseCategoryRepository(db *sql.DB) ExpenseCategoryRepository {
+	return &expenseCategoryRepository{db}
+}
+
+func (r *expenseCategoryRepository) FindAll(tx *sql.Tx, includeInactive bool) ([]models.ExpenseCategory, error) {
+	categories := []models.ExpenseCategory{}
+
+	query := "select id, name, description, is_active, created_at, updated_at from expense_categories"
+	if !includeInactive {
+		query += " where is_active = true"
+	}
+	query += " order by name"
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category models.ExpenseCategory
+
+		if err := rows.Scan(&category.Id, &category.Name, &category.Description, &category.IsActive, &category.CreatedAt, &category.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, nil
+}
+
+func (r *expenseCategoryRepository) FindByID(tx *sql.Tx, id int) (models.ExpenseCategory, error) {
+	var category models.ExpenseCategory
+
+	if err := tx.QueryRow("select id, name, description, is_active, created_at, updated_at from expense_categories where id=$1", id).Scan(&category.Id, &category.Name, &category.Description, &category.IsActive, &category.CreatedAt, &category.UpdatedAt); err != nil {
+		return category, err
+	}
+
+	return category, nil
+}
+
+func (r *expenseCategoryRepository) Create(tx *sql.Tx, category *models.ExpenseCategoryInput) error {
+	isActive := true
+	if category.IsActive != nil {
+		isActive = *category.IsActive
+	}
+
+	if err := tx.QueryRow("insert into expense_categories (name, description, is_active) values ($1, $2, $3) returning id", category.Name, category.Description, isActive).Scan(&category.Id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Update category, is_active is only changed when provided
+func (r *expenseCategoryRepository) Update(tx *sql.Tx, category *models.ExpenseCategoryInput, id int) error {
+	if _, err := tx.Exec("update expense_categories set name=$1, description=$2, is_active=coalesce($3, is_active), updated_at=now() where id=$4", category.Name, category.Description, category.IsActive, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *expenseCategoryRepository) Delete(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("delete from expense_categories where id=$1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *expenseCategoryRepository) CountItems(tx *sql.Tx, id int) (int, error) {
+	var total int
+
+	if err := tx.QueryRow("select count(id) from log_line_items where category_id=$1", id).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakedb is a database/sql driver for repository tests without postgres. A query return the rows of the fixture, with
+// the columns taken from its select list, so database/sql fail the test when the select list and the Scan
+// destinations of a repository do not match
+func init() {
+	sql.Register("fakedb", fakeDriver{})
+}
+
+var (
+	fakeFixturesMu sync.Mutex
+	fakeFixtures   = map[string][]map[string]driver.Value{}
+)
+
+// openFakeDB open a database whose queries return rows, every row must have a value for each selected column
+func openFakeDB(t *testing.T, rows []map[string]driver.Value) *sql.DB {
+	t.Helper()
+
+	fakeFixturesMu.Lock()
+	fakeFixtures[t.Name()] = rows
+	fakeFixturesMu.Unlock()
+
+	db, err := sql.Open("fakedb", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+
+		fakeFixturesMu.Lock()
+		delete(fakeFixtures, t.Name())
+		fakeFixturesMu.Unlock()
+	})
+
+	return db
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeFixturesMu.Lock()
+	defer fakeFixturesMu.Unlock()
+
+	rows, ok := fakeFixtures[name]
+	if !ok {
+		return nil, fmt.Errorf("fakedb: no fixture %q", name)
+	}
+
+	return &fakeConn{rows: rows}, nil
+}
+
+type fakeConn struct {
+	rows []map[string]driver.Value
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	columns, err := selectColumns(s.query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{columns: columns, rows: s.conn.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    []map[string]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+
+	row := r.rows[r.next]
+	r.next++
+
+	for i, column := range r.columns {
+		value, ok := row[column]
+		if !ok {
+			return fmt.Errorf("fakedb: fixture has no column %q", column)
+		}
+
+		dest[i] = value
+	}
+
+	return nil
+}
+
+// selectColumns get the column names of the select list, "alias.column" is the column and "expr as name" is the name
+func selectColumns(query string) ([]string, error) {
+	lower := strings.ToLower(query)
+
+	start := strings.Index(lower, "select")
+	if start < 0 {
+		return nil, errors.New("fakedb: query is not a select")
+	}
+
+	columns := []string{}
+	depth := 0
+	from := start + len("select")
+	for i := from; i < len(lower); i++ {
+		switch lower[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				columns = append(columns, columnName(lower[from:i]))
+				from = i + 1
+			}
+		}
+
+		if depth == 0 && strings.HasPrefix(lower[i:], "from") && i > 0 && isSpace(lower[i-1]) {
+			columns = append(columns, columnName(lower[from:i]))
+			return columns, nil
+		}
+	}
+
+	return nil, errors.New("fakedb: select has no from")
+}
+
+func columnName(expr string) string {
+	expr = strings.TrimSpace(expr)
+
+	if i := strings.LastIndex(expr, " as "); i >= 0 {
+		return strings.TrimSpace(expr[i+len(" as "):])
+	}
+
+	if i := strings.LastIndex(expr, "."); i >= 0 && !strings.ContainsAny(expr, "()") {
+		return expr[i+1:]
+	}
+
+	return expr
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type JobRepository interface {
+	Enqueue(tx *sql.Tx, jobType string, payload interface{}, runAt time.Time, maxAttempts int, scheduleName string) (int64, error)
+	Claim(tx *sql.Tx, workerId string, now time.Time) (models.Job, error)
+	Complete(tx *sql.Tx, id int64) error
+	Fail(tx *sql.Tx, id int64, message string, retryAt time.Time) (string, error)
+	ReleaseStale(tx *sql.Tx, timeout time.Duration) (int64, error)
+	Retry(tx *sql.Tx, id int64, runAt time.Time) (bool, error)
+	DeleteFinished(tx *sql.Tx, maxAge time.Duration) (int64, error)
+	HasUnfinished(tx *sql.Tx, scheduleName string) (bool, error)
+	FindByID(tx *sql.Tx, id int64) (models.Job, error)
+	FindWithPagination(tx *sql.Tx, size int, page int, filter models.JobFilter) ([]models.Job, int, error)
+	CountByStatus(tx *sql.Tx) ([]models.JobStatusCount, error)
+
+	SaveSchedule(tx *sql.Tx, name string, jobType string, cron string, nextRunAt time.Time) error
+	ClaimDueSchedules(tx *sql.Tx, now time.Time) ([]models.JobSchedule, error)
+	UpdateScheduleRun(tx *sql.Tx, name string, nextRunAt time.Time) error
+	FindSchedules(tx *sql.Tx) ([]models.JobSchedule, error)
+	FindSchedule(tx *sql.Tx, name string) (models.JobSchedule, error)
+	DeleteSchedulesExcept(tx *sql.Tx, names []string) error
+}
+
+type jobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) JobRepository {
+	return &jobRepository{db}
+}
+
+const jobColumns = "id, type, payload, status, attempts, max_attempts, run_at, locked_at, locked_by, last_error, schedule_name, created_at, finished_at"
+
+func scanJob(row rowScanner) (models.Job, error) {
+	var job models.Job
+
+	err := row.Scan(&job.Id, &job.Type, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.RunAt, &job.LockedAt, &job.LockedBy, &job.LastError, &job.ScheduleName, &job.CreatedAt, &job.FinishedAt)
+	return job, err
+}
+
+// Enqueue add a pending job that run at runAt, payload is stored as json and scheduleName is empty for a job that is
+// not enqueued by a schedule
+func (r *jobRepository) Enqueue(tx *sql.Tx, jobType string, payload interface{}, runAt time.Time, maxAttempts int, scheduleName string) (int64, error) {
+	var id int64
+
+	if payload == nil {
+		payload = struct{}{}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		insert into jobs (type, payload, run_at, max_attempts, schedule_name) values ($1, $2, $3, $4, $5)
+		returning id
+	`
+
+	err = tx.QueryRow(query, jobType, data, runAt, maxAttempts, sql.NullString{String: scheduleName, Valid: scheduleName != ""}).Scan(&id)
+	return id, err
+}
+
+// Claim lock the oldest pending job due at now for the worker and mark it running, a job locked by another worker is
+// skipped. sql.ErrNoRows is returned when there is no due job
+func (r *jobRepository) Claim(tx *sql.Tx, workerId string, now time.Time) (models.Job, error) {
+	query := `
+		update jobs
+		set status = 'running', attempts = attempts + 1, locked_at = now(), locked_by = $1
+		where id = (
+			select id from jobs
+			where status = 'pending' and run_at <= $2
+			order by run_at, id
+			limit 1
+			for update skip locked
+		)
+		returning ` + jobColumns
+
+	return scanJob(tx.QueryRow(query, workerId, now))
+}
+
+func (r *jobRepository) Complete(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec("update jobs set status = 'done', locked_at = null, finished_at = now() where id = $1", id)
+	return err
+}
+
+// Fail record the error of the running job, the job is pending again at retryAt until it reach max attempts, then it
+// is failed. The new status is returned
+func (r *jobRepository) Fail(tx *sql.Tx, id int64, message string, retryAt time.Time) (string, error) {
+	var status string
+
+	query := `
+		update jobs
+		set status = case when attempts >= max_attempts then 'failed' else 'pending' end,
+			run_at = case when attempts >= max_attempts then run_at else $3 end,
+			finished_at = case when attempts >= max_attempts then now() else null end,
+			last_error = $2, locked_at = null
+		where id = $1
+		returning status
+	`
+
+	err := tx.QueryRow(query, id, message, retryAt).Scan(&status)
+	return status, err
+}
+
+// ReleaseStale fail the running jobs locked longer than timeout, usually because the app stopped while running it, so
+// they are retried or failed the same as a returned error
+func (r *jobRepository) ReleaseStale(tx *sql.Tx, timeout time.Duration) (int64, error) {
+	query := `
+		update jobs
+		set status = case when attempts >= max_attempts then 'failed' else 'pending' end,
+			finished_at = case when attempts >= max_attempts then now() else null end,
+			last_error = 'job was not finished by ' || locked_by || ' within the lock timeout', locked_at = null
+		where status = 'running' and locked_at < now() - make_interval(secs => $1::float8)
+	`
+
+	result, err := tx.Exec(query, timeout.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Retry run the failed job again at runAt with all its attempts, false is returned when the job is not failed
+func (r *jobRepository) Retry(tx *sql.Tx, id int64, runAt time.Time) (bool, error) {
+	query := `
+		update jobs set status = 'pending', attempts = 0, run_at = $2, finished_at = null
+		where id = $1 and status = 'failed'
+	`
+
+	result, err := tx.Exec(query, id, runAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteFinished remove done jobs finished more than maxAge ago, failed jobs are kept until they are retried
+func (r *jobRepository) DeleteFinished(tx *sql.Tx, maxAge time.Duration) (int64, error) {
+	result, err := tx.Exec("delete from jobs where status = 'done' and finished_at < now() - make_interval(secs => $1::float8)", maxAge.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// HasUnfinished check if the schedule has a pending or running job
+func (r *jobRepository) HasUnfinished(tx *sql.Tx, scheduleName string) (bool, error) {
+	var exists bool
+
+	query := "select exists (select 1 from jobs where schedule_name = $1 and status in ('pending', 'running'))"
+	err := tx.QueryRow(query, scheduleName).Scan(&exists)
+	return exists, err
+}
+
+func (r *jobRepository) FindByID(tx *sql.Tx, id int64) (models.Job, error) {
+	return scanJob(tx.QueryRow("select "+jobColumns+" from jobs where id = $1", id))
+}
+
+func (r *jobRepository) FindWithPagination(tx *sql.Tx, size int, page int, filter models.JobFilter) ([]models.Job, int, error) {
+	var total int
+	jobs := []models.Job{}
+
+	filterQuery := ""
+	paramData := []interface{}{}
+
+	if filter.Status != "" {
+		paramData = append(paramData, filter.Status)
+		filterQuery += " and status = $" + strconv.Itoa(len(paramData))
+	}
+
+	if filter.Type != "" {
+		paramData = append(paramData, filter.Type)
+		filterQuery += " and type = $" + strconv.Itoa(len(paramData))
+	}
+
+	// count total row before pagination
+	if err := tx.QueryRow("select count(id) from jobs where 1=1"+filterQuery, paramData...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	paramData = append(paramData, size, (page-1)*size)
+	query := "select " + jobColumns + " from jobs where 1=1" + filterQuery +
+		" order by id desc limit $" + strconv.Itoa(len(paramData)-1) + " offset $" + strconv.Itoa(len(paramData))
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, total, nil
+}
+
+func (r *jobRepository) CountByStatus(tx *sql.Tx) ([]models.JobStatusCount, error) {
+	counts := []models.JobStatusCount{}
+
+	rows, err := tx.Query("select status, count(id) from jobs group by status order by status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.JobStatusCount
+
+		if err := rows.Scan(&count.Status, &count.Total); err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// SaveSchedule add or update the schedule defined in the code, next_run_at is only replaced when the cron is changed
+func (r *jobRepository) SaveSchedule(tx *sql.Tx, name string, jobType string, cron string, nextRunAt time.Time) error {
+	query := `
+		insert into job_schedules (name, job_type, cron, next_run_at) values ($1, $2, $3, $4)
+		on conflict (name) do update set
+			job_type = excluded.job_type,
+			next_run_at = case when job_schedules.cron = excluded.cron then job_schedules.next_run_at else excluded.next_run_at end,
+			cron = excluded.cron,
+			updated_at = now()
+	`
+
+	_, err := tx.Exec(query, name, jobType, cron, nextRunAt)
+	return err
+}
+
+// ClaimDueSchedules lock the schedules due at now, a schedule locked by another instance is skipped
+func (r *jobRepository) ClaimDueSchedules(tx *sql.Tx, now time.Time) ([]models.JobSchedule, error) {
+	rows, err := tx.Query("select name, job_type, cron, next_run_at, last_run_at from job_schedules where next_run_at <= $1 order by next_run_at for update skip locked", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanJobSchedules(rows)
+}
+
+func (r *jobRepository) UpdateScheduleRun(tx *sql.Tx, name string, nextRunAt time.Time) error {
+	_, err := tx.Exec("update job_schedules set next_run_at = $2, last_run_at = now() where name = $1", name, nextRunAt)
+	return err
+}
+
+func (r *jobRepository) FindSchedules(tx *sql.Tx) ([]models.JobSchedule, error) {
+	rows, err := tx.Query("select name, job_type, cron, next_run_at, last_run_at from job_schedules order by name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanJobSchedules(rows)
+}
+
+func (r *jobRepository) FindSchedule(tx *sql.Tx, name string) (models.JobSchedule, error) {
+	var schedule models.JobSchedule
+
+	query := "select name, job_type, cron, next_run_at, last_run_at from job_schedules where name = $1"
+	err := tx.QueryRow(query, name).Scan(&schedule.Name, &schedule.JobType, &schedule.Cron, &schedule.NextRunAt, &schedule.LastRunAt)
+	return schedule, err
+}
+
+func scanJobSchedules(rows *sql.Rows) ([]models.JobSchedule, error) {
+	schedules := []models.JobSchedule{}
+
+	for rows.Next() {
+		var schedule models.JobSchedule
+
+		if err := rows.Scan(&schedule.Name, &schedule.JobType, &schedule.Cron, &schedule.NextRunAt, &schedule.LastRunAt); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// DeleteSchedulesExcept remove the saved schedules that are no longer defined in the code
+func (r *jobRepository) DeleteSchedulesExcept(tx *sql.Tx, names []string) error {
+	_, err := tx.Exec("delete from job_schedules where name <> all($1)", pq.Array(names))
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+)
+
+type LineItemRepository interface {
+	FindByLog(tx *sql.Tx, logId int) ([]models.LineItem, error)
+	FindByID(tx *sql.Tx, id int) (models.LineItem, error)
+	CountByLog(tx *sql.Tx, logId int) (int, error)
+	Create(tx *sql.Tx, item *models.LineItemInput) error
+	Update(tx *sql.Tx, item *models.LineItemInput, id int) error
+	Delete(tx *sql.Tx, id int) error
+}
+
+type lineItemRepository struct {
+	db *sql.DB
+}
+
+func NewLineItemRepository(db *sql.DB) LineItemRepository {
+	return &lineItemRepository{db}
+}
+
+func (r *lineItemRepository) FindByLog(tx *sql.Tx, logId int) ([]models.LineItem, error) {
+	items := []models.LineItem{}
+
+	query := `
+		select 
+			li.id, li.daily_log_id, li.type, li.category_id, ec.name, li.description, li.quantity, li.unit_price, li.amount, li.created_at, li.updated_at
+		from 
+			log_line_items li left join expense_categories ec on li.category_id = ec.id
+		where li.daily_log_id = $1
+		order by li.type, li.id
+	`
+
+	rows, err := tx.Query(query, logId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.LineItem
+
+		if err := rows.Scan(&item.Id, &item.DailyLogId, &item.Type, &item.CategoryId, &item.CategoryName, &item.Description, &item.Quantity, &item.UnitPrice, &item.Amount, &item.CreatedAt, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func (r *lineItemRepository) FindByID(tx *sql.Tx, id int) (models.LineItem, error) {
+	var item models.LineItem
+
+	query := `
+		select 
+			li.id, li.daily_log_id, li.type, li.category_id, ec.name, li.description, li.quantity, li.unit_price, li.amount, li.created_at, li.updated_at
+		from 
+			log_line_items li left join expense_categories ec on li.category_id = ec.id
+		where li.id = $1
+	`
+
+	if err := tx.QueryRow(query, id).Scan(&item.Id, &item.DailyLogId, &item.Type, &item.CategoryId, &item.CategoryName, &item.Description, &item.Quantity, &item.UnitPrice, &item.Amount, &item.CreatedAt, &item.UpdatedAt); err != nil {
+		return item, err
+	}
+
+	return item, nil
+}
+
+func (r *lineItemRepository) CountByLog(tx *sql.Tx, logId int) (int, error) {
+	var total int
+
+	if err := tx.QueryRow("select count(id) from log_line_items where daily_log_id=$1", logId).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r *lineItemRepository) Create(tx *sql.Tx, item *models.LineItemInput) error {
+	query := `
+		insert into log_line_items (daily_log_id, type, category_id, description, quantity, unit_price, amount) 
+		values ($1, $2, nullif($3, 0), $4, $5, $6, $7)
+		returning id
+	`
+
+	if err := tx.QueryRow(query, item.DailyLogId, item.Type, item.CategoryId, item.Description, item.Quantity, item.UnitPrice, item.Amount).Scan(&item.Id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *lineItemRepository) Update(tx *sql.Tx, item *models.LineItemInput, id int) error {
+	query := `
+		update log_line_items 
+		set type=$1, category_id=nullif($2, 0), description=$3, quantity=$4, unit_price=$5, amount=$6, updated_at=now() 
+		where id=$7
+	`
+
+	if _, err := tx.Exec(query, item.Type, item.CategoryId, item.Description, item.Quantity, item.UnitPrice, item.Amount, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *lineItemRepository) Delete(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("delete from log_line_items where id=$1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
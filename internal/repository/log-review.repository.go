@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"strconv"
+	"time"
+)
+
+type LogReviewRepository interface {
+	Create(tx *sql.Tx, dailyLogId int, userId int, action string, comment string) error
+	FindByLog(tx *sql.Tx, dailyLogId int) ([]models.LogReview, error)
+	FindPending(tx *sql.Tx, reviewerId int) ([]models.PendingLogReview, error)
+	FindReviewers(tx *sql.Tx, excludeUserId int) ([]models.LogReviewer, error)
+	FindUnnotified(tx *sql.Tx, maxAge time.Duration) ([]models.LogReview, error)
+	MarkNotified(tx *sql.Tx, id int) error
+}
+
+type logReviewRepository struct {
+	db *sql.DB
+}
+
+func NewLogReviewRepository(db *sql.DB) LogReviewRepository {
+	return &logReviewRepository{db}
+}
+
+func (r *logReviewRepository) Create(tx *sql.Tx, dailyLogId int, userId int, action string, comment string) error {
+	query := "insert into daily_log_reviews (daily_log_id, user_id, action, comment) values ($1, $2, $3, nullif($4, ''))"
+	if _, err := tx.Exec(query, dailyLogId, userId, action, comment); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindByLog get review history of the log, oldest first
+func (r *logReviewRepository) FindByLog(tx *sql.Tx, dailyLogId int) ([]models.LogReview, error) {
+	reviews := []models.LogReview{}
+
+	query := `
+		select r.id, r.daily_log_id, r.user_id, coalesce(u.username, ''), r.action, r.comment, r.created_at
+		from daily_log_reviews r
+		left join users u on u.id = r.user_id
+		where r.daily_log_id = $1
+		order by r.created_at, r.id
+	`
+
+	rows, err := tx.Query(query, dailyLogId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review models.LogReview
+
+		if err := rows.Scan(&review.Id, &review.DailyLogId, &review.UserId, &review.Username, &review.Action, &review.Comment, &review.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+// FindPending get submitted logs assigned to the reviewer, reviewerId 0 is all submitted logs
+func (r *logReviewRepository) FindPending(tx *sql.Tx, reviewerId int) ([]models.PendingLogReview, error) {
+	pending := []models.PendingLogReview{}
+
+	query := `
+		select dl.id, dl.project_id, p.name, dl.log_date, dl.income, dl.expense, dl.currency, dl.reviewer_id, dl.submitted_at
+		from daily_logs dl
+		join projects p on p.id = dl.project_id
+		where dl.status = 'submitted'`
+	paramData := []interface{}{}
+
+	if reviewerId != 0 {
+		paramData = append(paramData, reviewerId)
+		query += " and dl.reviewer_id = $" + strconv.Itoa(len(paramData))
+	}
+
+	query += " order by dl.submitted_at, dl.id limit 100"
+
+	rows, err := tx.Query(query, paramData...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log models.PendingLogReview
+
+		if err := rows.Scan(&log.DailyLogId, &log.ProjectId, &log.ProjectName, &log.LogDate, &log.Income, &log.Expense, &log.Currency, &log.ReviewerId, &log.SubmittedAt); err != nil {
+			return nil, err
+		}
+
+		pending = append(pending, log)
+	}
+
+	return pending, nil
+}
+
+// FindReviewers get admins and super admins that can review logs of the excluded user
+func (r *logReviewRepository) FindReviewers(tx *sql.Tx, excludeUserId int) ([]models.LogReviewer, error) {
+	reviewers := []models.LogReviewer{}
+
+	rows, err := tx.Query("select id, username, role from users where role in (1, 3) and is_deleted = false and id <> $1 order by role desc, username", excludeUserId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reviewer models.LogReviewer
+
+		if err := rows.Scan(&reviewer.Id, &reviewer.Username, &reviewer.Role); err != nil {
+			return nil, err
+		}
+
+		reviewers = append(reviewers, reviewer)
+	}
+
+	return reviewers, nil
+}
+
+// FindUnnotified get approve and reject reviews that not emailed yet and not older than maxAge with the project owner
+// email, locked so the author is only notified once
+func (r *logReviewRepository) FindUnnotified(tx *sql.Tx, maxAge time.Duration) ([]models.LogReview, error) {
+	reviews := []models.LogReview{}
+
+	query := `
+		select r.id, r.daily_log_id, r.user_id, coalesce(ru.username, ''), r.action, r.comment, r.created_at,
+			p.id, p.name, dl.log_date, coalesce(u.email, '')
+		from daily_log_reviews r
+		join daily_logs dl on dl.id = r.daily_log_id
+		join projects p on p.id = dl.project_id
+		join users u on u.id = p.created_by
+		left join users ru on ru.id = r.user_id
+		where r.action in ('approved', 'rejected') and r.notified_at is null and r.created_at > now() - make_interval(secs => $1::float8)
+		order by r.id
+		for update of r skip locked
+	`
+
+	rows, err := tx.Query(query, maxAge.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review models.LogReview
+
+		if err := rows.Scan(&review.Id, &review.DailyLogId, &review.UserId, &review.Username, &review.Action, &review.Comment, &review.CreatedAt,
+			&review.ProjectId, &review.ProjectName, &review.LogDate, &review.OwnerEmail); err != nil {
+			return nil, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (r *logReviewRepository) MarkNotified(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("update daily_log_reviews set notified_at = now() where id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fiber-prjct-management-web/internal/models"
+)
+
+type LogRevisionRepository interface {
+	Create(tx *sql.Tx, revision *models.DailyLogRevision) error
+	FindLatest(tx *sql.Tx, dailyLogId int) (models.DailyLogRevision, error)
+	FindByVersion(tx *sql.Tx, dailyLogId int, version int) (models.DailyLogRevision, error)
+	FindByLog(tx *sql.Tx, dailyLogId int) ([]models.DailyLogRevision, error)
+}
+
+type logRevisionRepository struct {
+	db *sql.DB
+}
+
+func NewLogRevisionRepository(db *sql.DB) LogRevisionRepository {
+	return &logRevisionRepository{db}
+}
+
+const logRevisionColumns = "r.id, r.daily_log_id, r.version, r.action, r.snapshot, r.changes, r.user_id, coalesce(u.username, ''), r.created_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLogRevision(row rowScanner) (models.DailyLogRevision, error) {
+	var (
+		revision models.DailyLogRevision
+		snapshot []byte
+		changes  []byte
+	)
+
+	if err := row.Scan(&revision.Id, &revision.DailyLogId, &revision.Version, &revision.Action, &snapshot, &changes, &revision.UserId, &revision.Username, &revision.CreatedAt); err != nil {
+		return revision, err
+	}
+
+	if err := json.Unmarshal(snapshot, &revision.Snapshot); err != nil {
+		return revision, err
+	}
+
+	if err := json.Unmarshal(changes, &revision.Changes); err != nil {
+		return revision, err
+	}
+
+	return revision, nil
+}
+
+// Create insert the revision as the next version of the log
+func (r *logRevisionRepository) Create(tx *sql.Tx, revision *models.DailyLogRevision) error {
+	snapshot, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	if revision.Changes == nil {
+		revision.Changes = map[string]models.FieldChange{}
+	}
+
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		insert into daily_log_revisions (daily_log_id, version, action, snapshot, changes, user_id)
+		values ($1, (select coalesce(max(version), 0) + 1 from daily_log_revisions where daily_log_id = $1), $2, $3, $4, $5)
+		returning id, version, created_at
+	`
+
+	return tx.QueryRow(query, revision.DailyLogId, revision.Action, snapshot, changes, revision.UserId).Scan(&revision.Id, &revision.Version, &revision.CreatedAt)
+}
+
+func (r *logRevisionRepository) FindLatest(tx *sql.Tx, dailyLogId int) (models.DailyLogRevision, error) {
+	query := "select " + logRevisionColumns + " from daily_log_revisions r left join users u on u.id = r.user_id where r.daily_log_id = $1 order by r.version desc limit 1"
+	return scanLogRevision(tx.QueryRow(query, dailyLogId))
+}
+
+func (r *logRevisionRepository) FindByVersion(tx *sql.Tx, dailyLogId int, version int) (models.DailyLogRevision, error) {
+	query := "select " + logRevisionColumns + " from daily_log_revisions r left join users u on u.id = r.user_id where r.daily_log_id = $1 and r.version = $2"
+	return scanLogRevision(tx.QueryRow(query, dailyLogId, version))
+}
+
+// FindByLog get all versions of the log, newest first
+func (r *logRevisionRepository) FindByLog(tx *sql.Tx, dailyLogId int) ([]models.DailyLogRevision, error) {
+	revisions := []models.DailyLogRevision{}
+
+	query := "select " + logRevisionColumns + " from daily_log_revisions r left join users u on u.id = r.user_id where r.daily_log_id = $1 order by r.version desc"
+	rows, err := tx.Query(query, dailyLogId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		revision, err := scanLogRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"time"
+)
+
+type NotificationRepository interface {
+	Create(tx *sql.Tx, userId int, notification models.Notification) error
+	CreateForProject(tx *sql.Tx, projectId int, excludeUserId int, notification models.Notification) error
+	FindWithPagination(tx *sql.Tx, userId int, size int, page int, unreadOnly bool) ([]models.Notification, int, error)
+	CountUnread(tx *sql.Tx, userId int) (int, error)
+	MarkRead(tx *sql.Tx, id int64, userId int) (bool, error)
+	MarkAllRead(tx *sql.Tx, userId int) (int64, error)
+	DeleteReadOlderThan(tx *sql.Tx, maxAge time.Duration) (int64, error)
+}
+
+type notificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db}
+}
+
+// Create add the notification for the user, deleted user is skipped
+func (r *notificationRepository) Create(tx *sql.Tx, userId int, notification models.Notification) error {
+	query := `
+		insert into notifications (user_id, type, title, message, link, project_id)
+		select id, $2, $3, $4, $5, $6::int from users where id = $1 and is_deleted = false
+	`
+
+	_, err := tx.Exec(query, userId, notification.Type, notification.Title, notification.Message, notification.Link, notification.ProjectId)
+	return err
+}
+
+// CreateForProject add the notification for the members of the project, the project owner and every super admin,
+// except excludeUserId that is usually the user who made the change
+func (r *notificationRepository) CreateForProject(tx *sql.Tx, projectId int, excludeUserId int, notification models.Notification) error {
+	query := `
+		insert into notifications (user_id, type, title, message, link, project_id)
+		select u.id, $3, $4, $5, $6, $1 from users u
+		where u.is_deleted = false and u.id <> $2
+			and (u.role = 3 or u.id = (select created_by from projects where id = $1))
+	`
+
+	_, err := tx.Exec(query, projectId, excludeUserId, notification.Type, notification.Title, notification.Message, notification.Link)
+	return err
+}
+
+func (r *notificationRepository) FindWithPagination(tx *sql.Tx, userId int, size int, page int, unreadOnly bool) ([]models.Notification, int, error) {
+	var total int
+	notifications := []models.Notification{}
+
+	filterQuery := " where user_id = $1"
+	if unreadOnly {
+		filterQuery += " and is_read = false"
+	}
+
+	// count total row before pagination
+	if err := tx.QueryRow("select count(id) from notifications"+filterQuery, userId).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "select id, user_id, type, title, message, link, project_id, is_read, created_at from notifications" + filterQuery +
+		" order by id desc limit $2 offset $3"
+
+	rows, err := tx.Query(query, userId, size, (page-1)*size)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var notification models.Notification
+
+		if err := rows.Scan(&notification.Id, &notification.UserId, &notification.Type, &notification.Title, &notification.Message, &notification.Link, &notification.ProjectId, &notification.IsRead, &notification.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, total, nil
+}
+
+func (r *notificationRepository) CountUnread(tx *sql.Tx, userId int) (int, error) {
+	var total int
+
+	err := tx.QueryRow("select count(id) from notifications where user_id = $1 and is_read = false", userId).Scan(&total)
+	return total, err
+}
+
+// MarkRead mark the notification of the user as read, false is returned when the user has no such notification
+func (r *notificationRepository) MarkRead(tx *sql.Tx, id int64, userId int) (bool, error) {
+	result, err := tx.Exec("update notifications set is_read = true where id = $1 and user_id = $2", id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *notificationRepository) MarkAllRead(tx *sql.Tx, userId int) (int64, error) {
+	result, err := tx.Exec("update notifications set is_read = true where user_id = $1 and is_read = false", userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteReadOlderThan remove read notifications older than maxAge, unread notifications are kept
+func (r *notificationRepository) DeleteReadOlderThan(tx *sql.Tx, maxAge time.Duration) (int64, error) {
+	result, err := tx.Exec("delete from notifications where is_read = true and created_at < now() - make_interval(secs => $1::float8)", maxAge.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
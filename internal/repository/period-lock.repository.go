@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+)
+
+// PeriodLockRepository projectId 0 is the global lock of every project
+type PeriodLockRepository interface {
+	FindLocks(tx *sql.Tx, projectId int) ([]models.PeriodLock, error)
+	FindLockByID(tx *sql.Tx, id int) (models.PeriodLock, error)
+	IsLocked(tx *sql.Tx, projectId int, date string) (bool, error)
+	Lock(tx *sql.Tx, projectId int, period string, userId int) (bool, error)
+	Delete(tx *sql.Tx, id int) error
+	CreateEvent(tx *sql.Tx, projectId int, period string, action string, reason string, userId int) error
+	FindEvents(tx *sql.Tx, projectId int) ([]models.PeriodLockEvent, error)
+}
+
+type periodLockRepository struct {
+	db *sql.DB
+}
+
+func NewPeriodLockRepository(db *sql.DB) PeriodLockRepository {
+	return &periodLockRepository{db}
+}
+
+func nullProjectId(projectId int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(projectId), Valid: projectId != 0}
+}
+
+// FindLocks get locks of the project and the global locks, only global locks when projectId is 0
+func (r *periodLockRepository) FindLocks(tx *sql.Tx, projectId int) ([]models.PeriodLock, error) {
+	locks := []models.PeriodLock{}
+
+	query := `
+		select l.id, l.project_id, to_char(l.period, 'YYYY-MM'), l.locked_by, coalesce(u.username, ''), l.created_at
+		from period_locks l
+		left join users u on u.id = l.locked_by
+		where l.project_id is null or l.project_id = $1
+		order by l.period desc, l.project_id nulls first
+	`
+
+	rows, err := tx.Query(query, nullProjectId(projectId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lock models.PeriodLock
+
+		if err := rows.Scan(&lock.Id, &lock.ProjectId, &lock.Period, &lock.LockedBy, &lock.LockedByName, &lock.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		locks = append(locks, lock)
+	}
+
+	return locks, nil
+}
+
+func (r *periodLockRepository) FindLockByID(tx *sql.Tx, id int) (models.PeriodLock, error) {
+	var lock models.PeriodLock
+
+	query := "select id, project_id, to_char(period, 'YYYY-MM'), locked_by, created_at from period_locks where id = $1"
+	if err := tx.QueryRow(query, id).Scan(&lock.Id, &lock.ProjectId, &lock.Period, &lock.LockedBy, &lock.CreatedAt); err != nil {
+		return lock, err
+	}
+
+	return lock, nil
+}
+
+// IsLocked check if the month of the date (YYYY-MM-DD) is locked for the project or globally
+func (r *periodLockRepository) IsLocked(tx *sql.Tx, projectId int, date string) (bool, error) {
+	var locked bool
+
+	query := `
+		select exists (
+			select 1 from period_locks
+			where (project_id is null or project_id = $1) and period = date_trunc('month', $2::date)::date
+		)
+	`
+	if err := tx.QueryRow(query, projectId, date).Scan(&locked); err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
+
+// Lock lock the month (YYYY-MM), false when the month is already locked
+func (r *periodLockRepository) Lock(tx *sql.Tx, projectId int, period string, userId int) (bool, error) {
+	query := `
+		insert into period_locks (project_id, period, locked_by) values ($1, ($2 || '-01')::date, $3)
+		on conflict ((coalesce(project_id, 0)), period) do nothing
+	`
+
+	result, err := tx.Exec(query, nullProjectId(projectId), period, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *periodLockRepository) Delete(tx *sql.Tx, id int) error {
+	if _, err := tx.Exec("delete from period_locks where id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *periodLockRepository) CreateEvent(tx *sql.Tx, projectId int, period string, action string, reason string, userId int) error {
+	query := "insert into period_lock_events (project_id, period, action, reason, user_id) values ($1, ($2 || '-01')::date, $3, nullif($4, ''), $5)"
+	if _, err := tx.Exec(query, nullProjectId(projectId), period, action, reason, userId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindEvents get lock and reopen history of the project and the global locks, only global when projectId is 0
+func (r *periodLockRepository) FindEvents(tx *sql.Tx, projectId int) ([]models.PeriodLockEvent, error) {
+	events := []models.PeriodLockEvent{}
+
+	query := `
+		select e.id, e.project_id, to_char(e.period, 'YYYY-MM'), e.action, e.reason, e.user_id, coalesce(u.username, ''), e.created_at
+		from period_lock_events e
+		left join users u on u.id = e.user_id
+		where e.project_id is null or e.project_id = $1
+		order by e.created_at desc, e.id desc
+		limit 100
+	`
+
+	rows, err := tx.Query(query, nullProjectId(projectId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.PeriodLockEvent
+
+		if err := rows.Scan(&event.Id, &event.ProjectId, &event.Period, &event.Action, &event.Reason, &event.UserId, &event.Username, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
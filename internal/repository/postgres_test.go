@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// openTestDB open the postgres of TEST_DATABASE_URL with the app tables created on a new schema, the schema is
+// dropped after the test. The test is skipped when TEST_DATABASE_URL is not set
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// search_path is per connection, one connection keep every query on the test schema
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(fmt.Sprintf("create schema %s; set search_path to %s", schema, schema)); err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(fmt.Sprintf("drop schema %s cascade", schema))
+		db.Close()
+	})
+
+	migration, err := os.ReadFile("../../pkg/database/migrations/table.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec(string(migration)); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
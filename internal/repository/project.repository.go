@@ -3,19 +3,26 @@ package repository
 import (
 	"database/sql"
 	"fiber-prjct-management-web/internal/models"
+	"fiber-prjct-management-web/pkg/utils"
 	"strconv"
 )
 
 type ProjectRepository interface {
 	Create(tx *sql.Tx, project *models.ProjectInput) error
 	Update(tx *sql.Tx, project *models.ProjectInput, id int) error
+	UpdateBudget(tx *sql.Tx, id int, budget int) error
 	UpdateStatus(tx *sql.Tx, id int, status int) error
 	Delete(tx *sql.Tx, id int) error
 	FindWithPagination(tx *sql.Tx, size int, page int, search string, status string, toDate string, fromDate string, userId int) ([]models.Project, int, error)
+	Each(tx *sql.Tx, search string, status string, toDate string, fromDate string, userId int, fn func(project models.Project) error) error
 	FindByID(tx *sql.Tx, id int) (models.Project, error)
 	FindIfProjectOwner(tx *sql.Tx, id int, userId int) (models.Project, error)
-	FindProjectsStats(tx *sql.Tx, userId int) (models.ProjectStats, error)
+	FindProjectsStats(tx *sql.Tx, userId int, opts models.StatsOptions) (models.ProjectStats, error)
 	FindProjectStatusStats(tx *sql.Tx, userId int) ([]models.ProjectStatusStats, error)
+	FindStorageUsage(tx *sql.Tx, id int, forUpdate bool) (models.ProjectStorageUsage, error)
+	FindStorageUsageStats(tx *sql.Tx, limit int) (models.StorageUsageStats, error)
+	AddStorageUsage(tx *sql.Tx, id int, bytes int64, files int) error
+	UpdateStorageQuota(tx *sql.Tx, id int, quota sql.NullInt64) error
 }
 
 type projectRepository struct {
@@ -64,34 +71,35 @@ func (r *projectRepository) FindProjectStatusStats(tx *sql.Tx, userId int) ([]mo
 	return projectStatusStats, nil
 }
 
-func (r *projectRepository) FindProjectsStats(tx *sql.Tx, userId int) (models.ProjectStats, error) {
+// FindProjectsStats get stats of user projects (all projects when userId is 0), budgets are converted with the current rate
+func (r *projectRepository) FindProjectsStats(tx *sql.Tx, userId int, opts models.StatsOptions) (models.ProjectStats, error) {
 
-	var projectStats models.ProjectStats
+	projectStats := models.ProjectStats{Currency: opts.Currency}
 
-	paramData := []interface{}{}
+	paramData := []interface{}{opts.Currency}
 	query := `
-		SELECT
-			COUNT(id) as total_projects,
-			COALESCE(SUM(CASE WHEN status = 3 THEN 1 ELSE 0 END), 0) as total_projects_done,
-			COALESCE(SUM(CASE WHEN status = 2 THEN 1 ELSE 0 END), 0) as total_projects_on_going,
-			COALESCE(SUM(budget), 0) as total_budget_all_projects,
-			CASE WHEN COUNT(id) = 0 THEN 0 ELSE ROUND(COALESCE(SUM(budget), 0) / COUNT(id), 2) END as avg_budget_per_project,
-			MAX(CASE WHEN budget = (SELECT MAX(budget) FROM projects
+		WITH scoped_projects AS (
+			SELECT id, name, status, ROUND(fx_convert(budget, currency, $1, CURRENT_DATE))::bigint as budget
+			FROM projects
 	`
 
 	if userId != 0 {
-		query += " WHERE created_by = $1"
+		query += " WHERE created_by = $2"
 		paramData = append(paramData, userId)
 	}
 
-	query += `) THEN name ELSE NULL END) as project_max_budget
-		FROM projects
+	query += `
+		)
+		SELECT
+			COUNT(id) as total_projects,
+			COALESCE(SUM(CASE WHEN status = 3 THEN 1 ELSE 0 END), 0) as total_projects_done,
+			COALESCE(SUM(CASE WHEN status = 2 THEN 1 ELSE 0 END), 0) as total_projects_on_going,
+			COALESCE(SUM(budget), 0) as total_budget_all_projects,
+			CASE WHEN COUNT(id) = 0 THEN 0 ELSE ROUND(COALESCE(SUM(budget), 0) / COUNT(id), 2) END as avg_budget_per_project,
+			MAX(CASE WHEN budget = (SELECT MAX(budget) FROM scoped_projects) THEN name ELSE NULL END) as project_max_budget
+		FROM scoped_projects
 	`
 
-	if userId != 0 {
-		query += " WHERE created_by = $1"
-	}
-
 	if err := tx.QueryRow(query, paramData...).Scan(
 		&projectStats.TotalProjects,
 		&projectStats.TotalProjectsDone,
@@ -109,7 +117,7 @@ func (r *projectRepository) FindProjectsStats(tx *sql.Tx, userId int) (models.Pr
 func (r *projectRepository) FindIfProjectOwner(tx *sql.Tx, id int, userId int) (models.Project, error) {
 	var project models.Project
 
-	if err := tx.QueryRow("select id, name, description, status, start_date, end_date, budget, created_by, created_at, updated_at from projects where id=$1 and created_by=$2", id, userId).Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.CreatedBy, &project.CreatedAt, &project.UpdatedAt); err != nil {
+	if err := tx.QueryRow("select id, name, description, status, start_date, end_date, budget, currency, created_by, created_at, updated_at from projects where id=$1 and created_by=$2", id, userId).Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.Currency, &project.CreatedBy, &project.CreatedAt, &project.UpdatedAt); err != nil {
 		return project, err
 	}
 
@@ -124,7 +132,73 @@ func (r *projectRepository) FindWithPagination(tx *sql.Tx, size int, page int, s
 	offset := (page - 1) * size
 
 	baseQueryCnt := "select count(id) from projects where 1=1"
-	baseQuery := "select id, name, description, status, start_date, end_date, budget, created_by, created_at, updated_at from projects where 1=1"
+	baseQuery := projectListQuery
+	paramQuery, dataQuery, orderQuery := projectFilterQuery(search, status, toDate, fromDate, userId)
+	index := len(dataQuery) + 1
+
+	// count total pagiantion
+	baseQueryCnt += paramQuery
+	if err := tx.QueryRow(baseQueryCnt, dataQuery...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// base query with limit
+	baseQuery += paramQuery + orderQuery + " limit $" + strconv.Itoa(index) + " offset $" + strconv.Itoa(index+1)
+	dataQuery = append(dataQuery, size, offset)
+
+	rows, err := tx.Query(baseQuery, dataQuery...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		project, err := scanProjectListRow(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, total, nil
+}
+
+// Each call fn for every filtered project in the list order, without loading all of them into memory
+func (r *projectRepository) Each(tx *sql.Tx, search string, status string, toDate string, fromDate string, userId int, fn func(project models.Project) error) error {
+	paramQuery, dataQuery, orderQuery := projectFilterQuery(search, status, toDate, fromDate, userId)
+
+	rows, err := tx.Query(projectListQuery+paramQuery+orderQuery, dataQuery...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		project, err := scanProjectListRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(project); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+const projectListQuery = "select id, name, description, status, start_date, end_date, budget, currency, created_by, created_at, updated_at from projects where 1=1"
+
+func scanProjectListRow(row rowScanner) (models.Project, error) {
+	var project models.Project
+
+	err := row.Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.Currency, &project.CreatedBy, &project.CreatedAt, &project.UpdatedAt)
+	return project, err
+}
+
+// projectFilterQuery build the where conditions of the project list starting with " and", and the list order
+func projectFilterQuery(search string, status string, toDate string, fromDate string, userId int) (string, []interface{}, string) {
 	paramQuery := ""
 	dataQuery := []interface{}{}
 	index := 1
@@ -157,42 +231,14 @@ func (r *projectRepository) FindWithPagination(tx *sql.Tx, size int, page int, s
 	if userId != 0 {
 		paramQuery += " and created_by = $" + strconv.Itoa(index)
 		dataQuery = append(dataQuery, userId)
-		index++
 	}
 
-	// count total pagiantion
-	baseQueryCnt += paramQuery
-	if err := tx.QueryRow(baseQueryCnt, dataQuery...).Scan(&total); err != nil {
-		return nil, 0, err
-	}
-
-	// base query with limit
 	orderQuery := " order by id desc"
 	if (fromDate != "") || (toDate != "") {
 		orderQuery = " order by start_date desc"
 	}
 
-	baseQuery += paramQuery + orderQuery + " limit $" + strconv.Itoa(index) + " offset $" + strconv.Itoa(index+1)
-	dataQuery = append(dataQuery, size, offset)
-	index += 2
-
-	rows, err := tx.Query(baseQuery, dataQuery...)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var project models.Project
-		err := rows.Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.CreatedBy, &project.CreatedAt, &project.UpdatedAt)
-		if err != nil {
-			return nil, 0, err
-		}
-
-		projects = append(projects, project)
-	}
-
-	return projects, total, nil
+	return paramQuery, dataQuery, orderQuery
 }
 
 func (r *projectRepository) FindByID(tx *sql.Tx, id int) (models.Project, error) {
@@ -200,14 +246,14 @@ func (r *projectRepository) FindByID(tx *sql.Tx, id int) (models.Project, error)
 
 	query := `
 		SELECT 	
-			p.id, p.name, description, status, start_date, end_date, budget, created_by, u.username, p.created_at, p.updated_at
+			p.id, p.name, description, status, start_date, end_date, budget, currency, created_by, u.username, p.created_at, p.updated_at
 		FROM 
 			projects p LEFT JOIN users u ON p.created_by = u.id
 		WHERE
 			p.id = $1
 	`
 
-	if err := tx.QueryRow(query, id).Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.CreatedBy, &project.CreatedByName, &project.CreatedAt, &project.UpdatedAt); err != nil {
+	if err := tx.QueryRow(query, id).Scan(&project.Id, &project.Name, &project.Description, &project.Status, &project.StartDate, &project.EndDate, &project.Budget, &project.Currency, &project.CreatedBy, &project.CreatedByName, &project.CreatedAt, &project.UpdatedAt); err != nil {
 		return project, err
 	}
 
@@ -215,9 +261,9 @@ func (r *projectRepository) FindByID(tx *sql.Tx, id int) (models.Project, error)
 }
 
 func (r *projectRepository) Create(tx *sql.Tx, project *models.ProjectInput) error {
-	paramIndex := 5
-	paramValues := []interface{}{project.Name, project.Description, project.Status, project.Budget, project.CreatedBy}
-	baseQuery := "insert into projects (name, description, status, budget, created_by"
+	paramIndex := 6
+	paramValues := []interface{}{project.Name, project.Description, project.Status, project.Budget, project.CreatedBy, project.Currency}
+	baseQuery := "insert into projects (name, description, status, budget, created_by, currency"
 	if project.StartDate != "" {
 		baseQuery += ", start_date"
 		paramIndex++
@@ -229,9 +275,9 @@ func (r *projectRepository) Create(tx *sql.Tx, project *models.ProjectInput) err
 		baseQuery += "$" + strconv.Itoa(i+1) + ","
 	}
 	baseQuery = baseQuery[:len(baseQuery)-1]
-	baseQuery += " )"
+	baseQuery += " ) returning id"
 
-	if _, err := tx.Exec(baseQuery, paramValues...); err != nil {
+	if err := tx.QueryRow(baseQuery, paramValues...).Scan(&project.Id); err != nil {
 		return err
 	}
 
@@ -239,9 +285,9 @@ func (r *projectRepository) Create(tx *sql.Tx, project *models.ProjectInput) err
 }
 
 func (r *projectRepository) Update(tx *sql.Tx, project *models.ProjectInput, id int) error {
-	indexQuery := 4
-	queryData := []interface{}{project.Name, project.Description, project.Status, project.Budget}
-	query := "update projects set name=$1, description=$2, status=$3, budget=$4, updated_at=now()"
+	indexQuery := 5
+	queryData := []interface{}{project.Name, project.Description, project.Status, project.Budget, project.Currency}
+	query := "update projects set name=$1, description=$2, status=$3, budget=$4, currency=$5, updated_at=now()"
 
 	if project.StartDate != "" {
 		query += ", start_date=$" + strconv.Itoa(indexQuery+1)
@@ -269,6 +315,15 @@ func (r *projectRepository) Update(tx *sql.Tx, project *models.ProjectInput, id
 	return nil
 }
 
+// UpdateBudget set project budget, only called when a budget revision is approved
+func (r *projectRepository) UpdateBudget(tx *sql.Tx, id int, budget int) error {
+	if _, err := tx.Exec("update projects set budget=$1, updated_at=now() where id=$2", budget, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (r *projectRepository) UpdateStatus(tx *sql.Tx, id int, status int) error {
 	if _, err := tx.Exec("update projects set status=$1 where id=$2", status, id); err != nil {
 		return err
@@ -284,3 +339,87 @@ func (r *projectRepository) Delete(tx *sql.Tx, id int) error {
 
 	return nil
 }
+
+// FindStorageUsage get project storage usage, forUpdate lock the project row so concurrent uploads can not pass the quota together
+func (r *projectRepository) FindStorageUsage(tx *sql.Tx, id int, forUpdate bool) (models.ProjectStorageUsage, error) {
+	var usage models.ProjectStorageUsage
+
+	query := "select id, name, storage_used_bytes, storage_file_count, storage_quota_bytes from projects where id=$1"
+	if forUpdate {
+		query += " for update"
+	}
+
+	if err := tx.QueryRow(query, id).Scan(&usage.ProjectId, &usage.ProjectName, &usage.UsedBytes, &usage.FileCount, &usage.QuotaBytes); err != nil {
+		return usage, err
+	}
+
+	usage.EffectiveQuota = utils.EffectiveStorageQuota(usage.QuotaBytes)
+	usage.UsagePercentage = utils.StorageUsagePercentage(usage.UsedBytes, usage.EffectiveQuota)
+
+	return usage, nil
+}
+
+func (r *projectRepository) FindStorageUsageStats(tx *sql.Tx, limit int) (models.StorageUsageStats, error) {
+	stats := models.StorageUsageStats{
+		DefaultQuota: utils.DefaultStorageQuota(),
+		TopProjects:  []models.ProjectStorageUsage{},
+	}
+
+	if err := tx.QueryRow("select coalesce(sum(storage_used_bytes), 0), coalesce(sum(storage_file_count), 0) from projects").Scan(&stats.TotalUsedBytes, &stats.TotalFiles); err != nil {
+		return stats, err
+	}
+
+	query := `
+		select id, name, storage_used_bytes, storage_file_count, storage_quota_bytes 
+		from projects 
+		where storage_used_bytes > 0
+		order by storage_used_bytes desc 
+		limit $1
+	`
+
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var usage models.ProjectStorageUsage
+
+		if err := rows.Scan(&usage.ProjectId, &usage.ProjectName, &usage.UsedBytes, &usage.FileCount, &usage.QuotaBytes); err != nil {
+			return stats, err
+		}
+
+		usage.EffectiveQuota = utils.EffectiveStorageQuota(usage.QuotaBytes)
+		usage.UsagePercentage = utils.StorageUsagePercentage(usage.UsedBytes, usage.EffectiveQuota)
+
+		stats.TopProjects = append(stats.TopProjects, usage)
+	}
+
+	return stats, nil
+}
+
+// AddStorageUsage add or subtract (with negative value) project storage usage
+func (r *projectRepository) AddStorageUsage(tx *sql.Tx, id int, bytes int64, files int) error {
+	query := `
+		update projects 
+		set 
+			storage_used_bytes = greatest(storage_used_bytes + $1, 0),
+			storage_file_count = greatest(storage_file_count + $2, 0)
+		where id = $3
+	`
+
+	if _, err := tx.Exec(query, bytes, files, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *projectRepository) UpdateStorageQuota(tx *sql.Tx, id int, quota sql.NullInt64) error {
+	if _, err := tx.Exec("update projects set storage_quota_bytes=$1, updated_at=now() where id=$2", quota, id); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"time"
+)
+
+// ReportSubscriptionRepository projectId 0 is the subscription of all projects of the user
+type ReportSubscriptionRepository interface {
+	FindByUser(tx *sql.Tx, userId int) ([]models.ReportSubscription, error)
+	Create(tx *sql.Tx, userId int, projectId int, frequency string, nextSendAt time.Time) (int, bool, error)
+	Delete(tx *sql.Tx, id int, userId int) (bool, error)
+	FindDue(tx *sql.Tx, now time.Time) ([]models.ReportSubscription, error)
+	MarkSent(tx *sql.Tx, id int, sent bool, nextSendAt time.Time) error
+}
+
+type reportSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewReportSubscriptionRepository(db *sql.DB) ReportSubscriptionRepository {
+	return &reportSubscriptionRepository{db}
+}
+
+const reportSubscriptionQuery = `
+		select s.id, s.user_id, s.project_id, coalesce(p.name, ''), s.frequency, s.next_send_at, s.last_sent_at, s.created_at,
+			u.role, coalesce(u.email, '')
+		from report_subscriptions s
+		join users u on u.id = s.user_id
+		left join projects p on p.id = s.project_id`
+
+func scanReportSubscriptions(rows *sql.Rows) ([]models.ReportSubscription, error) {
+	subscriptions := []models.ReportSubscription{}
+
+	for rows.Next() {
+		var subscription models.ReportSubscription
+
+		if err := rows.Scan(&subscription.Id, &subscription.UserId, &subscription.ProjectId, &subscription.ProjectName, &subscription.Frequency, &subscription.NextSendAt, &subscription.LastSentAt, &subscription.CreatedAt, &subscription.UserRole, &subscription.UserEmail); err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+func (r *reportSubscriptionRepository) FindByUser(tx *sql.Tx, userId int) ([]models.ReportSubscription, error) {
+	rows, err := tx.Query(reportSubscriptionQuery+" where s.user_id = $1 order by s.project_id nulls first, s.frequency", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReportSubscriptions(rows)
+}
+
+// Create add the subscription, false is returned when the user already subscribe the project with the frequency
+func (r *reportSubscriptionRepository) Create(tx *sql.Tx, userId int, projectId int, frequency string, nextSendAt time.Time) (int, bool, error) {
+	var id int
+
+	query := `
+		insert into report_subscriptions (user_id, project_id, frequency, next_send_at) values ($1, $2, $3, $4)
+		on conflict (user_id, (coalesce(project_id, 0)), frequency) do nothing
+		returning id
+	`
+
+	err := tx.QueryRow(query, userId, nullProjectId(projectId), frequency, nextSendAt).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// Delete remove the subscription of the user, false is returned when the user has no subscription with the id
+func (r *reportSubscriptionRepository) Delete(tx *sql.Tx, id int, userId int) (bool, error) {
+	result, err := tx.Exec("delete from report_subscriptions where id = $1 and user_id = $2", id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// FindDue get subscriptions of active users that should be sent at now
+func (r *reportSubscriptionRepository) FindDue(tx *sql.Tx, now time.Time) ([]models.ReportSubscription, error) {
+	rows, err := tx.Query(reportSubscriptionQuery+" where s.next_send_at <= $1 and not coalesce(u.is_deleted, false) order by s.next_send_at", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReportSubscriptions(rows)
+}
+
+// MarkSent move the subscription to the next send time, last_sent_at is only updated when the digest was sent
+func (r *reportSubscriptionRepository) MarkSent(tx *sql.Tx, id int, sent bool, nextSendAt time.Time) error {
+	query := `
+		update report_subscriptions
+		set next_send_at = $2, last_sent_at = case when $3 then now() else last_sent_at end
+		where id = $1
+	`
+
+	_, err := tx.Exec(query, id, nextSendAt, sent)
+	return err
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"database/sql"
+	"fiber-prjct-management-web/internal/models"
+	"time"
+)
+
+type UploadRepository interface {
+	Create(tx *sql.Tx, upload *models.Upload, expiresIn time.Duration) error
+	FindByID(tx *sql.Tx, id string, forUpdate bool) (models.Upload, error)
+	UpdateOffset(tx *sql.Tx, id string, offset int64, status string) error
+	Delete(tx *sql.Tx, id string) error
+	FindExpired(tx *sql.Tx) ([]models.Upload, error)
+}
+
+type uploadRepository struct {
+	db *sql.DB
+}
+
+func NewUploadRepository(db *sql.DB) UploadRepository {
+	return &uploadRepository{db}
+}
+
+// Create insert new upload that expire after expiresIn when it is not attached to a log
+func (r *uploadRepository) Create(tx *sql.Tx, upload *models.Upload, expiresIn time.Duration) error {
+	query := `
+		insert into uploads (id, project_id, created_by, filename, content_type, size, upload_offset, checksum, status, expires_at) 
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, now() + $10 * interval '1 second')
+	`
+
+	if _, err := tx.Exec(query, upload.Id, upload.ProjectId, upload.CreatedBy, upload.Filename, upload.ContentType, upload.Size, upload.Offset, upload.Checksum, upload.Status, int64(expiresIn.Seconds())); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindByID get upload data, forUpdate lock the row so chunks of the same upload are written one by one
+func (r *uploadRepository) FindByID(tx *sql.Tx, id string, forUpdate bool) (models.Upload, error) {
+	var upload models.Upload
+
+	query := "select id, project_id, created_by, filename, content_type, size, upload_offset, checksum, status, expires_at, created_at, updated_at from uploads where id=$1"
+	if forUpdate {
+		query += " for update"
+	}
+
+	err := tx.QueryRow(query, id).Scan(&upload.Id, &upload.ProjectId, &upload.CreatedBy, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Offset, &upload.Checksum, &upload.Status, &upload.ExpiresAt, &upload.CreatedAt, &upload.UpdatedAt)
+	if err != nil {
+		return upload, err
+	}
+
+	return upload, nil
+}
+
+func (r *uploadRepository) UpdateOffset(tx *sql.Tx, id string, offset int64, status string) error {
+	if _, err := tx.Exec("update uploads set upload_offset=$1, status=$2, updated_at=now() where id=$3", offset, status, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *uploadRepository) Delete(tx *sql.Tx, id string) error {
+	if _, err := tx.Exec("delete from uploads where id=$1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *uploadRepository) FindExpired(tx *sql.Tx) ([]models.Upload, error) {
+	uploads := []models.Upload{}
+
+	rows, err := tx.Query("select id, project_id, created_by, filename, content_type, size, upload_offset, checksum, status, expires_at, created_at, updated_at from uploads where expires_at < now()")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var upload models.Upload
+
+		if err := rows.Scan(&upload.Id, &upload.ProjectId, &upload.CreatedBy, &upload.Filename, &upload.ContentType, &upload.Size, &upload.Offset, &upload.Checksum, &upload.Status, &upload.ExpiresAt, &upload.CreatedAt, &upload.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		uploads = append(uploads, upload)
+	}
+
+	return uploads, nil
+}
@@ -14,6 +14,7 @@ type UserRepository interface {
 	Delete(tx *sql.Tx, id int) error
 	SoftDelete(tx *sql.Tx, id int) error
 	FindWithPagination(tx *sql.Tx, size int, page int, search string, role string, toDate string, fromDate string) ([]models.User, int, error)
+	Each(tx *sql.Tx, search string, role string, toDate string, fromDate string, fn func(user models.User) error) error
 	FindByID(tx *sql.Tx, id int) (models.User, error)
 	FindByUsername(tx *sql.Tx, username string) (models.User, error)
 }
@@ -34,34 +35,9 @@ func (r *userRepository) FindWithPagination(tx *sql.Tx, size int, page int, sear
 	offset := (page - 1) * size
 
 	baseQueryCnt := "select count(id) from users where 1=1"
-	baseQuery := "select id, username, role, created_at, updated_at from users where 1=1 and is_deleted = FALSE"
-	paramQuery := ""
-	dataQuery := []interface{}{}
-	index := 1
-
-	if search != "" {
-		paramQuery += " and username like $" + strconv.Itoa(index)
-		dataQuery = append(dataQuery, "%"+search+"%")
-		index++
-	}
-
-	if role != "" {
-		paramQuery += " and role = $" + strconv.Itoa(index)
-		dataQuery = append(dataQuery, role)
-		index++
-	}
-
-	if fromDate != "" {
-		paramQuery += " and created_at >= $" + strconv.Itoa(index)
-		dataQuery = append(dataQuery, fromDate)
-		index++
-	}
-
-	if toDate != "" {
-		paramQuery += " and created_at <= $" + strconv.Itoa(index)
-		dataQuery = append(dataQuery, toDate)
-		index++
-	}
+	baseQuery := userListQuery
+	paramQuery, dataQuery := userFilterQuery(search, role, toDate, fromDate)
+	index := len(dataQuery) + 1
 
 	// count total row before pagination
 	baseQueryCnt += paramQuery
@@ -73,7 +49,6 @@ func (r *userRepository) FindWithPagination(tx *sql.Tx, size int, page int, sear
 	// modify baseQuery to include limit and offset
 	baseQuery += paramQuery + " order by id desc limit $" + strconv.Itoa(index) + " offset $" + strconv.Itoa(index+1)
 	dataQuery = append(dataQuery, size, offset)
-	index += 2
 
 	rows, err := tx.Query(baseQuery, dataQuery...)
 	if err != nil {
@@ -82,8 +57,7 @@ func (r *userRepository) FindWithPagination(tx *sql.Tx, size int, page int, sear
 	defer rows.Close()
 
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.Id, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		user, err := scanUserListRow(rows)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -94,10 +68,75 @@ func (r *userRepository) FindWithPagination(tx *sql.Tx, size int, page int, sear
 	return users, total, nil
 }
 
+// Each call fn for every filtered user, newest first, without loading all of them into memory
+func (r *userRepository) Each(tx *sql.Tx, search string, role string, toDate string, fromDate string, fn func(user models.User) error) error {
+	paramQuery, dataQuery := userFilterQuery(search, role, toDate, fromDate)
+
+	rows, err := tx.Query(userListQuery+paramQuery+" order by id desc", dataQuery...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUserListRow(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+const userListQuery = "select id, username, coalesce(email, ''), role, created_at, updated_at from users where 1=1 and is_deleted = FALSE"
+
+func scanUserListRow(row rowScanner) (models.User, error) {
+	var user models.User
+
+	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	return user, err
+}
+
+// userFilterQuery build the where conditions of the user list, starting with " and"
+func userFilterQuery(search string, role string, toDate string, fromDate string) (string, []interface{}) {
+	paramQuery := ""
+	dataQuery := []interface{}{}
+	index := 1
+
+	if search != "" {
+		paramQuery += " and username like $" + strconv.Itoa(index)
+		dataQuery = append(dataQuery, "%"+search+"%")
+		index++
+	}
+
+	if role != "" {
+		paramQuery += " and role = $" + strconv.Itoa(index)
+		dataQuery = append(dataQuery, role)
+		index++
+	}
+
+	if fromDate != "" {
+		paramQuery += " and created_at >= $" + strconv.Itoa(index)
+		dataQuery = append(dataQuery, fromDate)
+		index++
+	}
+
+	if toDate != "" {
+		paramQuery += " and created_at <= $" + strconv.Itoa(index)
+		dataQuery = append(dataQuery, toDate)
+	}
+
+	return paramQuery, dataQuery
+}
+
 func (r *userRepository) FindByID(tx *sql.Tx, id int) (models.User, error) {
 	var model models.User
 
-	err := tx.QueryRow("select id, username, role, password, created_at, updated_at, is_deleted from users where id = $1 and is_deleted = FALSE", id).Scan(&model.Id, &model.Username, &model.Role, &model.Password, &model.CreatedAt, &model.UpdatedAt, &model.IsDeleted)
+	err := tx.QueryRow("select id, username, coalesce(email, ''), role, password, created_at, updated_at, is_deleted from users where id = $1 and is_deleted = FALSE", id).Scan(&model.Id, &model.Username, &model.Email, &model.Role, &model.Password, &model.CreatedAt, &model.UpdatedAt, &model.IsDeleted)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return model, fmt.Errorf("user with id %d not found", id)
@@ -128,7 +167,7 @@ func (r *userRepository) Create(tx *sql.Tx, user *models.CreateUserInput) error
 }
 
 func (r *userRepository) Update(tx *sql.Tx, user *models.User) error {
-	if _, err := tx.Exec("update users set username = $1, password = $2, role = $3, updated_at = NOW() where id = $4", user.Username, user.Password, user.Role, user.Id); err != nil {
+	if _, err := tx.Exec("update users set username = $1, password = $2, role = $3, email = nullif($4, ''), updated_at = NOW() where id = $5", user.Username, user.Password, user.Role, user.Email, user.Id); err != nil {
 		return err
 	}
 
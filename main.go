@@ -1,18 +1,28 @@
 package main
 
 import (
+	"encoding/json"
+	"fiber-prjct-management-web/internal/events"
 	"fiber-prjct-management-web/internal/handlers"
+	"fiber-prjct-management-web/internal/jobs"
 	"fiber-prjct-management-web/internal/middleware"
 	"fiber-prjct-management-web/internal/repository"
+	"fiber-prjct-management-web/pkg/checkpoint"
 	"fiber-prjct-management-web/pkg/database"
+	"fiber-prjct-management-web/pkg/mailer"
+	"fiber-prjct-management-web/pkg/scanner"
 	"fiber-prjct-management-web/pkg/utils"
+	"log"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/template/html/v2"
 
 	_ "github.com/joho/godotenv/autoload"
@@ -20,18 +30,133 @@ import (
 
 func main() {
 	database.ConnectDB()
+
+	// run maintenance command if any, e.g. fsck
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	middleware.InitStore()
+	scanner.Init()
+	mailer.Init()
+	if err := checkpoint.Init(); err != nil {
+		log.Fatal(err)
+	}
 	// repo init
 	userRepo := repository.NewUserRepository(database.DB)
 	projectRepo := repository.NewProjectRepository(database.DB)
 	dailyLogRepo := repository.NewDailyLogRepository(database.DB)
+	uploadRepo := repository.NewUploadRepository(database.DB)
+	lineItemRepo := repository.NewLineItemRepository(database.DB)
+	categoryRepo := repository.NewExpenseCategoryRepository(database.DB)
+	budgetRepo := repository.NewBudgetRepository(database.DB)
+	rateRepo := repository.NewExchangeRateRepository(database.DB)
+	logReviewRepo := repository.NewLogReviewRepository(database.DB)
+	lockRepo := repository.NewPeriodLockRepository(database.DB)
+	logRevisionRepo := repository.NewLogRevisionRepository(database.DB)
+	auditRepo := repository.NewAuditRepository(database.DB)
+	reportSubscriptionRepo := repository.NewReportSubscriptionRepository(database.DB)
+	jobRepo := repository.NewJobRepository(database.DB)
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+
+	// domain events subscribers
+	events.NewNotifier(notificationRepo, projectRepo).Subscribe(events.Default)
+	events.NewRealtimePublisher(projectRepo).Subscribe(events.Default)
+
+	// realtime events of every app instance are received with postgres LISTEN
+	hub := events.NewHub()
+	if err := hub.Listen(database.ConnString()); err != nil {
+		log.Fatal(err)
+	}
 
 	// handler init
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(userRepo, auditRepo)
 	authHandler := handlers.NewAuthHandler(userRepo)
-	projectHandler := handlers.NewProjectHandler(projectRepo, dailyLogRepo)
-	dailyLogHandler := handlers.NewDailyLogHandler(projectRepo, dailyLogRepo)
-	dashboardHandler := handlers.NewDashboardHandler(projectRepo, dailyLogRepo)
+	projectHandler := handlers.NewProjectHandler(projectRepo, dailyLogRepo, budgetRepo, rateRepo, auditRepo)
+	dailyLogHandler := handlers.NewDailyLogHandler(projectRepo, dailyLogRepo, uploadRepo, budgetRepo, rateRepo, lockRepo, logRevisionRepo, auditRepo)
+	uploadHandler := handlers.NewUploadHandler(projectRepo, uploadRepo, auditRepo)
+	lineItemHandler := handlers.NewLineItemHandler(projectRepo, dailyLogRepo, lineItemRepo, categoryRepo, budgetRepo, lockRepo, logRevisionRepo, auditRepo)
+	categoryHandler := handlers.NewExpenseCategoryHandler(categoryRepo, auditRepo)
+	budgetHandler := handlers.NewBudgetHandler(projectRepo, dailyLogRepo, budgetRepo, categoryRepo, rateRepo, auditRepo)
+	rateHandler := handlers.NewExchangeRateHandler(rateRepo, auditRepo)
+	dashboardHandler := handlers.NewDashboardHandler(projectRepo, dailyLogRepo, rateRepo)
+	forecastHandler := handlers.NewForecastHandler(projectRepo, dailyLogRepo, rateRepo)
+	logReviewHandler := handlers.NewLogReviewHandler(projectRepo, dailyLogRepo, logReviewRepo, budgetRepo, userRepo, lockRepo, auditRepo)
+	periodLockHandler := handlers.NewPeriodLockHandler(projectRepo, lockRepo, auditRepo)
+	logRevisionHandler := handlers.NewLogRevisionHandler(projectRepo, dailyLogRepo, logRevisionRepo, budgetRepo, rateRepo, lockRepo, auditRepo)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	logImportHandler := handlers.NewLogImportHandler(projectRepo, dailyLogRepo, budgetRepo, rateRepo, lockRepo, logRevisionRepo, auditRepo)
+	exportHandler := handlers.NewExportHandler(projectRepo, dailyLogRepo, userRepo)
+	reportHandler := handlers.NewReportHandler(projectRepo, dailyLogRepo, budgetRepo, reportSubscriptionRepo, rateRepo, auditRepo)
+	jobHandler := handlers.NewJobHandler(jobRepo, auditRepo)
+	notificationHandler := handlers.NewNotificationHandler(notificationRepo)
+	eventHandler := handlers.NewEventHandler(hub)
+
+	// background jobs, every schedule enqueue a job on the queue that is run by one of the app instances
+	queue := jobs.NewQueue(database.DB, jobRepo)
+	queue.Register(jobs.JobFileScan, func(json.RawMessage) error {
+		return jobs.RescanPendingFiles(database.DB, dailyLogRepo)
+	})
+	queue.Register(jobs.JobUploadCleanup, func(json.RawMessage) error {
+		return jobs.CleanupExpiredUploads(database.DB, uploadRepo)
+	})
+	queue.Register(jobs.JobBudgetAlertMail, func(json.RawMessage) error {
+		return jobs.SendBudgetAlertMails(database.DB, budgetRepo)
+	})
+	queue.Register(jobs.JobLogReviewMail, func(json.RawMessage) error {
+		return jobs.SendLogReviewMails(database.DB, logReviewRepo)
+	})
+	queue.Register(jobs.JobAuditCheckpoint, func(json.RawMessage) error {
+		_, err := jobs.CreateAuditCheckpoint(database.DB, auditRepo)
+		return err
+	})
+	queue.Register(jobs.JobReportDigest, func(json.RawMessage) error {
+		return jobs.SendReportDigests(database.DB, reportSubscriptionRepo, projectRepo, dailyLogRepo, budgetRepo)
+	})
+	queue.Register(jobs.JobStorageGC, func(json.RawMessage) error {
+		report, err := jobs.StorageCheck(database.DB, dailyLogRepo, jobs.StorageCheckOptions{DeleteOrphans: jobs.StorageGCDeleteOrphans(), GracePeriod: 24 * time.Hour})
+		if err != nil {
+			return err
+		}
+
+		if len(report.MissingFiles) > 0 {
+			log.Printf("storage gc found %d missing files, run fsck for details", len(report.MissingFiles))
+		}
+		if orphans := len(report.OrphanedFiles) - report.DeletedFiles; orphans > 0 {
+			log.Printf("storage gc found %d orphaned files that are not deleted, run fsck for details", orphans)
+		}
+		return nil
+	})
+	queue.Register(jobs.JobCleanup, func(json.RawMessage) error {
+		return jobs.DeleteFinishedJobs(database.DB, jobRepo)
+	})
+	queue.Register(jobs.JobNotificationCleanup, func(json.RawMessage) error {
+		return jobs.DeleteReadNotifications(database.DB, notificationRepo)
+	})
+
+	schedules := []struct{ name, cron, jobType string }{
+		{"file-scan", "@every 5m", jobs.JobFileScan},
+		{"upload-cleanup", "@hourly", jobs.JobUploadCleanup},
+		{"budget-alert-mail", "* * * * *", jobs.JobBudgetAlertMail},
+		{"log-review-mail", "* * * * *", jobs.JobLogReviewMail},
+		{"audit-checkpoint", "@every " + checkpoint.Interval().String(), jobs.JobAuditCheckpoint},
+		{"report-digest", "*/10 * * * *", jobs.JobReportDigest},
+		{"storage-gc", "0 3 * * *", jobs.JobStorageGC},
+		{"job-cleanup", "30 3 * * *", jobs.JobCleanup},
+		{"notification-cleanup", "45 3 * * *", jobs.JobNotificationCleanup},
+	}
+	for _, schedule := range schedules {
+		if err := queue.Schedule(schedule.name, schedule.cron, schedule.jobType); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := queue.Start(jobs.QueueWorkers(), jobs.QueuePollInterval()); err != nil {
+		log.Fatal(err)
+	}
 
 	// engine := html.New("./web", ".html")
 	engine := html.New("./web", ".html")
@@ -61,8 +186,14 @@ func main() {
 		},
 	})
 	app.Use(cors.New())
+	// request id is stored on the audit events of the request
+	app.Use(requestid.New())
 	app.Use(logger.New())
 	app.Use(helmet.New())
+	// uploaded files only can be accessed through download endpoint that check the scan status
+	app.Use("/web/uploads", func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
 	app.Static("/web", "./web")
 
 	// routing
@@ -73,35 +204,142 @@ func main() {
 	// dashboard
 	app.Get("/", middleware.IsAuthWeb, dashboardHandler.ViewDashboard)
 	api.Get("/dashboard", middleware.IsAuthAPI, dashboardHandler.DashboardData)
+	app.Get("/events", middleware.IsAuthWeb, eventHandler.StreamEvents)
 
 	// project
 	app.Get("/project", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), projectHandler.ViewProject)
+	app.Get("/project/export", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), exportHandler.ExportProjects)
+	api.Get("/projects/export", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), exportHandler.ExportProjects)
 	api.Get("/projects", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), projectHandler.GetProjectsData)
 	api.Get("/projects/stats", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), projectHandler.GetProjectsStats)
+	api.Get("/projects/stats/categories", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), projectHandler.GetProjectsCategoryStats)
 	api.Get("/projects/:id", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), projectHandler.GetProjectByID)
 	api.Post("/projects", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), projectHandler.CreateProject)
 	api.Patch("/projects/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), projectHandler.EditProject)
 	api.Delete("/projects/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), projectHandler.DeleteProject)
+	api.Patch("/projects/:id/storage-quota", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), projectHandler.UpdateStorageQuota)
 
 	// project detail/ logs data
 	app.Get("/project/:id", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), dailyLogHandler.ViewProjectDetail)
 	api.Get("projects/:project_id/stats", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.GetProjectLogStats)
+	api.Get("/projects/:project_id/stats/categories", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.GetProjectCategoryStats)
+	app.Get("/project/:project_id/report", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), reportHandler.GetProjectReport)
+	api.Get("/projects/:project_id/report", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), reportHandler.GetProjectReport)
+	api.Get("/projects/:id/forecast", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), forecastHandler.GetProjectForecast)
 	api.Get("/projects/:project_id/logs", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.GetDailyLogsData)
+	app.Get("/project/:project_id/logs/export", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), exportHandler.ExportDailyLogs)
+	api.Get("/projects/:project_id/logs/export", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), exportHandler.ExportDailyLogs)
 	api.Get("/projects/:project_id/logs/:id", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.GetOneLogData)
 	api.Post("/projects/:project_id/logs", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), dailyLogHandler.CreateDailyLog)
+	api.Post("/projects/:project_id/logs/import", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), logImportHandler.ImportDailyLogs)
 	api.Patch("/projects/:project_id/logs/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), dailyLogHandler.UpdateDailyLog)
 	api.Delete("/projects/:project_id/logs/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), dailyLogHandler.DeleteLog)
 	api.Delete("/projects/:project_id/logs/:id/files", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), dailyLogHandler.DeleteFileLog)
+	app.Get("/project/:project_id/logs/:id/file", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), dailyLogHandler.DownloadFileLog)
+	app.Get("/project/:project_id/logs/:id/file/preview", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), dailyLogHandler.PreviewFileLog)
+	app.Get("/project/:project_id/logs/:id/file/thumbnail", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), dailyLogHandler.ThumbnailFileLog)
+	api.Get("/projects/:project_id/logs/:id/file", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.DownloadFileLog)
+	api.Get("/projects/:project_id/logs/:id/file/preview", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.PreviewFileLog)
+	api.Get("/projects/:project_id/logs/:id/file/thumbnail", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.ThumbnailFileLog)
+
+	// log approval, only approved logs are counted on the stats
+	api.Patch("/projects/:project_id/logs/:id/submit", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), logReviewHandler.SubmitLog)
+	api.Patch("/projects/:project_id/logs/:id/approve", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), logReviewHandler.ApproveLog)
+	api.Patch("/projects/:project_id/logs/:id/reject", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), logReviewHandler.RejectLog)
+	api.Get("/projects/:project_id/logs/:id/reviews", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), logReviewHandler.GetLogReviews)
+	api.Get("/log-reviews/pending", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), logReviewHandler.GetPendingLogReviews)
+	api.Get("/log-reviewers", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), logReviewHandler.GetLogReviewers)
+
+	// log version history
+	api.Get("/projects/:project_id/logs/:id/history", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), logRevisionHandler.GetLogHistory)
+	api.Post("/projects/:project_id/logs/:id/history/:version/restore", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), logRevisionHandler.RestoreLogVersion)
+
+	// closed accounting months, logs in a locked month can not be changed
+	api.Get("/projects/:project_id/period-locks", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), periodLockHandler.GetPeriodLocks)
+	api.Post("/projects/:project_id/period-locks", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), periodLockHandler.LockPeriod)
+	api.Get("/period-locks", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), periodLockHandler.GetPeriodLocks)
+	api.Post("/period-locks", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), periodLockHandler.LockPeriod)
+	api.Patch("/period-locks/:id/reopen", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), periodLockHandler.ReopenPeriod)
+
+	// log line items
+	api.Get("/projects/:project_id/logs/:id/items", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), lineItemHandler.GetLineItems)
+	api.Post("/projects/:project_id/logs/:id/items", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), lineItemHandler.CreateLineItem)
+	api.Patch("/projects/:project_id/logs/:id/items/:item_id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), lineItemHandler.UpdateLineItem)
+	api.Delete("/projects/:project_id/logs/:id/items/:item_id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), lineItemHandler.DeleteLineItem)
+
+	// budget per expense category and budget alerts
+	api.Get("/projects/:project_id/budgets", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), budgetHandler.GetProjectBudgets)
+	api.Post("/projects/:project_id/budgets", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), budgetHandler.SetCategoryBudget)
+	api.Delete("/projects/:project_id/budgets/:category_id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), budgetHandler.DeleteCategoryBudget)
+	api.Get("/budget-alerts", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), budgetHandler.GetBudgetAlerts)
+	api.Patch("/budget-alerts/:id/read", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), budgetHandler.ReadBudgetAlert)
+
+	// project budget revisions, approved by super admin when BUDGET_APPROVAL_REQUIRED is set
+	api.Get("/projects/:project_id/budget-revisions", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), budgetHandler.GetProjectBudgetRevisions)
+	api.Get("/budget-revisions/pending", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), budgetHandler.GetPendingBudgetRevisions)
+	api.Patch("/budget-revisions/:id/approve", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), budgetHandler.ApproveBudgetRevision)
+	api.Patch("/budget-revisions/:id/reject", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), budgetHandler.RejectBudgetRevision)
+
+	// resumable chunked upload for log attachment, GET also handle HEAD request
+	api.Post("/projects/:project_id/uploads", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), uploadHandler.CreateUpload)
+	api.Get("/projects/:project_id/uploads/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), uploadHandler.GetUpload)
+	api.Patch("/projects/:project_id/uploads/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), uploadHandler.PatchUpload)
+	api.Delete("/projects/:project_id/uploads/:id", middleware.IsAuthAPI, middleware.IsAdmin(utils.APIRequest), uploadHandler.DeleteUpload)
+
+	app.Get("/project/:id/files/archive", middleware.IsAuthWeb, middleware.IsSuperAdminOrAdmin(utils.WebRequest), dailyLogHandler.DownloadFilesArchive)
+	api.Get("/projects/:id/files/archive", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), dailyLogHandler.DownloadFilesArchive)
+
+	// expense category, managed by super admin
+	app.Get("/expense-category", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), categoryHandler.ViewExpenseCategory)
+	api.Get("/expense-categories", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), categoryHandler.GetCategories)
+	api.Post("/expense-categories", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), categoryHandler.CreateCategory)
+	api.Patch("/expense-categories/:id", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), categoryHandler.UpdateCategory)
+	api.Delete("/expense-categories/:id", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), categoryHandler.DeleteCategory)
+
+	// exchange rates for converting amounts into the reporting currency
+	app.Get("/exchange-rate", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), rateHandler.ViewExchangeRate)
+	api.Get("/exchange-rates", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), rateHandler.GetRates)
+	api.Post("/exchange-rates", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), rateHandler.CreateRate)
+	api.Post("/exchange-rates/import", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), rateHandler.ImportRates)
+	api.Delete("/exchange-rates/:id", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), rateHandler.DeleteRate)
 
 	app.Get("/user", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), userHandler.ViewUser)
+	app.Get("/user/export", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), exportHandler.ExportUsers)
 	app.Get("/user/self", middleware.IsAuthWeb, userHandler.ViewUserSelf)
 	api.Get("/users", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), userHandler.GetUsersData)
+	api.Get("/users/export", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), exportHandler.ExportUsers)
 	api.Get("/users/:id", middleware.IsAuthAPI, middleware.IsSuperAdminOrIsSelf(utils.APIRequest), userHandler.GetUserByID)
 	api.Patch("/users/:id", middleware.IsAuthAPI, middleware.IsSuperAdminOrIsSelf(utils.APIRequest), userHandler.EditUser)
 	api.Patch("/users/:id/password", middleware.IsAuthAPI, middleware.IsSelf(utils.APIRequest), userHandler.EditUserPassword)
 	api.Post("/users", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), userHandler.CreateUser)
 	api.Delete("/users/:id", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), userHandler.DeleteUser)
 
+	// in-app notifications of the request user, shown on the navbar bell
+	api.Get("/notifications", middleware.IsAuthAPI, notificationHandler.GetNotifications)
+	api.Get("/notifications/unread-count", middleware.IsAuthAPI, notificationHandler.GetUnreadNotificationCount)
+	api.Patch("/notifications/read-all", middleware.IsAuthAPI, notificationHandler.ReadAllNotifications)
+	api.Patch("/notifications/:id/read", middleware.IsAuthAPI, notificationHandler.ReadNotification)
+
+	// email digest subscriptions of the user, managed on /user/self
+	api.Get("/report-subscriptions", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), reportHandler.GetReportSubscriptions)
+	api.Post("/report-subscriptions", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), reportHandler.CreateReportSubscription)
+	api.Delete("/report-subscriptions/:id", middleware.IsAuthAPI, middleware.IsSuperAdminOrAdmin(utils.APIRequest), reportHandler.DeleteReportSubscription)
+
+	// audit log of every change, only for super admin
+	app.Get("/audit", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), auditHandler.ViewAudit)
+	app.Get("/audit/export", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), auditHandler.ExportAuditEvents)
+	api.Get("/audit-events", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), auditHandler.GetAuditEvents)
+	api.Get("/audit-events/export", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), auditHandler.ExportAuditEvents)
+	api.Get("/audit-events/verify", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), auditHandler.VerifyAuditChain)
+
+	// background job queue, failed jobs can be retried and schedules run now by super admin
+	app.Get("/jobs", middleware.IsAuthWeb, middleware.IsSuperAdmin(utils.WebRequest), jobHandler.ViewJobs)
+	api.Get("/jobs", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), jobHandler.GetJobs)
+	api.Get("/jobs/counts", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), jobHandler.GetJobCounts)
+	api.Get("/jobs/schedules", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), jobHandler.GetJobSchedules)
+	api.Post("/jobs/schedules/:name/run", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), jobHandler.RunJobSchedule)
+	api.Post("/jobs/:id/retry", middleware.IsAuthAPI, middleware.IsSuperAdmin(utils.APIRequest), jobHandler.RetryJob)
+
 	app.Get("/login", authHandler.LoginView)
 	api.Post("/login", authHandler.LoginWeb)
 	api.Post("/logout", middleware.IsAuthAPI, authHandler.Logout)
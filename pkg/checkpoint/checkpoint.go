@@ -0,0 +1,181 @@
+package checkpoint
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Checkpoint is a signed statement of the audit chain head, the chain up to EventId must end with Hash
+type Checkpoint struct {
+	EventId   int64  `json:"event_id"`
+	Hash      string `json:"hash"`
+	CreatedAt string `json:"created_at"`
+	Signature string `json:"signature"`
+}
+
+// Signer sign checkpoints with ed25519 key and append them to the checkpoint file, one json per line
+type Signer struct {
+	key  ed25519.PrivateKey
+	path string
+}
+
+// Default is the signer of audit checkpoints, nil means checkpoint is disabled
+var Default *Signer
+
+// Init setup the default signer from env, if AUDIT_SIGNING_KEY is empty no checkpoint is made
+func Init() error {
+	encoded := os.Getenv("AUDIT_SIGNING_KEY")
+	if encoded == "" {
+		Default = nil
+		return nil
+	}
+
+	path := os.Getenv("AUDIT_CHECKPOINT_FILE")
+	if path == "" {
+		path = "audit-checkpoints.jsonl"
+	}
+
+	signer, err := NewSigner(encoded, path)
+	if err != nil {
+		return err
+	}
+
+	Default = signer
+	return nil
+}
+
+// Interval is how often a checkpoint is made from AUDIT_CHECKPOINT_INTERVAL (e.g. 1h), default once a day
+func Interval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("AUDIT_CHECKPOINT_INTERVAL"))
+	if (err != nil) || (interval <= 0) {
+		return 24 * time.Hour
+	}
+
+	return interval
+}
+
+// NewSigner create signer from base64 ed25519 seed (32 bytes) or private key (64 bytes)
+func NewSigner(encodedKey string, path string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_SIGNING_KEY: %w", err)
+	}
+
+	var key ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		key = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		key = ed25519.PrivateKey(raw)
+	default:
+		return nil, errors.New("invalid AUDIT_SIGNING_KEY: must be base64 of 32 bytes ed25519 seed")
+	}
+
+	return &Signer{key: key, path: path}, nil
+}
+
+// GenerateKey create a new base64 ed25519 seed for AUDIT_SIGNING_KEY
+func GenerateKey() (string, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(seed), nil
+}
+
+func (s *Signer) Path() string {
+	return s.path
+}
+
+// PublicKey is the base64 key that auditors use to verify the checkpoints
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign create checkpoint of the chain head
+func (s *Signer) Sign(eventId int64, hash string) Checkpoint {
+	checkpoint := Checkpoint{
+		EventId:   eventId,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, message(checkpoint)))
+	return checkpoint
+}
+
+// Verify check that the checkpoint is signed by the signer key
+func (s *Signer) Verify(checkpoint Checkpoint) bool {
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(s.key.Public().(ed25519.PublicKey), message(checkpoint), signature)
+}
+
+// Append write the checkpoint at the end of the checkpoint file
+func (s *Signer) Append(checkpoint Checkpoint) error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(checkpoint)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// ReadAll read every checkpoint of the checkpoint file, no checkpoint when the file not exist yet
+func (s *Signer) ReadAll() ([]Checkpoint, error) {
+	checkpoints := []Checkpoint{}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoints, nil
+		}
+
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var checkpoint Checkpoint
+		if err := json.Unmarshal(scanner.Bytes(), &checkpoint); err != nil {
+			return nil, fmt.Errorf("checkpoint file line %d: %w", line, err)
+		}
+
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	return checkpoints, scanner.Err()
+}
+
+// message is the signed content of the checkpoint
+func message(checkpoint Checkpoint) []byte {
+	return []byte("audit-checkpoint|" + strconv.FormatInt(checkpoint.EventId, 10) + "|" + checkpoint.Hash + "|" + checkpoint.CreatedAt)
+}
@@ -0,0 +1,127 @@
+package checkpoint
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+
+	key, err := GenerateKey()
+	require.NoError(t, err)
+
+	signer, err := NewSigner(key, filepath.Join(t.TempDir(), "checkpoints.jsonl"))
+	require.NoError(t, err)
+
+	return signer
+}
+
+func TestSignVerify(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+
+	checkpoint := signer.Sign(42, "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+
+	tests := []struct {
+		name   string
+		signer *Signer
+		modify func(c *Checkpoint)
+		valid  bool
+	}{
+		{"signed checkpoint", signer, func(c *Checkpoint) {}, true},
+		{"other key", other, func(c *Checkpoint) {}, false},
+		{"changed event id", signer, func(c *Checkpoint) { c.EventId = 43 }, false},
+		{"changed hash", signer, func(c *Checkpoint) { c.Hash = strings.Repeat("0", 64) }, false},
+		{"changed time", signer, func(c *Checkpoint) { c.CreatedAt = "2000-01-01T00:00:00Z" }, false},
+		{"signature not base64", signer, func(c *Checkpoint) { c.Signature = "not base64!" }, false},
+		{"empty signature", signer, func(c *Checkpoint) { c.Signature = "" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := checkpoint
+			tt.modify(&modified)
+
+			assert.Equal(t, tt.valid, tt.signer.Verify(modified))
+		})
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	privateKey := ed25519.NewKeyFromSeed(seed)
+
+	tests := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{"seed", base64.StdEncoding.EncodeToString(seed), true},
+		{"private key", base64.StdEncoding.EncodeToString(privateKey), true},
+		{"not base64", "not base64!", false},
+		{"wrong size", base64.StdEncoding.EncodeToString(seed[:16]), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewSigner(tt.key, "checkpoints.jsonl")
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)), signer.PublicKey())
+		})
+	}
+}
+
+func TestAppendReadAll(t *testing.T) {
+	signer := newTestSigner(t)
+
+	checkpoints, err := signer.ReadAll()
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints, "no checkpoint before the file exists")
+
+	first := signer.Sign(1, "hash-1")
+	second := signer.Sign(2, "hash-2")
+	require.NoError(t, signer.Append(first))
+	require.NoError(t, signer.Append(second))
+
+	checkpoints, err = signer.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []Checkpoint{first, second}, checkpoints)
+
+	for _, checkpoint := range checkpoints {
+		assert.True(t, signer.Verify(checkpoint))
+	}
+
+	require.NoError(t, appendLine(signer.Path(), "{broken"))
+	_, err = signer.ReadAll()
+	assert.ErrorContains(t, err, "line 3")
+}
+
+func appendLine(path string, line string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -11,18 +11,21 @@ import (
 
 var DB *sql.DB
 
-func ConnectDB() {
-	var err error
-
+// ConnString get the postgres connection string from env, also used by the LISTEN connection of realtime events
+func ConnString() string {
 	host := os.Getenv("HOST_POSTGRES")
 	port := os.Getenv("PORT_POSTGRES")
 	user := os.Getenv("USER_POSTGRES")
 	password := os.Getenv("PASSWORD_POSTGRES")
 	dbname := os.Getenv("DATABASE_POSTGRES")
 
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s", host, port, user, password, dbname)
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s", host, port, user, password, dbname)
+}
+
+func ConnectDB() {
+	var err error
 
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = sql.Open("postgres", ConnString())
 	if err != nil {
 		log.Fatal("Connect postgres error: ", err)
 	}
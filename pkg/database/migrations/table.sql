@@ -1,7 +1,13 @@
+CREATE TABLE role (
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE
+);
+
 CREATE TABLE users (
     id SERIAL NOT NULL PRIMARY KEY,
     username VARCHAR(255) NOT NULL UNIQUE,
     password VARCHAR(255) NOT NULL,
+    email VARCHAR(255) DEFAULT NULL, -- used for email notification, e.g. budget alert
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
     role BIGINT NULL DEFAULT 2,
@@ -9,11 +15,6 @@ CREATE TABLE users (
     CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES role(id)
 );
 
-CREATE TABLE role (
-    id BIGSERIAL NOT NULL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL UNIQUE
-);
-
 CREATE TABLE project_status (
     id BIGSERIAL NOT NULL PRIMARY KEY,
     name VARCHAR(255) NOT NULL UNIQUE
@@ -27,6 +28,10 @@ CREATE TABLE projects (
     end_date DATE,
     status INT,
     budget BIGINT DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR', -- currency of the budget
+    storage_used_bytes BIGINT NOT NULL DEFAULT 0,
+    storage_file_count INT NOT NULL DEFAULT 0,
+    storage_quota_bytes BIGINT DEFAULT NULL, -- null use default quota from PROJECT_STORAGE_QUOTA_MB env
     created_by INT NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -42,12 +47,335 @@ CREATE TABLE daily_logs (
     issues TEXT,
     income BIGINT DEFAULT 0,
     expense BIGINT DEFAULT 0,
+    currency CHAR(3) NOT NULL DEFAULT 'IDR', -- currency of income, expense and line items
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     file TEXT DEFAULT NULL,
+    file_status VARCHAR(20) DEFAULT NULL, -- pending, clean, infected
+    file_thumbnail TEXT DEFAULT NULL,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    has_line_items BOOLEAN NOT NULL DEFAULT FALSE, -- set by the first line item, income and expense stay the sum of the items after that
+    status VARCHAR(10) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'submitted', 'approved', 'rejected')), -- only approved log is counted on stats
+    reviewer_id INT DEFAULT NULL, -- assigned reviewer, null is any super admin
+    submitted_at TIMESTAMP DEFAULT NULL,
+    reviewed_at TIMESTAMP DEFAULT NULL,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE expense_categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO expense_categories (name) VALUES ('Material'), ('Tenaga Kerja'), ('Peralatan'), ('Transportasi'), ('Lain-lain') ON CONFLICT (name) DO NOTHING;
+
+-- income and expense of a daily log is the sum of its line items once the log has had items
+CREATE TABLE log_line_items (
+    id SERIAL PRIMARY KEY,
+    daily_log_id INT NOT NULL,
+    type VARCHAR(10) NOT NULL, -- income, expense
+    category_id INT DEFAULT NULL,
+    description TEXT,
+    quantity NUMERIC(12, 2) NOT NULL DEFAULT 1,
+    unit_price BIGINT NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL DEFAULT 0, -- quantity * unit_price
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (daily_log_id) REFERENCES daily_logs(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES expense_categories(id)
+);
+
+CREATE INDEX log_line_items_daily_log_id_idx ON log_line_items (daily_log_id);
+
+-- resumable chunked upload, completed upload is attached to a daily log with upload_id
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY,
+    project_id INT NOT NULL,
+    created_by INT NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    checksum VARCHAR(64) NOT NULL, -- sha256 hex of the whole file
+    status VARCHAR(20) NOT NULL DEFAULT 'uploading', -- uploading, completed
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- budget line per expense category, projects.budget stay as the whole project budget
+CREATE TABLE project_category_budgets (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    category_id INT NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (project_id, category_id),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES expense_categories(id) ON DELETE CASCADE
+);
+
+-- raised once per threshold (BUDGET_ALERT_THRESHOLDS env) when budget usage cross it, removed when usage drop below the threshold again
+CREATE TABLE budget_alerts (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    category_id INT DEFAULT NULL, -- null is the whole project budget
+    threshold INT NOT NULL,
+    usage_percentage NUMERIC(8, 2) NOT NULL,
+    budget BIGINT NOT NULL,
+    actual BIGINT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    emailed_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES expense_categories(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX budget_alerts_scope_threshold_idx ON budget_alerts (project_id, (COALESCE(category_id, 0)), threshold);
+
+-- 1 base_currency = rate quote_currency on rate_date
+CREATE TABLE exchange_rates (
+    id SERIAL PRIMARY KEY,
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(20, 8) NOT NULL CHECK (rate > 0),
+    created_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (base_currency, quote_currency, rate_date),
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- rate to convert from_currency into to_currency on a date, use the latest rate on or before the date, or the
+-- earliest rate after it when there is none. The opposite pair is used as inverse rate when the pair has no rate
+CREATE OR REPLACE FUNCTION fx_rate(from_currency CHAR(3), to_currency CHAR(3), on_date DATE) RETURNS NUMERIC AS $$
+DECLARE
+    result NUMERIC;
+BEGIN
+    IF from_currency = to_currency THEN
+        RETURN 1;
+    END IF;
+
+    SELECT rates.rate INTO result
+    FROM (
+        SELECT er.rate, er.rate_date FROM exchange_rates er WHERE er.base_currency = from_currency AND er.quote_currency = to_currency
+        UNION ALL
+        SELECT 1 / er.rate, er.rate_date FROM exchange_rates er WHERE er.base_currency = to_currency AND er.quote_currency = from_currency
+    ) rates
+    ORDER BY rates.rate_date > on_date, ABS(rates.rate_date - on_date)
+    LIMIT 1;
+
+    IF result IS NULL THEN
+        RAISE EXCEPTION 'exchange rate % to % not found', from_currency, to_currency;
+    END IF;
+
+    RETURN result;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+CREATE OR REPLACE FUNCTION fx_convert(amount NUMERIC, from_currency CHAR(3), to_currency CHAR(3), on_date DATE) RETURNS NUMERIC AS $$
+    SELECT amount * fx_rate(from_currency, to_currency, on_date);
+$$ LANGUAGE sql STABLE;
+
+-- every change of projects.budget, takes effect when approved (directly when BUDGET_APPROVAL_REQUIRED env is off)
+CREATE TABLE budget_revisions (
+    id SERIAL PRIMARY KEY,
+    project_id INT NOT NULL,
+    old_budget BIGINT NOT NULL,
+    new_budget BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    requested_by INT DEFAULT NULL,
+    reviewed_by INT DEFAULT NULL, -- approver or rejecter
+    review_note TEXT DEFAULT NULL,
+    reviewed_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (requested_by) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (reviewed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- one pending revision per project
+CREATE UNIQUE INDEX budget_revisions_pending_idx ON budget_revisions (project_id) WHERE status = 'pending';
+CREATE INDEX budget_revisions_project_id_idx ON budget_revisions (project_id, reviewed_at);
+
+-- project budget in effect on a date, the new budget of the last revision approved on or before the date, or the
+-- old budget of the first approved revision when the date is before every revision
+CREATE OR REPLACE FUNCTION project_budget_at(p_project_id INT, on_date DATE) RETURNS BIGINT AS $$
+    SELECT COALESCE(
+        (SELECT br.new_budget FROM budget_revisions br
+            WHERE br.project_id = p_project_id AND br.status = 'approved' AND br.reviewed_at::date <= on_date
+            ORDER BY br.reviewed_at DESC, br.id DESC LIMIT 1),
+        (SELECT br.old_budget FROM budget_revisions br
+            WHERE br.project_id = p_project_id AND br.status = 'approved'
+            ORDER BY br.reviewed_at, br.id LIMIT 1),
+        (SELECT p.budget FROM projects p WHERE p.id = p_project_id)
+    );
+$$ LANGUAGE sql STABLE;
+
+-- submit, approve and reject history of daily logs, approve and reject are emailed to the project owner
+CREATE TABLE daily_log_reviews (
+    id SERIAL PRIMARY KEY,
+    daily_log_id INT NOT NULL,
+    user_id INT DEFAULT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('submitted', 'approved', 'rejected')),
+    comment TEXT DEFAULT NULL,
+    notified_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (daily_log_id) REFERENCES daily_logs(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX daily_log_reviews_daily_log_id_idx ON daily_log_reviews (daily_log_id);
+
+-- closed accounting months, logs dated in a locked month can not be created, changed or deleted.
+-- project_id null is a global lock of every project
+CREATE TABLE period_locks (
+    id SERIAL PRIMARY KEY,
+    project_id INT DEFAULT NULL,
+    period DATE NOT NULL, -- first day of the month
+    locked_by INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (locked_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX period_locks_project_period_idx ON period_locks ((COALESCE(project_id, 0)), period);
+
+-- lock and reopen history, a reopen needs a reason
+CREATE TABLE period_lock_events (
+    id SERIAL PRIMARY KEY,
+    project_id INT DEFAULT NULL,
+    period DATE NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('locked', 'reopened')),
+    reason TEXT DEFAULT NULL,
+    user_id INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX period_lock_events_project_id_idx ON period_lock_events (project_id);
+
+-- every version of a daily log, snapshot is the log after the change and changes the old and new value of
+-- every changed field
+CREATE TABLE daily_log_revisions (
+    id SERIAL PRIMARY KEY,
+    daily_log_id INT NOT NULL,
+    version INT NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('created', 'updated', 'restored')),
+    snapshot JSONB NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    user_id INT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (daily_log_id) REFERENCES daily_logs(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE (daily_log_id, version)
+);
+
+-- record of every change made through the app, before_data and after_data are the entity before and after the
+-- change (null on create and delete), actor_name is kept when the user is deleted.
+-- Events are chained in id order, hash is sha256 of the event with prev_hash (the hash of the previous event, empty
+-- for the first one), so a changed or deleted event break the chain
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INT DEFAULT NULL,
+    actor_name VARCHAR(50) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(50) NOT NULL DEFAULT '',
+    before_data JSONB DEFAULT NULL,
+    after_data JSONB DEFAULT NULL,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_entity_idx ON audit_events (entity_type, entity_id);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id);
+
+-- weekly or monthly email digest of a project, project_id null is all projects of the user. The digest is sent on
+-- next_send_at and cover the week or the month before it
+CREATE TABLE report_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    project_id INT DEFAULT NULL,
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('weekly', 'monthly')),
+    next_send_at TIMESTAMP NOT NULL,
+    last_sent_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
 );
 
+CREATE UNIQUE INDEX report_subscriptions_scope_idx ON report_subscriptions (user_id, (COALESCE(project_id, 0)), frequency);
+CREATE INDEX report_subscriptions_next_send_at_idx ON report_subscriptions (next_send_at);
+
+-- background job queue, workers pick pending jobs with FOR UPDATE SKIP LOCKED. A failed job is retried with backoff
+-- (run_at is moved) until max_attempts, then it stays failed until retried from the jobs page. schedule_name is the
+-- recurring schedule that enqueued the job
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    run_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP DEFAULT NULL,
+    locked_by VARCHAR(100) NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    schedule_name VARCHAR(50) DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX jobs_pending_run_at_idx ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX jobs_status_idx ON jobs (status, created_at);
+
+-- recurring jobs, the schedules are defined in the code and saved on start. next_run_at is moved by the cron
+-- expression every time the job is enqueued
+CREATE TABLE job_schedules (
+    name VARCHAR(50) PRIMARY KEY,
+    job_type VARCHAR(50) NOT NULL,
+    cron VARCHAR(100) NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP DEFAULT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- in-app notifications of the navbar bell, made from domain events (log created, budget threshold crossed, project
+-- status changed, account changed by super admin). link is the page of the notification
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    link VARCHAR(255) NOT NULL DEFAULT '',
+    project_id INT DEFAULT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, is_read, created_at);
+
 --  BELOW IS NOT IMPLEMENTED YET
 -- CREATE TABLE task_status (
 --     id SERIAL PRIMARY KEY,
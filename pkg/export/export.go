@@ -0,0 +1,280 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatPDF  = "pdf"
+)
+
+// Column is a column of the exported table, Width is the relative width of the column on pdf
+type Column struct {
+	Title string
+	Width float64
+}
+
+// Writer write table rows into an export file, Close must be called to finish the file. A row value is written as
+// number cell on xlsx when it is a number
+type Writer interface {
+	Write(row []interface{}) error
+	Close() error
+}
+
+func IsFormat(format string) bool {
+	return (format == FormatCSV) || (format == FormatXLSX) || (format == FormatPDF)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv"
+	}
+}
+
+// NewWriter create writer of the format. Csv rows are written to w directly, xlsx rows are buffered by excelize stream
+// writer (on temporary file when large) and pdf is kept in memory, both are written to w on Close
+func NewWriter(format string, w io.Writer, title string, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, title, columns)
+	case FormatPDF:
+		return newPDFWriter(w, title, columns), nil
+	default:
+		return nil, fmt.Errorf("export format must be csv, xlsx or pdf")
+	}
+}
+
+type csvWriter struct {
+	cw *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	writer := &csvWriter{cw: csv.NewWriter(w)}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column.Title
+	}
+
+	return writer, writer.Write(header)
+}
+
+func (w *csvWriter) Write(row []interface{}) error {
+	return w.cw.Write(textRow(row))
+}
+
+func (w *csvWriter) Close() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer, title string, columns []Column) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+
+	if err := file.SetDocProps(&excelize.DocProperties{Title: title}); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	stream, err := file.NewStreamWriter(file.GetSheetName(0))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	headerStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = excelize.Cell{StyleID: headerStyle, Value: column.Title}
+
+		// column width follow the pdf relative width
+		if err := stream.SetColWidth(i+1, i+1, 10+column.Width*4); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	writer := &xlsxWriter{w: w, file: file, stream: stream}
+	return writer, writer.Write(header)
+}
+
+func (w *xlsxWriter) Write(row []interface{}) error {
+	w.row++
+
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+
+	return w.stream.SetRow(cell, row)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+
+	return w.file.Write(w.w)
+}
+
+// maxPDFCellLines limit wrapped lines of a pdf cell
+const maxPDFCellLines = 20
+
+type pdfWriter struct {
+	w          io.Writer
+	pdf        *fpdf.Fpdf
+	translate  func(string) string
+	columns    []Column
+	widths     []float64
+	lineHeight float64
+}
+
+func newPDFWriter(w io.Writer, title string, columns []Column) *pdfWriter {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(false, 10)
+	pdf.AliasNbPages("")
+
+	writer := &pdfWriter{
+		w:          w,
+		pdf:        pdf,
+		translate:  pdf.UnicodeTranslatorFromDescriptor(""),
+		columns:    columns,
+		lineHeight: 5,
+	}
+
+	// columns share the page width by their relative width
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	totalWidth := 0.0
+	for _, column := range columns {
+		totalWidth += column.Width
+	}
+
+	for _, column := range columns {
+		writer.widths = append(writer.widths, (pageWidth-left-right)*column.Width/totalWidth)
+	}
+
+	generatedAt := time.Now().Format("2006-01-02 15:04")
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, writer.translate(title), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 5, "Generated "+generatedAt, "", 1, "L", false, 0, "")
+		pdf.Ln(2)
+
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.SetFillColor(230, 230, 230)
+		for i, column := range writer.columns {
+			pdf.CellFormat(writer.widths[i], 6, writer.translate(column.Title), "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d / {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	return writer
+}
+
+// Write add the row with wrapped cells, the row is moved to the next page when it does not fit
+func (w *pdfWriter) Write(row []interface{}) error {
+	cells := make([][]string, len(w.columns))
+	lines := 1
+	for i := range w.columns {
+		text := ""
+		if i < len(row) {
+			text = w.translate(cellText(row[i]))
+		}
+
+		// text is already translated into the font code page, so it is split as bytes
+		cells[i] = []string{}
+		for _, line := range w.pdf.SplitLines([]byte(text), w.widths[i]-2) {
+			cells[i] = append(cells[i], string(line))
+		}
+
+		// very long text is cut so a row always fit on a page
+		if len(cells[i]) > maxPDFCellLines {
+			cells[i] = append(cells[i][:maxPDFCellLines-1], "...")
+		}
+
+		if len(cells[i]) > lines {
+			lines = len(cells[i])
+		}
+	}
+
+	height := float64(lines) * w.lineHeight
+	_, pageHeight := w.pdf.GetPageSize()
+	if w.pdf.GetY()+height > pageHeight-15 {
+		w.pdf.AddPage()
+	}
+
+	x, y := w.pdf.GetXY()
+	for i, cellLines := range cells {
+		w.pdf.Rect(x, y, w.widths[i], height, "D")
+		for line, text := range cellLines {
+			w.pdf.SetXY(x+1, y+float64(line)*w.lineHeight)
+			w.pdf.CellFormat(w.widths[i]-2, w.lineHeight, text, "", 0, "L", false, 0, "")
+		}
+		x += w.widths[i]
+	}
+
+	left, _, _, _ := w.pdf.GetMargins()
+	w.pdf.SetXY(left, y+height)
+
+	return w.pdf.Error()
+}
+
+func (w *pdfWriter) Close() error {
+	return w.pdf.Output(w.w)
+}
+
+func textRow(row []interface{}) []string {
+	text := make([]string, len(row))
+	for i, value := range row {
+		text[i] = cellText(value)
+	}
+
+	return text
+}
+
+func cellText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
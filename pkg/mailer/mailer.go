@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"os"
+	"strconv"
+)
+
+// Mailer is implemented by every email backend used for notification
+type Mailer interface {
+	Send(to []string, subject string, body string) error
+	SendWithAttachments(to []string, subject string, body string, attachments []Attachment) error
+}
+
+// Attachment is a file attached to the email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Default is the mailer used for notification, nil means email notification is disabled
+var Default Mailer
+
+// Init setup the default mailer from env, if SMTP_HOST is empty no email is sent
+func Init() {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		Default = nil
+		return
+	}
+
+	port := 587
+	if p, err := strconv.Atoi(os.Getenv("SMTP_PORT")); err == nil && p > 0 {
+		port = p
+	}
+
+	Default = NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPMailer struct {
+	address  string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer create mailer that send plain text email through SMTP server, auth is only used when username is set
+func NewSMTPMailer(host string, port int, username string, password string, from string) *SMTPMailer {
+	if from == "" {
+		from = username
+	}
+
+	return &SMTPMailer{
+		address:  net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(to []string, subject string, body string) error {
+	return m.SendWithAttachments(to, subject, body, nil)
+}
+
+// SendWithAttachments send plain text email, with attachments it is sent as multipart/mixed
+func (m *SMTPMailer) SendWithAttachments(to []string, subject string, body string, attachments []Attachment) error {
+	if len(to) == 0 {
+		return nil
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(m.address, auth, m.from, to, m.message(to, subject, body, attachments))
+}
+
+func (m *SMTPMailer) message(to []string, subject string, body string, attachments []Attachment) []byte {
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+
+	body = strings.ReplaceAll(body, "\n", "\r\n")
+	if len(attachments) == 0 {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		msg.WriteString("\r\n")
+		msg.WriteString(body)
+
+		return msg.Bytes()
+	}
+
+	parts := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%s\r\n", parts.Boundary())
+	msg.WriteString("\r\n")
+
+	// writing into bytes.Buffer never fail
+	part, _ := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	part.Write([]byte(body))
+
+	for _, attachment := range attachments {
+		part, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		writeBase64Lines(part, attachment.Data)
+	}
+	parts.Close()
+
+	return msg.Bytes()
+}
+
+// writeBase64Lines write data as base64 with 76 characters lines as required by MIME
+func writeBase64Lines(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 * 1024
+
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner create scanner for clamd daemon, address format is tcp://host:port or unix:///path/to/clamd.sock
+func NewClamdScanner(address string, timeout time.Duration) *ClamdScanner {
+	network := "tcp"
+
+	if strings.HasPrefix(address, "unix://") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix://")
+	} else {
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+
+	return &ClamdScanner{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}
+}
+
+func (s *ClamdScanner) Ping() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+
+	if reply != "PONG" {
+		return fmt.Errorf("clamd unexpected ping reply: %s", reply)
+	}
+
+	return nil
+}
+
+// Scan stream data to clamd using INSTREAM command
+func (s *ClamdScanner) Scan(r io.Reader) (Result, error) {
+	result := Result{}
+
+	conn, err := s.dial()
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return result, err
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return result, err
+			}
+
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return result, err
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// zero length chunk to mark end of stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return result, err
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return result, err
+	}
+
+	// reply format is "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return result, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		result.Infected = true
+		result.Signature = strings.TrimSuffix(reply, " FOUND")
+		return result, nil
+	default:
+		return result, fmt.Errorf("clamd scan error: %s", reply)
+	}
+}
+
+func (s *ClamdScanner) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func readReply(conn net.Conn) (string, error) {
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
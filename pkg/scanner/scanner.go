@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Result is the verdict returned by a scanner for a single file
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// Scanner is implemented by every antivirus backend that can inspect uploaded files
+type Scanner interface {
+	Scan(r io.Reader) (Result, error)
+}
+
+// Default is the scanner used by the upload path, nil means scanning is disabled
+var Default Scanner
+
+// Init setup the default scanner from env, if CLAMD_ADDRESS is empty no scanner is used
+func Init() {
+	address := os.Getenv("CLAMD_ADDRESS")
+	if address == "" {
+		Default = nil
+		return
+	}
+
+	timeout := 30 * time.Second
+	if t, err := strconv.Atoi(os.Getenv("CLAMD_TIMEOUT_SECONDS")); err == nil && t > 0 {
+		timeout = time.Duration(t) * time.Second
+	}
+
+	Default = NewClamdScanner(address, timeout)
+}
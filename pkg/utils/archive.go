@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"os"
+)
+
+type ArchiveFile struct {
+	Name string // path inside the archive
+	Path string // path on disk
+}
+
+// ZipArchive write files directly to w as zip while they are added, so the archive never stored as temporary file.
+// The csv manifest is the last entry, its rows are kept on a temporary file until Close
+type ZipArchive struct {
+	zw           *zip.Writer
+	manifestName string
+	manifestFile *os.File
+	manifest     *csv.Writer
+}
+
+func NewZipArchive(w io.Writer, manifestName string, header []string) (*ZipArchive, error) {
+	manifestFile, err := os.CreateTemp("", "archive-manifest-*.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &ZipArchive{
+		zw:           zip.NewWriter(w),
+		manifestName: manifestName,
+		manifestFile: manifestFile,
+		manifest:     csv.NewWriter(manifestFile),
+	}
+
+	if err := archive.AddManifestRow(header); err != nil {
+		archive.removeManifest()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+func (a *ZipArchive) AddFile(file ArchiveFile) error {
+	return addZipFile(a.zw, file)
+}
+
+func (a *ZipArchive) AddManifestRow(row []string) error {
+	return a.manifest.Write(row)
+}
+
+// Close write the manifest and finish the zip, it must be called even after an error to remove the temporary file
+func (a *ZipArchive) Close() error {
+	defer a.removeManifest()
+
+	a.manifest.Flush()
+	if err := a.manifest.Error(); err != nil {
+		return err
+	}
+
+	if _, err := a.manifestFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	mw, err := a.zw.Create(a.manifestName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(mw, a.manifestFile); err != nil {
+		return err
+	}
+
+	return a.zw.Close()
+}
+
+func (a *ZipArchive) removeManifest() {
+	a.manifestFile.Close()
+	os.Remove(a.manifestFile.Name())
+}
+
+func addZipFile(zw *zip.Writer, file ArchiveFile) error {
+	src, err := os.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = file.Name
+	header.Method = zip.Deflate
+
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
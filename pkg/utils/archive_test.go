@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipArchive(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	require.NoError(t, os.WriteFile(first, []byte("first file"), 0644))
+	require.NoError(t, os.WriteFile(second, []byte("second file"), 0644))
+
+	var buf bytes.Buffer
+	archive, err := NewZipArchive(&buf, "manifest.csv", []string{"log_date", "filename"})
+	require.NoError(t, err)
+
+	require.NoError(t, archive.AddFile(ArchiveFile{Name: "2024-05-02/second.txt", Path: second}))
+	require.NoError(t, archive.AddManifestRow([]string{"2024-05-02", "2024-05-02/second.txt"}))
+	require.NoError(t, archive.AddManifestRow([]string{"2024-05-01", ""}))
+	require.NoError(t, archive.AddFile(ArchiveFile{Name: "2024-05-01/first.txt", Path: first}))
+	require.NoError(t, archive.AddManifestRow([]string{"2024-05-01", "2024-05-01/first.txt"}))
+
+	manifestPath := archive.manifestFile.Name()
+	require.NoError(t, archive.Close())
+
+	_, err = os.Stat(manifestPath)
+	assert.True(t, os.IsNotExist(err), "temporary manifest is removed")
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"2024-05-02/second.txt", "2024-05-01/first.txt", "manifest.csv"}, names)
+
+	assert.Equal(t, "second file", readZipFile(t, zr.File[0]))
+
+	rows, err := csv.NewReader(bytes.NewBufferString(readZipFile(t, zr.File[2]))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"log_date", "filename"},
+		{"2024-05-02", "2024-05-02/second.txt"},
+		{"2024-05-01", ""},
+		{"2024-05-01", "2024-05-01/first.txt"},
+	}, rows)
+}
+
+func TestZipArchiveMissingFile(t *testing.T) {
+	var buf bytes.Buffer
+	archive, err := NewZipArchive(&buf, "manifest.csv", []string{"filename"})
+	require.NoError(t, err)
+
+	err = archive.AddFile(ArchiveFile{Name: "missing.txt", Path: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+
+	manifestPath := archive.manifestFile.Name()
+	archive.Close()
+
+	_, err = os.Stat(manifestPath)
+	assert.True(t, os.IsNotExist(err), "temporary manifest is removed after an error")
+}
+
+func readZipFile(t *testing.T, file *zip.File) string {
+	t.Helper()
+
+	r, err := file.Open()
+	require.NoError(t, err)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(data)
+}
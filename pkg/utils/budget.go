@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BudgetAlertThresholds get budget usage percentages that raise an alert from BUDGET_ALERT_THRESHOLDS env, default is 80 and 100
+func BudgetAlertThresholds() []int {
+	thresholds := []int{}
+
+	for _, value := range strings.Split(os.Getenv("BUDGET_ALERT_THRESHOLDS"), ",") {
+		threshold, err := strconv.Atoi(strings.TrimSpace(value))
+		if (err != nil) || (threshold <= 0) {
+			continue
+		}
+
+		thresholds = append(thresholds, threshold)
+	}
+
+	if len(thresholds) == 0 {
+		return []int{80, 100}
+	}
+
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+// BudgetApprovalRequired check BUDGET_APPROVAL_REQUIRED env, when true project budget changes wait for super admin approval
+func BudgetApprovalRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("BUDGET_APPROVAL_REQUIRED"))
+	return required
+}
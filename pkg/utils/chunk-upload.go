@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var chunkPath = basePath + "chunks/"
+
+// status of resumable upload
+const (
+	UploadStatusUploading = "uploading"
+	UploadStatusCompleted = "completed"
+)
+
+// UploadExpiry is how long an upload can be resumed or attached before it is removed
+const UploadExpiry = 24 * time.Hour
+
+func ChunkFile(id string) string {
+	return chunkPath + id + ".part"
+}
+
+// WriteChunk write chunk data to the upload file at offset and return the new offset. Data after the offset is
+// discarded first, it can only come from a chunk that was written but not committed on the database
+func WriteChunk(id string, offset int64, data []byte) (int64, error) {
+	if err := os.MkdirAll(chunkPath, 0755); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(ChunkFile(id), os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Size() < offset {
+		return 0, fmt.Errorf("upload file is smaller than the offset, upload must be restarted")
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		return 0, err
+	}
+
+	if _, err := file.WriteAt(data, offset); err != nil {
+		return 0, err
+	}
+
+	return offset + int64(len(data)), nil
+}
+
+// RemoveStaleChunks remove chunk files that not written for longer than maxAge, the upload is already expired
+func RemoveStaleChunks(maxAge time.Duration) error {
+	entries, err := os.ReadDir(chunkPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if time.Since(info.ModTime()) > maxAge {
+			if err := os.Remove(chunkPath + entry.Name()); (err != nil) && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FileChecksum get sha256 hex of the file
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// AttachChunkUpload link completed upload to quarantine and process it like FileUpload. The chunk file is kept,
+// so the upload can still be attached again if the transaction is rolled back
+func AttachChunkUpload(id string, path string, filename string, originalName string) (UploadedFile, error) {
+	uploaded := UploadedFile{}
+
+	info, err := os.Stat(ChunkFile(id))
+	if err != nil {
+		return uploaded, err
+	}
+
+	quarantineFile := quarantinePath + path + filename + filepath.Ext(originalName)
+	if err := os.MkdirAll(filepath.Dir(quarantineFile), 0755); err != nil {
+		return uploaded, err
+	}
+
+	if err := os.Link(ChunkFile(id), quarantineFile); err != nil {
+		return uploaded, err
+	}
+
+	return processUploadedFile(quarantineFile, info.Size()), nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ReportingCurrency get the currency all stats are converted into from REPORTING_CURRENCY env, default is IDR
+func ReportingCurrency() string {
+	currency := NormalizeCurrency(os.Getenv("REPORTING_CURRENCY"))
+	if !IsCurrencyCode(currency) {
+		return "IDR"
+	}
+
+	return currency
+}
+
+func NormalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// IsCurrencyCode check if the code is 3 uppercase letters like ISO 4217 code
+func IsCurrencyCode(currency string) bool {
+	return currencyCodePattern.MatchString(currency)
+}
+
+// FormatAmount format amount with the currency and dot as thousands separator e.g. IDR 1.250.000
+func FormatAmount(amount int, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.Itoa(amount)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "." + digits[i:]
+	}
+
+	return strings.TrimSpace(currency + " " + sign + digits)
+}
@@ -25,3 +25,21 @@ func ErrorJSON(c *fiber.Ctx, code int, message string) error {
 		"message": message,
 	})
 }
+
+// ErrorJSONWithData is ErrorJSON with data that explain the error, e.g. the invalid rows of an import
+func ErrorJSONWithData(c *fiber.Ctx, code int, message string, data interface{}) error {
+	return c.Status(code).JSON(fiber.Map{
+		"error":   true,
+		"message": message,
+		"data":    data,
+	})
+}
+
+// ErrorStatus get status code from fiber error, other error use the default code
+func ErrorStatus(err error, defaultCode int) int {
+	if e, ok := err.(*fiber.Error); ok {
+		return e.Code
+	}
+
+	return defaultCode
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"log"
+	"os"
+)
+
+// FileTx is unit of work for file changes made inside a database transaction.
+// new files are staged and removed if the transaction is rolled back,
+// deleted files are only removed from disk after the transaction is committed
+type FileTx struct {
+	staged  []string
+	deletes []string
+}
+
+func NewFileTx() *FileTx {
+	return &FileTx{}
+}
+
+// Stage register files that already written during the transaction
+func (f *FileTx) Stage(paths ...string) {
+	for _, path := range paths {
+		if path != "" {
+			f.staged = append(f.staged, path)
+		}
+	}
+}
+
+// Delete schedule files to be removed after commit
+func (f *FileTx) Delete(paths ...string) {
+	for _, path := range paths {
+		if path != "" {
+			f.deletes = append(f.deletes, path)
+		}
+	}
+}
+
+// Commit remove all files that scheduled to be deleted, the database already committed so failed remove is only logged
+func (f *FileTx) Commit() {
+	removeFiles(f.deletes)
+	f.staged, f.deletes = nil, nil
+}
+
+// Rollback remove all staged files and keep the files that scheduled to be deleted
+func (f *FileTx) Rollback() {
+	removeFiles(f.staged)
+	f.staged, f.deletes = nil, nil
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); (err != nil) && !os.IsNotExist(err) {
+			log.Println("remove file error: ", err)
+		}
+	}
+}
@@ -1,18 +1,32 @@
 package utils
 
 import (
+	"fiber-prjct-management-web/pkg/scanner"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 var (
-	basePath = "./web/uploads/"
-	maxSize  = 8 * 1024 * 1024 // 8MB
+	basePath       = "./web/uploads/"
+	quarantinePath = basePath + "quarantine/"
+	thumbnailPath  = basePath + "thumbnails/"
+	maxSize        = 8 * 1024 * 1024 // 8MB
+)
+
+// scan status of uploaded files, only clean files can be downloaded
+const (
+	FileStatusPending  = "pending"
+	FileStatusClean    = "clean"
+	FileStatusInfected = "infected"
 )
 
 func IsAllowedFileTypes(fileType string) bool {
@@ -53,31 +67,159 @@ func GenerateNameLogsFiles(date string, project_id int) (string, error) {
 	return filename, nil
 }
 
-func FileUpload(c *fiber.Ctx, path string, filename string) (string, error) {
+type StoredFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+type UploadedFile struct {
+	Path      string
+	Status    string
+	Thumbnail string
+	Size      int64
+}
+
+// FileUpload save uploaded file to quarantine first and scan it, clean image file also get a thumbnail
+func FileUpload(c *fiber.Ctx, path string, filename string) (UploadedFile, error) {
+	uploaded := UploadedFile{}
+
 	file, err := c.FormFile("file")
 	if err != nil {
-		return "", err
-	}
-
-	// check file type
-	if !IsAllowedFileTypes(file.Header.Get("Content-Type")) {
-		return "", fmt.Errorf("tipe file tidak diizinkan (jpeg, jpg, png, gif, pdf, doc, docx, xls, xlsx, ppt, pptx, zip, rar)")
+		return uploaded, err
 	}
 
-	// check size
-	if file.Size > int64(maxSize) {
-		return "", fmt.Errorf("file maksimal berukuran 8MB")
+	if err := ValidateUploadFile(file.Header.Get("Content-Type"), file.Size); err != nil {
+		return uploaded, err
 	}
 
 	// change file name
 	filename = filename + filepath.Ext(file.Filename)
-	filepath := basePath + path + filename // add to file path
+	quarantineFile := quarantinePath + path + filename
+
+	if err := os.MkdirAll(quarantinePath+path, 0755); err != nil {
+		return uploaded, err
+	}
+
+	if err := c.SaveFile(file, quarantineFile); err != nil {
+		return uploaded, err
+	}
+
+	return processUploadedFile(quarantineFile, file.Size), nil
+}
+
+// ValidateUploadFile check file type and size of the upload
+func ValidateUploadFile(contentType string, size int64) error {
+	if !IsAllowedFileTypes(contentType) {
+		return fiber.NewError(fiber.StatusBadRequest, "tipe file tidak diizinkan (jpeg, jpg, png, gif, pdf, doc, docx, xls, xlsx, ppt, pptx, zip, rar)")
+	}
+
+	if size > int64(maxSize) {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, "file maksimal berukuran 8MB")
+	}
+
+	return nil
+}
+
+// processUploadedFile scan file on quarantine, clean image file also get a thumbnail
+func processUploadedFile(quarantineFile string, size int64) UploadedFile {
+	uploaded := UploadedFile{Size: size}
+	uploaded.Path, uploaded.Status = ScanFile(quarantineFile)
+
+	// thumbnail only generated from clean file
+	if (uploaded.Status == FileStatusClean) && IsThumbnailSupported(uploaded.Path) {
+		thumbnail, err := GenerateThumbnail(uploaded.Path)
+		if err != nil {
+			log.Println("generate thumbnail error: ", err)
+		}
+
+		uploaded.Thumbnail = thumbnail
+	}
+
+	return uploaded
+}
+
+// ScanFile scan file with the default scanner and move it to the right place based on the result.
+// clean file is released from quarantine, infected file is moved to quarantine and
+// if scanner is not available or the clean file can not be released the file stays where it is with pending status
+// to be scanned later
+func ScanFile(path string) (string, string) {
+	if scanner.Default == nil {
+		return releaseCleanFile(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println("scan file open error: ", err)
+		return path, FileStatusPending
+	}
+
+	result, err := scanner.Default.Scan(file)
+	file.Close()
+	if err != nil {
+		log.Println("scan file error: ", err)
+		return path, FileStatusPending
+	}
 
-	if err := c.SaveFile(file, filepath); err != nil {
-		return "", err
+	if result.Infected {
+		log.Printf("file %s infected: %s\n", path, result.Signature)
+		return quarantineFile(path), FileStatusInfected
 	}
 
-	return filepath, nil
+	return releaseCleanFile(path)
+}
+
+// releaseCleanFile release the clean file, a file that can not be moved out of quarantine is kept pending so the
+// rescan job retry the move
+func releaseCleanFile(path string) (string, string) {
+	dest, err := releaseFile(path)
+	if err != nil {
+		log.Println("release file error: ", err)
+		return path, FileStatusPending
+	}
+
+	return dest, FileStatusClean
+}
+
+func IsQuarantined(path string) bool {
+	return strings.HasPrefix(path, quarantinePath)
+}
+
+// releaseFile move file out from quarantine folder to the public upload folder
+func releaseFile(path string) (string, error) {
+	if !IsQuarantined(path) {
+		return path, nil
+	}
+
+	dest := basePath + strings.TrimPrefix(path, quarantinePath)
+	if err := moveFile(path, dest); err != nil {
+		return path, err
+	}
+
+	return dest, nil
+}
+
+// quarantineFile move file to the quarantine folder
+func quarantineFile(path string) string {
+	if IsQuarantined(path) {
+		return path
+	}
+
+	dest := quarantinePath + strings.TrimPrefix(path, basePath)
+	if err := moveFile(path, dest); err != nil {
+		log.Println("quarantine file error: ", err)
+		return path
+	}
+
+	return dest
+}
+
+func moveFile(src string, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(src, dest)
 }
 
 func DeleteFile(pathfile string) error {
@@ -87,3 +229,46 @@ func DeleteFile(pathfile string) error {
 
 	return nil
 }
+
+// ListStoredFiles list all files inside upload folder including quarantine and thumbnails except chunked uploads, path format is the same as saved on database
+func ListStoredFiles() ([]StoredFile, error) {
+	files := []StoredFile{}
+
+	err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// unfinished chunked uploads are not referenced by logs, they are cleaned when the upload expired
+		if d.IsDir() {
+			if path == filepath.Clean(chunkPath) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(basePath, path)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, StoredFile{
+			Path:    basePath + filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+
+	return files, err
+}
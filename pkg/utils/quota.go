@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MaxStorageQuotaMB is the largest quota in MB that still fits in bytes
+const MaxStorageQuotaMB = math.MaxInt64 / (1024 * 1024)
+
+// DefaultStorageQuota get default project storage quota in bytes from env, 0 is unlimited
+func DefaultStorageQuota() int64 {
+	quotaMB, err := strconv.ParseInt(os.Getenv("PROJECT_STORAGE_QUOTA_MB"), 10, 64)
+	if (err != nil) || (quotaMB < 0) || (quotaMB > MaxStorageQuotaMB) {
+		return 0
+	}
+
+	return quotaMB * 1024 * 1024
+}
+
+// EffectiveStorageQuota resolve project quota, project without own quota use the default quota
+func EffectiveStorageQuota(projectQuota sql.NullInt64) int64 {
+	if projectQuota.Valid {
+		return projectQuota.Int64
+	}
+
+	return DefaultStorageQuota()
+}
+
+func StorageUsagePercentage(used int64, quota int64) float64 {
+	if quota <= 0 {
+		return 0
+	}
+
+	return float64(int64(float64(used)/float64(quota)*10000)) / 100
+}
+
+// CheckStorageQuota return 413 error if the new file make the project storage usage exceed the quota
+func CheckStorageQuota(used int64, quota int64, size int64) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	if used+size > quota {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("kuota penyimpanan project terlampaui, terpakai %s dari %s, ukuran file %s", FormatBytes(used), FormatBytes(quota), FormatBytes(size)))
+	}
+
+	return nil
+}
+
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultStorageQuota(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", 0},
+		{"abc", 0},
+		{"-5", 0},
+		{"0", 0},
+		{"10", 10 * 1024 * 1024},
+		{"8796093022207", MaxStorageQuotaMB * 1024 * 1024},
+		{"8796093022208", 0}, // overflow bytes
+	}
+
+	for _, tt := range tests {
+		t.Setenv("PROJECT_STORAGE_QUOTA_MB", tt.value)
+
+		assert.Equal(t, tt.want, DefaultStorageQuota(), "PROJECT_STORAGE_QUOTA_MB=%q", tt.value)
+	}
+}
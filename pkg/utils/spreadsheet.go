@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ReadSpreadsheet read rows of a csv or xlsx file (by file extension), the first row is the header. Only the first
+// sheet of xlsx is read and cell values are raw, so dates are excel serial numbers (see SpreadsheetDate)
+func ReadSpreadsheet(r io.Reader, filename string, maxRows int) ([][]string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return readCSVRows(r, maxRows)
+	case ".xlsx":
+		return readXLSXRows(r, maxRows)
+	default:
+		return nil, fmt.Errorf("file must be .csv or .xlsx")
+	}
+}
+
+func readCSVRows(r io.Reader, maxRows int) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows := [][]string{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		// header is not counted
+		if len(rows) > maxRows {
+			return nil, fmt.Errorf("file max %d rows", maxRows)
+		}
+
+		rows = append(rows, record)
+	}
+
+	return rows, nil
+}
+
+func readXLSXRows(r io.Reader, maxRows int) ([][]string, error) {
+	file, err := excelize.OpenReader(r, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("XLSX file is invalid: %v", err)
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("XLSX file has no sheet")
+	}
+
+	sheetRows, err := file.Rows(sheets[0])
+	if err != nil {
+		return nil, err
+	}
+	defer sheetRows.Close()
+
+	rows := [][]string{}
+	for sheetRows.Next() {
+		if len(rows) > maxRows {
+			return nil, fmt.Errorf("file max %d rows", maxRows)
+		}
+
+		columns, err := sheetRows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, columns)
+	}
+
+	return rows, sheetRows.Error()
+}
+
+// SpreadsheetDate normalize date cell into YYYY-MM-DD, the cell is either YYYY-MM-DD text or excel date serial number
+func SpreadsheetDate(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date.Format("2006-01-02"), true
+	}
+
+	serial, err := strconv.ParseFloat(value, 64)
+	if (err != nil) || (serial < 1) {
+		return "", false
+	}
+
+	date, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return "", false
+	}
+
+	return date.Format("2006-01-02"), true
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	// register gif and png decoder for image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+var (
+	thumbnailMaxWidth  = 320
+	thumbnailMaxHeight = 320
+	thumbnailMaxPixels = 40 * 1000 * 1000 // reject image bigger than 40MP to avoid decompression bomb
+)
+
+func IsThumbnailSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+
+	return false
+}
+
+// IsPreviewSupported check if file is safe to be shown inline on the browser
+func IsPreviewSupported(path string) bool {
+	return IsThumbnailSupported(path) || strings.ToLower(filepath.Ext(path)) == ".pdf"
+}
+
+// GenerateThumbnail create jpeg thumbnail from image file and return the thumbnail path
+func GenerateThumbnail(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return "", err
+	}
+
+	if config.Width*config.Height > thumbnailMaxPixels {
+		return "", fmt.Errorf("image too large for thumbnail (%dx%d)", config.Width, config.Height)
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	width, height := thumbnailSize(src.Bounds().Dx(), src.Bounds().Dy())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// white background for transparent png/gif since jpeg has no alpha
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	thumbnail := thumbnailPath + strings.TrimSuffix(strings.TrimPrefix(path, basePath), filepath.Ext(path)) + ".jpg"
+	if err := os.MkdirAll(filepath.Dir(thumbnail), 0755); err != nil {
+		return "", err
+	}
+
+	out, err := os.Create(thumbnail)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if err := jpeg.Encode(out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		os.Remove(thumbnail)
+		return "", err
+	}
+
+	return thumbnail, nil
+}
+
+// thumbnailSize keep the aspect ratio and never upscale small image
+func thumbnailSize(width int, height int) (int, int) {
+	if (width <= thumbnailMaxWidth) && (height <= thumbnailMaxHeight) {
+		return width, height
+	}
+
+	ratio := float64(width) / float64(height)
+	if ratio >= float64(thumbnailMaxWidth)/float64(thumbnailMaxHeight) {
+		return thumbnailMaxWidth, max(1, int(float64(thumbnailMaxWidth)/ratio))
+	}
+
+	return max(1, int(float64(thumbnailMaxHeight)*ratio)), thumbnailMaxHeight
+}
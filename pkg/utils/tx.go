@@ -6,11 +6,97 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// beforeCommitKey is the Locals key of the writes that run just before the request transaction is committed
+const beforeCommitKey = "beforeCommit"
+
+type beforeCommitWrite struct {
+	tx *sql.Tx
+	fn func() error
+}
+
+// BeforeCommit run fn just before the request transaction is committed, in the order it is added. It is for write
+// that take a lock wanted by every request, the lock is only held for the write and the commit
+func BeforeCommit(c *fiber.Ctx, tx *sql.Tx, fn func() error) {
+	writes, _ := c.Locals(beforeCommitKey).([]beforeCommitWrite)
+	c.Locals(beforeCommitKey, append(writes, beforeCommitWrite{tx, fn}))
+}
+
+// runBeforeCommit run the writes added for the transaction, the writes of other transaction are kept
+func runBeforeCommit(tx *sql.Tx, c *fiber.Ctx) error {
+	writes, _ := c.Locals(beforeCommitKey).([]beforeCommitWrite)
+
+	others := []beforeCommitWrite{}
+	for _, write := range writes {
+		if write.tx != tx {
+			others = append(others, write)
+		}
+	}
+	c.Locals(beforeCommitKey, others)
+
+	for _, write := range writes {
+		if write.tx != tx {
+			continue
+		}
+
+		if err := write.fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func CommitOrRollback(tx *sql.Tx, c *fiber.Ctx) {
 	if r := recover(); r != nil {
 		_ = tx.Rollback()
 		ErrorJSON(c, fiber.StatusInternalServerError, "Internal Server Error")
+	} else if err := runBeforeCommit(tx, c); err != nil {
+		_ = tx.Rollback()
+		ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
 	} else {
 		_ = tx.Commit()
 	}
 }
+
+// CommitOrRollbackOnError is CommitOrRollback for handler that make several writes, the transaction is also
+// rolled back when the handler respond with error status so a failed write does not leave the earlier ones committed
+func CommitOrRollbackOnError(tx *sql.Tx, c *fiber.Ctx) {
+	finishTx(tx, c, NewFileTx(), recover())
+}
+
+// CommitOrRollbackWithFiles is CommitOrRollbackOnError for handler that change files, the files are kept or removed
+// with the transaction so they stay consistent with the database
+func CommitOrRollbackWithFiles(tx *sql.Tx, c *fiber.Ctx, files *FileTx) {
+	finishTx(tx, c, files, recover())
+}
+
+// finishTx get the recovered panic from the deferred caller, recover only works when called by the deferred function
+func finishTx(tx *sql.Tx, c *fiber.Ctx, files *FileTx, r interface{}) {
+	if r != nil {
+		_ = tx.Rollback()
+		files.Rollback()
+		ErrorJSON(c, fiber.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if c.Response().StatusCode() >= fiber.StatusBadRequest {
+		_ = tx.Rollback()
+		files.Rollback()
+		return
+	}
+
+	if err := runBeforeCommit(tx, c); err != nil {
+		_ = tx.Rollback()
+		files.Rollback()
+		ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		files.Rollback()
+		ErrorJSON(c, fiber.StatusInternalServerError, err.Error())
+		return
+	}
+
+	files.Commit()
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// txDriver is a database/sql driver that only record how the transaction ended
+type txDriver struct{ ended *string }
+
+func (d txDriver) Open(name string) (driver.Conn, error) { return txConn(d), nil }
+
+type txConn txDriver
+
+func (c txConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c txConn) Close() error                              { return nil }
+func (c txConn) Begin() (driver.Tx, error)                 { return txEnd(c), nil }
+
+type txEnd txConn
+
+func (t txEnd) Commit() error   { *t.ended = "commit"; return nil }
+func (t txEnd) Rollback() error { *t.ended = "rollback"; return nil }
+
+func TestCommitOrRollbackOnError(t *testing.T) {
+	var ended string
+	sql.Register("txtest", txDriver{&ended})
+
+	db, err := sql.Open("txtest", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name   string
+		handle func(c *fiber.Ctx) error
+		ended  string
+		status int
+	}{
+		{"success", func(c *fiber.Ctx) error { return RespondMessage(c, fiber.StatusOK, "ok") }, "commit", fiber.StatusOK},
+		{"error response", func(c *fiber.Ctx) error { return ErrorJSON(c, fiber.StatusBadRequest, "invalid") }, "rollback", fiber.StatusBadRequest},
+		{"panic", func(c *fiber.Ctx) error { panic("write failed") }, "rollback", fiber.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ended = ""
+
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				tx, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				defer CommitOrRollbackOnError(tx, c)
+
+				return tt.handle(c)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.ended, ended)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestBeforeCommit(t *testing.T) {
+	var ended string
+	sql.Register("txtest-before-commit", txDriver{&ended})
+
+	db, err := sql.Open("txtest-before-commit", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name   string
+		commit func(tx *sql.Tx, c *fiber.Ctx)
+		write  error
+		status int
+		writes []string
+		ended  string
+	}{
+		{"commit", CommitOrRollback, nil, fiber.StatusOK, []string{"first", "second"}, "commit"},
+		{"commit on error", CommitOrRollbackOnError, nil, fiber.StatusOK, []string{"first", "second"}, "commit"},
+		{"failed write", CommitOrRollback, errors.New("chain locked"), fiber.StatusOK, []string{"first"}, "rollback"},
+		{"error response", CommitOrRollbackOnError, nil, fiber.StatusBadRequest, []string{}, "rollback"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ended = ""
+			writes := []string{}
+
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				tx, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				defer tt.commit(tx, c)
+
+				// write of an other transaction is not run
+				other, err := db.Begin()
+				if err != nil {
+					return err
+				}
+				defer other.Rollback()
+				BeforeCommit(c, other, func() error { writes = append(writes, "other"); return nil })
+
+				BeforeCommit(c, tx, func() error { writes = append(writes, "first"); return tt.write })
+				BeforeCommit(c, tx, func() error { writes = append(writes, "second"); return nil })
+
+				if tt.status >= fiber.StatusBadRequest {
+					return ErrorJSON(c, tt.status, "invalid")
+				}
+
+				return RespondMessage(c, tt.status, "ok")
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.writes, writes)
+			assert.Equal(t, tt.ended, ended)
+			if tt.write != nil {
+				assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+			}
+		})
+	}
+}